@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/database"
+	"github.com/madalinpopa/go-event-planner/internal/backup"
+	"os"
+	"time"
+)
+
+// defaultBackupDir is the directory of the backups, the same as the one of
+// the scheduled backups of the web application.
+const defaultBackupDir = "database/backups"
+
+func backupDatabase(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("backup")
+	dir := fs.String("dir", defaultBackupDir, "directory of the backups")
+	keep := fs.Int("keep", 0, "number of backups kept in -dir, 0 to keep them all")
+	out := fs.String("out", "", "path of the backup, instead of a timestamped file in -dir")
+	_ = fs.Parse(args)
+
+	if *out != "" {
+		err := backup.Create(ctx, a.db, *out)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Backed up to %s\n", *out)
+		return nil
+	}
+
+	s := &backup.Scheduler{DB: a.db, Dir: *dir, Keep: *keep}
+	path, deleted, err := s.Backup(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Backed up to %s\n", path)
+	for _, old := range deleted {
+		fmt.Fprintf(a.out, "Deleted %s\n", old)
+	}
+	return nil
+}
+
+func listBackups(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("list-backups")
+	dir := fs.String("dir", defaultBackupDir, "directory of the backups")
+	_ = fs.Parse(args)
+
+	backups, err := backup.List(*dir)
+	if err != nil {
+		return err
+	}
+
+	versions, err := backup.Versions(database.Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	w := newTable(a.out)
+	w.row("PATH", "TAKEN", "SIZE", "SCHEMA")
+	for _, b := range backups {
+		w.row(b.Path, b.TakenAt.Local().Format("2006-01-02 15:04"), byteSize(b.Size), schemaStatus(ctx, b.Path, versions))
+	}
+	return w.flush()
+}
+
+func restoreDatabase(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("restore")
+	file := fs.String("file", "", "path of the backup to restore")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	versions, err := backup.Versions(database.Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	pending, err := backup.Restore(ctx, *file, a.dbPath, versions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Restored %s from %s\n", a.dbPath, *file)
+	_, err = os.Stat(a.dbPath + ".pre-restore")
+	if err == nil {
+		fmt.Fprintf(a.out, "The replaced database was kept as %s.pre-restore\n", a.dbPath)
+	}
+	if pending > 0 {
+		fmt.Fprintf(a.out, "%d migrations are pending, run them before starting the application\n", pending)
+	}
+	return nil
+}
+
+// schemaStatus describes the schema version of the backup at path compared
+// with the migrations.
+func schemaStatus(ctx context.Context, path string, versions []int64) string {
+	version, err := backup.FileSchemaVersion(ctx, path)
+	if err != nil {
+		return "unreadable"
+	}
+
+	pending, err := backup.Pending(version, versions)
+	if err != nil {
+		return fmt.Sprintf("%d (unknown)", version)
+	}
+	if pending > 0 {
+		return fmt.Sprintf("%d (%d pending)", version, pending)
+	}
+	return fmt.Sprintf("%d (current)", version)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// table writes rows of tab aligned columns.
+type table struct {
+	w *tabwriter.Writer
+}
+
+// newTable returns a table writing to out.
+func newTable(out io.Writer) *table {
+	return &table{w: tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)}
+}
+
+// row writes a row of the table.
+func (t *table) row(columns ...string) {
+	_, _ = fmt.Fprintln(t.w, strings.Join(columns, "\t"))
+}
+
+// flush writes the aligned table.
+func (t *table) flush() error {
+	return t.w.Flush()
+}
+
+// byteSize formats a number of bytes with a binary unit.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
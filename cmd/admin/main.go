@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// admin holds the models used by the commands.
+type admin struct {
+	out          io.Writer
+	db           *sql.DB
+	dbPath       string
+	userModel    *models.UserModel
+	eventModel   *models.EventModel
+	sessionModel *models.SessionModel
+	auditModel   *models.AuditModel
+	statsModel   *models.StatsModel
+}
+
+// command is a subcommand of the tool. Its arguments are the ones following
+// its name on the command line.
+type command struct {
+	summary string
+	run     func(ctx context.Context, a *admin, args []string) error
+}
+
+// commands lists the subcommands by name.
+var commands = map[string]command{
+	"create-user":     {"create a user", createUser},
+	"reset-password":  {"set a new password for a user and sign out their sessions", resetPassword},
+	"set-role":        {"change the role of a user to user or admin", setRole},
+	"list-users":      {"list the users", listUsers},
+	"disable":         {"disable a user, who can no longer log in", disableUser},
+	"enable":          {"enable a disabled user", enableUser},
+	"reassign-events": {"transfer the events of a user, or a single event, to another user", reassignEvents},
+	"purge-sessions":  {"delete the expired sessions", purgeSessions},
+	"stats":           {"print statistics about the database", printStats},
+	"backup":          {"back up the database while it is in use", backupDatabase},
+	"list-backups":    {"list the backups and their schema versions", listBackups},
+	"restore":         {"replace the database with a backup, with the application stopped", restoreDatabase},
+}
+
+// offline lists the commands which work without opening the database, which
+// may be missing or damaged.
+var offline = map[string]bool{
+	"restore":      true,
+	"list-backups": true,
+}
+
+// auditDetail is the detail of the audit events recorded by the commands.
+const auditDetail = "by the admin command"
+
+func main() {
+	dsn := flag.String("db", "database/events.db", "path of the SQLite database")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "admin: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	a := &admin{out: os.Stdout, dbPath: *dsn}
+
+	if !offline[flag.Arg(0)] {
+		db, err := sql.Open("sqlite3", *dsn+"?_foreign_keys=on&_busy_timeout=5000&mode=rw")
+		if err == nil {
+			err = db.Ping()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = db.Close() }()
+
+		a.db = db
+		a.userModel = &models.UserModel{DB: db}
+		a.eventModel = &models.EventModel{DB: db}
+		a.sessionModel = &models.SessionModel{DB: db}
+		a.auditModel = &models.AuditModel{DB: db}
+		a.statsModel = &models.StatsModel{DB: db}
+	}
+
+	// Interrupting the tool cancels the query in progress.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := cmd.run(ctx, a, flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the global flags and the commands.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: admin [-db path] <command> [flags]\n\nFlags:\n")
+	flag.PrintDefaults()
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(out, "\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-16s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(out, "\nRun admin <command> -h for the flags of a command.\n")
+}
+
+// newFlagSet returns the flag set of the command.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("admin "+name, flag.ExitOnError)
+}
+
+// user returns the user with the email, with a readable error if there is none.
+func (a *admin) user(ctx context.Context, email string) (models.User, error) {
+	if email == "" {
+		return models.User{}, errors.New("-email is required")
+	}
+
+	u, err := a.userModel.RetrieveByEmail(ctx, email)
+	if errors.Is(err, models.ErrNoRecord) {
+		return models.User{}, fmt.Errorf("no user with the email %q", email)
+	}
+	return u, err
+}
+
+// password returns the password given on the command line or, when there is
+// none, a random one printed for the operator to hand over.
+func (a *admin) password(password string) (string, error) {
+	if password != "" {
+		if len(password) < 8 {
+			return "", errors.New("the password must be at least 8 characters")
+		}
+		return password, nil
+	}
+
+	b := make([]byte, 12)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	password = base64.RawURLEncoding.EncodeToString(b)
+	fmt.Fprintf(a.out, "Generated password: %s\n", password)
+	return password, nil
+}
+
+func createUser(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("create-user")
+	name := fs.String("name", "", "name of the user")
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "password of the user (generated if empty)")
+	isAdmin := fs.Bool("admin", false, "give the user the admin role")
+	_ = fs.Parse(args)
+
+	if strings.TrimSpace(*name) == "" || *email == "" {
+		return errors.New("-name and -email are required")
+	}
+
+	_, err := a.userModel.RetrieveByEmail(ctx, *email)
+	if err == nil {
+		return fmt.Errorf("the email %q is already registered", *email)
+	}
+	if !errors.Is(err, models.ErrNoRecord) {
+		return err
+	}
+
+	pw, err := a.password(*password)
+	if err != nil {
+		return err
+	}
+
+	err = a.userModel.Create(ctx, strings.TrimSpace(*name), *email, pw)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			return fmt.Errorf("the email %q is already registered", *email)
+		}
+		return err
+	}
+
+	u, err := a.user(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	if *isAdmin {
+		err = a.userModel.SetRole(ctx, u.ID, models.RoleAdmin)
+		if err != nil {
+			return err
+		}
+		err = a.auditModel.Record(ctx, u.ID, models.AuditRoleChanged, "", fmt.Sprintf("%s to %s %s", models.RoleUser, models.RoleAdmin, auditDetail))
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(a.out, "Created user %d <%s>\n", u.ID, u.Email)
+	return nil
+}
+
+func resetPassword(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("reset-password")
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "new password (generated if empty)")
+	_ = fs.Parse(args)
+
+	u, err := a.user(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	pw, err := a.password(*password)
+	if err != nil {
+		return err
+	}
+
+	err = a.userModel.UpdatePassword(ctx, u.ID, pw)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := a.sessionModel.RevokeAll(ctx, u.ID, "")
+	if err != nil {
+		return err
+	}
+
+	err = a.userModel.ResetLoginFailures(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	err = a.auditModel.Record(ctx, u.ID, models.AuditPasswordReset, "", auditDetail)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Password of %s reset, %d sessions signed out\n", u.Email, revoked)
+	return nil
+}
+
+func setRole(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("set-role")
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "", "new role: user or admin")
+	_ = fs.Parse(args)
+
+	u, err := a.user(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	err = a.userModel.SetRole(ctx, u.ID, *role)
+	if err != nil {
+		return err
+	}
+
+	err = a.auditModel.Record(ctx, u.ID, models.AuditRoleChanged, "", fmt.Sprintf("%s to %s %s", u.Role, *role, auditDetail))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Role of %s changed from %s to %s\n", u.Email, u.Role, *role)
+	return nil
+}
+
+func listUsers(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("list-users")
+	disabledOnly := fs.Bool("disabled", false, "only list the disabled users")
+	_ = fs.Parse(args)
+
+	users, err := a.userModel.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := newTable(a.out)
+	w.row("ID", "EMAIL", "NAME", "ROLE", "2FA", "STATUS", "CREATED")
+	for _, u := range users {
+		if *disabledOnly && !u.Disabled {
+			continue
+		}
+
+		status := "active"
+		if u.Disabled {
+			status = "disabled"
+		}
+		twoFactor := "no"
+		if u.TOTPEnabled {
+			twoFactor = "yes"
+		}
+
+		w.row(fmt.Sprint(u.ID), u.Email, u.Name, u.Role, twoFactor, status, u.CreatedAt.Format("2006-01-02"))
+	}
+	return w.flush()
+}
+
+func disableUser(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("disable")
+	email := fs.String("email", "", "email of the user")
+	_ = fs.Parse(args)
+
+	u, err := a.user(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	err = a.userModel.SetDisabled(ctx, u.ID, true)
+	if err != nil {
+		return err
+	}
+
+	// The sessions stop working while the account is disabled; revoking
+	// them also keeps them from coming back if it is enabled again.
+	revoked, err := a.sessionModel.RevokeAll(ctx, u.ID, "")
+	if err != nil {
+		return err
+	}
+
+	err = a.auditModel.Record(ctx, u.ID, models.AuditAccountDisabled, "", auditDetail)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Disabled %s, %d sessions signed out\n", u.Email, revoked)
+	return nil
+}
+
+func enableUser(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("enable")
+	email := fs.String("email", "", "email of the user")
+	_ = fs.Parse(args)
+
+	u, err := a.user(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	err = a.userModel.SetDisabled(ctx, u.ID, false)
+	if err != nil {
+		return err
+	}
+
+	err = a.auditModel.Record(ctx, u.ID, models.AuditAccountEnabled, "", auditDetail)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Enabled %s\n", u.Email)
+	return nil
+}
+
+func reassignEvents(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("reassign-events")
+	from := fs.String("from", "", "email of the current owner; empty for the events without an owner")
+	to := fs.String("to", "", "email of the new owner")
+	eventID := fs.Int("event", 0, "ID of a single event to transfer, instead of all the events of -from")
+	_ = fs.Parse(args)
+
+	target, err := a.user(ctx, *to)
+	if err != nil {
+		return err
+	}
+
+	if *eventID != 0 {
+		err = a.eventModel.SetOwner(ctx, *eventID, target.ID)
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("no event with the ID %d", *eventID)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(a.out, "Event %d transferred to %s\n", *eventID, target.Email)
+		return nil
+	}
+
+	fromID, fromLabel := 0, "no owner"
+	if *from != "" {
+		source, err := a.user(ctx, *from)
+		if err != nil {
+			return err
+		}
+		fromID, fromLabel = source.ID, source.Email
+	}
+
+	n, err := a.eventModel.Reassign(ctx, fromID, target.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "%d events transferred from %s to %s\n", n, fromLabel, target.Email)
+	return nil
+}
+
+func purgeSessions(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("purge-sessions")
+	_ = fs.Parse(args)
+
+	n, err := a.sessionModel.Prune(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "%d expired sessions deleted\n", n)
+	return nil
+}
+
+func printStats(ctx context.Context, a *admin, args []string) error {
+	fs := newFlagSet("stats")
+	_ = fs.Parse(args)
+
+	s, err := a.statsModel.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := newTable(a.out)
+	w.row("Users", fmt.Sprintf("%d (%d admins, %d disabled)", s.Users, s.Admins, s.DisabledUsers))
+	w.row("Events", fmt.Sprintf("%d (%d without an owner)", s.Events, s.EventsWithoutUser))
+	w.row("RSVPs", fmt.Sprint(s.RSVPs))
+	w.row("Venues", fmt.Sprint(s.Venues))
+	w.row("Attachments", fmt.Sprintf("%d (%s)", s.Attachments, byteSize(s.AttachmentBytes)))
+	w.row("Active sessions", fmt.Sprint(s.ActiveSessions))
+	w.row("API tokens", fmt.Sprint(s.APITokens))
+	w.row("Webhook deliveries", fmt.Sprintf("%d pending, %d dead", s.PendingWebhooks, s.DeadWebhooks))
+	w.row("Audit events", fmt.Sprint(s.AuditEvents))
+	w.row("Database size", fmt.Sprintf("%s (%s free)", byteSize(s.DatabaseBytes), byteSize(s.FreeBytes)))
+	return w.flush()
+}
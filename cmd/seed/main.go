@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/seed"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	var o seed.Options
+	var now, password string
+
+	dsn := flag.String("db", "database/events.db", "path of the SQLite database")
+	flag.Uint64Var(&o.Seed, "seed", 1, "seed of the generator; the same seed produces the same data")
+	flag.IntVar(&o.Users, "users", 20, "number of users, the first one being an administrator")
+	flag.IntVar(&o.Events, "events", 50, "number of events")
+	flag.IntVar(&o.MaxRSVPs, "max-rsvps", 10, "largest number of attendees of an event")
+	flag.IntVar(&o.PastDays, "past-days", 90, "how many days in the past events are spread over")
+	flag.IntVar(&o.FutureDays, "future-days", 180, "how many days in the future events are spread over")
+	flag.StringVar(&now, "now", "", "reference date of the events, as YYYY-MM-DD (default today)")
+	flag.StringVar(&password, "password", "password123", "password of every generated user")
+	flag.Parse()
+
+	err := run(*dsn, o, now, password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the data and inserts it into the database.
+func run(dsn string, o seed.Options, now, password string) error {
+	if o.Users < 0 || o.Events < 0 || o.MaxRSVPs < 0 || o.PastDays < 0 || o.FutureDays < 0 {
+		return errors.New("the counts must not be negative")
+	}
+
+	o.Now = time.Now()
+	if now != "" {
+		var err error
+		o.Now, err = time.Parse("2006-01-02", now)
+		if err != nil {
+			return fmt.Errorf("-now: %w", err)
+		}
+	}
+
+	// The password is hashed once for every user, as bcrypt is slow on
+	// purpose and large fixtures would take minutes otherwise.
+	var err error
+	o.HashedPassword, err = bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", dsn+"?_foreign_keys=on&_busy_timeout=5000&mode=rw")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	fixture := seed.Generate(o)
+
+	fixtures := &models.FixtureModel{DB: db}
+	err = fixtures.Insert(context.Background(), fixture)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			return fmt.Errorf("%w; the database was already seeded, use another -seed", err)
+		}
+		return err
+	}
+
+	fmt.Printf("Inserted %d users, %d events and %d RSVPs (seed %d)\n", len(fixture.Users), len(fixture.Events), len(fixture.RSVPs), o.Seed)
+	if len(fixture.Users) > 0 {
+		fmt.Printf("Log in as %s with the password %q\n", fixture.Users[0].Email, password)
+	}
+	return nil
+}
@@ -2,4 +2,13 @@ package main
 
 type contextKey string
 
-const isAuthenticatedContextKey = contextKey("isAuthenticated")
+const (
+	isAuthenticatedContextKey = contextKey("isAuthenticated")
+	isAdminContextKey         = contextKey("isAdmin")
+	userIDContextKey          = contextKey("userID")
+	userContextKey            = contextKey("user")
+	sessionContextKey         = contextKey("session")
+	apiTokenContextKey        = contextKey("apiToken")
+	requestInfoContextKey     = contextKey("requestInfo")
+	localeContextKey          = contextKey("locale")
+)
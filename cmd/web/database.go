@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/alexedwards/scs/v2"
+	"github.com/madalinpopa/go-event-planner/internal/ratelimit"
+	"github.com/madalinpopa/go-event-planner/internal/sqlhook"
+	"strings"
+)
+
+// postgresDriver is the database/sql driver of PostgreSQL, and
+// newPostgresSessionStore returns a session store kept in the PostgreSQL
+// database. Both are set by postgres.go, which is only built with the
+// postgres build tag.
+var (
+	postgresDriver          string
+	newPostgresSessionStore func(dsn string) (scs.Store, error)
+)
+
+// isPostgres reports whether the DSN selects PostgreSQL rather than SQLite.
+func isPostgres(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// openDatabase opens the database selected by the DSN and returns it with
+// the session store of the same database. The statements run through the
+// returned database are reported to the hooks.
+func openDatabase(dsn string, hooks ...sqlhook.Hook) (*sql.DB, scs.Store, error) {
+	if !isPostgres(dsn) {
+		// The busy timeout lets several app instances share the same SQLite
+		// file without failing immediately on a locked database.
+		db, err := openDB("sqlite3", dsn+"?_foreign_keys=on&_busy_timeout=5000", hooks...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, sqlite3store.New(db), nil
+	}
+
+	if postgresDriver == "" {
+		return nil, nil, errors.New("this binary was built without PostgreSQL support, build it with -tags postgres")
+	}
+
+	db, err := openDB(postgresDriver, dsn, hooks...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store, err := newPostgresSessionStore(dsn)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+	return db, store, nil
+}
+
+// openDB opens the database of the driver with the DSN, and checks that it
+// can be reached.
+func openDB(driver, dsn string, hooks ...sqlhook.Hook) (*sql.DB, error) {
+	// sql.Open only looks up the driver, which is wrapped to run the hooks.
+	lookup, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	_ = lookup.Close()
+
+	connector, err := sqlhook.Connector(lookup.Driver(), dsn, hooks...)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sql.OpenDB(connector)
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// storedLimiter is a limiter keeping its buckets in the rate_limits table of
+// the database, whose full buckets are deleted by Prune.
+type storedLimiter interface {
+	ratelimit.Limiter
+	Prune(ctx context.Context) error
+}
+
+// newLimiter returns a limiter with the bucket keeping its buckets in the
+// database, PostgreSQL or SQLite, under keys starting with the prefix.
+func newLimiter(db *sql.DB, postgres bool, bucket ratelimit.Bucket, prefix string) storedLimiter {
+	if postgres {
+		return &ratelimit.Postgres{DB: db, Bucket: bucket, Prefix: prefix}
+	}
+	return &ratelimit.SQLite{DB: db, Bucket: bucket, Prefix: prefix}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/storage"
+	"github.com/madalinpopa/go-event-planner/internal/validator"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// handlerFunc is a handler which returns its errors instead of writing them to the response.
+// It is adapted to an http.Handler by App.handle.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// statusCoder is implemented by the errors which know the status of the response sent for them.
+type statusCoder interface {
+	error
+	Status() int
+}
+
+// httpError is an error answered with an HTTP status, such as a 400 for a form which cannot be
+// parsed or a 403 for a user not allowed to change an event.
+type httpError struct {
+	status int
+	err    error
+}
+
+// statusError returns an error answered with the status, wrapping err.
+func statusError(status int, err error) error {
+	return &httpError{status: status, err: err}
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+// Status returns the status of the response.
+func (e *httpError) Status() int {
+	return e.status
+}
+
+// errNotFound is returned by handlers for URLs which do not match a record, such as an event
+// ID which is not a number.
+var errNotFound = statusError(http.StatusNotFound, errors.New("page not found"))
+
+// validationError is answered with a 422 status listing the errors of a submitted form.
+type validationError struct {
+	validator.Validator
+}
+
+func (e *validationError) Error() string {
+	var messages []string
+	messages = append(messages, e.NonFieldErrors...)
+	for _, key := range sortedKeys(e.FieldErrors) {
+		messages = append(messages, key+": "+e.FieldErrors[key])
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Status returns the status of the response.
+func (e *validationError) Status() int {
+	return http.StatusUnprocessableEntity
+}
+
+// sortedKeys returns the keys of the field errors in order, so that they are logged and listed
+// the same way every time.
+func sortedKeys(m map[string]string) []string {
+	return slices.Sorted(maps.Keys(m))
+}
+
+// errorStatus returns the status of the response sent for an error returned by a handler:
+// the status carried by the error, a 404 for missing records and files, the status of a
+// request whose context ended, or a 500 otherwise.
+func errorStatus(r *http.Request, err error) int {
+	var sc statusCoder
+	switch {
+	case errors.As(err, &sc):
+		return sc.Status()
+	case errors.Is(err, models.ErrNoRecord), errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	}
+	if status := contextStatus(r, err); status != 0 {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// handle adapts a handler returning its errors to an http.Handler. Errors are answered by
+// handleError, so that every handler maps them to the same statuses and pages.
+func (app *App) handle(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		if err != nil {
+			app.handleError(w, r, err)
+		}
+	})
+}
+
+// handleError answers an error returned by a handler with its status: server errors are
+// logged with a stack trace and client errors with their cause.
+func (app *App) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errorStatus(r, err)
+	if status >= http.StatusInternalServerError || status == statusClientClosedRequest {
+		app.serverError(w, r, err)
+		return
+	}
+	app.clientError(w, r, status, err)
+}
+
+// errorPage holds the status, title and explanation displayed by the error page.
+type errorPage struct {
+	Status int
+	Title  string
+	Detail string
+
+	// Errors lists the errors of the form which failed validation, if any.
+	Errors []string
+}
+
+// errorDetails explains the statuses which have an error page of their own. The texts are
+// keys of the message catalogs, translated when the page is rendered.
+var errorDetails = map[int]string{
+	http.StatusBadRequest:          "The request could not be understood. Please check it and try again.",
+	http.StatusForbidden:           "You do not have permission to access this page.",
+	http.StatusNotFound:            "The page you are looking for does not exist or has been removed.",
+	http.StatusMethodNotAllowed:    "This page does not accept the method of the request.",
+	http.StatusUnprocessableEntity: "The submitted data is not valid. Please correct the errors and try again.",
+	http.StatusInternalServerError: "Something went wrong on our side. Please try again later.",
+}
+
+// newErrorPage returns the error page of the status. Statuses without an explanation of
+// their own get the one of the 400 or 500 page.
+func newErrorPage(status int, err error) errorPage {
+	detail, ok := errorDetails[status]
+	if !ok {
+		if status >= http.StatusInternalServerError {
+			detail = errorDetails[http.StatusInternalServerError]
+		} else {
+			detail = errorDetails[http.StatusBadRequest]
+		}
+	}
+
+	page := errorPage{Status: status, Title: http.StatusText(status), Detail: detail}
+
+	var ve *validationError
+	if errors.As(err, &ve) {
+		page.Errors = append(page.Errors, ve.NonFieldErrors...)
+		for _, key := range sortedKeys(ve.FieldErrors) {
+			page.Errors = append(page.Errors, ve.FieldErrors[key])
+		}
+	}
+	return page
+}
+
+// problem is the body of the error responses sent to clients accepting JSON, as described
+// by RFC 9457.
+type problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// errorResponse writes the error page of the status, rendered through the layout of the
+// application, or a JSON problem to clients which prefer JSON. The cause of the error is only
+// shown when it lists the errors of a form.
+func (app *App) errorResponse(w http.ResponseWriter, r *http.Request, status int, err error) {
+	page := newErrorPage(status, err)
+	page.Detail = app.printer(r).Sprintf(page.Detail)
+
+	if wantsJSON(r) {
+		p := problem{
+			Type:     "about:blank",
+			Title:    page.Title,
+			Status:   status,
+			Detail:   page.Detail,
+			Instance: r.URL.RequestURI(),
+		}
+		if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+			p.RequestID = info.id
+		}
+		var ve *validationError
+		if errors.As(err, &ve) {
+			p.Errors = ve.FieldErrors
+		}
+
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		err := json.NewEncoder(w).Encode(p)
+		if err != nil {
+			app.loggerFor(r).Error(err.Error(), "method", r.Method, "url", r.URL.RequestURI())
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Error = page
+
+	buf, err := app.execute(r, "errors/error.tmpl", data)
+	if err != nil {
+		// The error page itself failed, so fall back to the status text.
+		app.loggerFor(r).Error(err.Error(), "method", r.Method, "url", r.URL.RequestURI())
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		app.loggerFor(r).Error(err.Error(), "method", r.Method, "url", r.URL.RequestURI())
+	}
+}
+
+// wantsJSON reports whether the client prefers JSON to HTML, going by the first of the
+// application/json, application/problem+json and text/html media types in its Accept header.
+func wantsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json", "application/problem+json":
+			return true
+		case "text/html":
+			return false
+		}
+	}
+	return false
+}
+
+// routeErrors answers the requests which match no route of the mux with the 404 or 405 error
+// page, instead of the plain text of the mux. The Allow header of 405 responses is kept.
+func (app *App) routeErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+		if rec.status != http.StatusNotFound && rec.status != http.StatusMethodNotAllowed {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		if allow := rec.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		app.clientError(w, r, rec.status, errors.New(strings.ToLower(http.StatusText(rec.status))))
+	})
+}
+
+// statusRecorder records the status written by a handler, discarding its headers and body.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
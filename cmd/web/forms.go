@@ -10,7 +10,9 @@ type EventForm struct {
 	Title               string    `form:"title"`
 	Description         string    `form:"description"`
 	Location            string    `form:"location"`
+	VenueID             int       `form:"venueID"`
 	EventDate           time.Time `form:"eventDate"`
+	EndDate             time.Time `form:"endDate"`
 	validator.Validator `form:"-"`
 }
 
@@ -28,3 +30,75 @@ type UserLoginForm struct {
 	Password            string `form:"password"`
 	validator.Validator `form:"-"`
 }
+
+// TOTPForm represents the form submitting a two-factor authentication code,
+// either from an authenticator app or a recovery code.
+type TOTPForm struct {
+	Code                string `form:"code"`
+	validator.Validator `form:"-"`
+}
+
+// PasswordForm represents the form confirming a sensitive action with the
+// password of the user.
+type PasswordForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// PasswordChangeForm represents the form changing the password of the user.
+type PasswordChangeForm struct {
+	CurrentPassword     string `form:"currentPassword"`
+	NewPassword         string `form:"newPassword"`
+	validator.Validator `form:"-"`
+}
+
+// WebhookForm represents the structure for the webhook subscription form.
+type WebhookForm struct {
+	URL                 string   `form:"url"`
+	Secret              string   `form:"secret"`
+	EventTypes          []string `form:"eventTypes"`
+	validator.Validator `form:"-"`
+}
+
+// VenueForm represents the structure for the venue form. The coordinates are
+// kept as text, as they are optional and 0 is a valid value.
+type VenueForm struct {
+	Name                string `form:"name"`
+	Address             string `form:"address"`
+	Capacity            int    `form:"capacity"`
+	Latitude            string `form:"latitude"`
+	Longitude           string `form:"longitude"`
+	Amenities           string `form:"amenities"`
+	validator.Validator `form:"-"`
+}
+
+// APITokenForm represents the structure for the API token form. ExpiresIn is
+// the lifetime of the token in days, 0 for a token which never expires.
+type APITokenForm struct {
+	Name                string   `form:"name"`
+	Scopes              []string `form:"scopes"`
+	ExpiresIn           int      `form:"expiresIn"`
+	validator.Validator `form:"-"`
+}
+
+// ProfileForm represents the form changing the name of the user.
+type ProfileForm struct {
+	Name                string `form:"name"`
+	validator.Validator `form:"-"`
+}
+
+// EmailChangeForm represents the form requesting a new email for the user.
+// The password is only required from users who have one.
+type EmailChangeForm struct {
+	Email               string `form:"newEmail"`
+	Password            string `form:"emailPassword"`
+	validator.Validator `form:"-"`
+}
+
+// AccountDeleteForm represents the form deleting the account of the user,
+// confirmed by typing its email and, for users who have one, the password.
+type AccountDeleteForm struct {
+	Email               string `form:"confirmEmail"`
+	Password            string `form:"deletePassword"`
+	validator.Validator `form:"-"`
+}
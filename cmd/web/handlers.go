@@ -1,221 +1,639 @@
 package main
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"errors"
 	"fmt"
-	"github.com/justinas/nosurf"
+	"github.com/madalinpopa/go-event-planner/internal/calendar"
+	"github.com/madalinpopa/go-event-planner/internal/export"
+	"github.com/madalinpopa/go-event-planner/internal/i18n"
+	"github.com/madalinpopa/go-event-planner/internal/mail"
+	"github.com/madalinpopa/go-event-planner/internal/markdown"
 	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/oidc"
+	"github.com/madalinpopa/go-event-planner/internal/thumbnail"
+	"github.com/madalinpopa/go-event-planner/internal/totp"
 	"github.com/madalinpopa/go-event-planner/internal/validator"
+	"io"
+	"mime"
 	"net/http"
-	"runtime/debug"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // ping handles the /ping endpoint, responding with "pong" to indicate the service is available and operational.
 func (app *App) ping(w http.ResponseWriter, r *http.Request) {
-
-	var (
-		method = r.Method
-		url    = r.URL.RequestURI()
-		trace  = string(debug.Stack())
-	)
-
 	_, err := w.Write([]byte("pong"))
 	if err != nil {
-		app.logger.Error(err.Error(), "method", method, "url", url, "trace", trace)
-		return
+		app.loggerFor(r).Error(err.Error(), "method", r.Method, "url", r.URL.RequestURI())
 	}
 }
 
 // home renders the home template and responds with an HTTP 200 status. It does not take or process any additional data.
-func (app *App) home(w http.ResponseWriter, r *http.Request) {
+func (app *App) home(w http.ResponseWriter, r *http.Request) error {
+
+	events, err := app.eventModel.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Events = events
+	app.render(w, r, "home.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// localeCookieMaxAge is how long the locale cookie remembers the locale chosen by visitors.
+const localeCookieMaxAge = 365 * 24 * time.Hour
 
-	events, err := app.eventModel.List()
+// localePost changes the locale of the pages, remembered by a cookie and, for authenticated
+// users, as the locale of their account. It redirects back to the page the locale was chosen
+// on, or to the home page.
+func (app *App) localePost(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
 	if err != nil {
-		app.serverError(w, r, err)
-		return
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	locale := r.PostForm.Get("locale")
+	if !i18n.Supported(locale) {
+		return statusError(http.StatusBadRequest, fmt.Errorf("unsupported locale %q", locale))
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     localeCookie,
+		Value:    locale,
+		Path:     "/",
+		MaxAge:   int(localeCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if id := app.authenticatedUserID(r); id != 0 {
+		err = app.userModel.UpdateLocale(r.Context(), id, locale)
+		if err != nil {
+			return err
+		}
 	}
-	authenticated := app.isAuthenticated(r)
 
-	app.data.Events = events
-	app.data.CSRFToken = nosurf.Token(r)
-	app.IsAuthenticated = authenticated
-	app.render(w, r, "home.tmpl", app.data, http.StatusOK)
+	// Only pages of this site are redirected to.
+	redirect := "/"
+	if u, err := url.Parse(r.Referer()); err == nil && u.Host == r.Host && strings.HasPrefix(u.Path, "/") {
+		redirect = u.RequestURI()
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+	return nil
 }
 
 // eventDetail retrieves the details of a specific event based on the ID from the URL, renders the detail template, and responds.
-func (app *App) eventView(w http.ResponseWriter, r *http.Request) {
+func (app *App) eventView(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.NotFound(w, r)
-		return
+		return errNotFound
 	}
 
-	event, err := app.eventModel.Retrieve(id)
+	event, err := app.eventModel.Retrieve(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, models.ErrNoRecord) {
-			http.NotFound(w, r)
+		return err
+	}
+
+	attendees, err := app.rsvpModel.Count(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	attending, err := app.rsvpModel.Exists(r.Context(), id, app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	attachments, err := app.attachmentModel.List(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	var venue models.Venue
+	if event.VenueID != 0 {
+		venue, err = app.venueModel.Retrieve(r.Context(), event.VenueID)
+		if err != nil {
+			return err
+		}
+	}
+
+	data := app.newTemplateData(r)
+
+	// Separate the cover image from the files listed for download.
+	for _, a := range attachments {
+		if a.Kind == models.AttachmentCover {
+			data.Cover = a
 		} else {
-			app.serverError(w, r, err)
+			data.Attachments = append(data.Attachments, a)
 		}
-		return
 	}
 
-	// Assign the retrieved event data to the application data structure.
-	app.data.Event = event
+	data.Event = event
+	data.Venue = venue
+	data.AttendeeCount = attendees
+	data.IsAttending = attending
+	data.CanEdit = app.canEditEvent(r, event)
 
-	app.render(w, r, "events/view.tmpl", app.data, http.StatusOK)
+	app.render(w, r, "events/view.tmpl", data, http.StatusOK)
+	return nil
 }
 
-func (app *App) eventList(w http.ResponseWriter, r *http.Request) {
-	events, err := app.eventModel.List()
+func (app *App) eventList(w http.ResponseWriter, r *http.Request) error {
+	events, err := app.eventModel.List(r.Context())
 	if err != nil {
-		app.serverError(w, r, err)
-		return
+		return err
 	}
-	authenticated := app.isAuthenticated(r)
 
-	app.data.Events = events
-	app.data.CSRFToken = nosurf.Token(r)
-	app.IsAuthenticated = authenticated
-	app.render(w, r, "events/list.tmpl", app.data, http.StatusOK)
+	data := app.newTemplateData(r)
+	data.Events = events
+	app.render(w, r, "events/list.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// eventCalendar renders the events in a month, week or day calendar selected with the view and date
+// query parameters, e.g. /calendar?view=month&date=2026-10 or /calendar?view=week&date=2026-10-19.
+func (app *App) eventCalendar(w http.ResponseWriter, r *http.Request) error {
+	today := time.Now().UTC()
+
+	kind, date, err := calendar.Parse(r.URL.Query().Get("view"), r.URL.Query().Get("date"), today)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	start, end := calendar.Range(kind, date)
+
+	events, err := app.eventModel.Between(r.Context(), start, end)
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Calendar = calendar.New(kind, date, today, events, app.printer(r).Format)
+	app.render(w, r, "events/calendar.tmpl", data, http.StatusOK)
+	return nil
 }
 
 // eventCreate renders the "create event" template and responds with an HTTP 200 status. It does not process input data.
-func (app *App) eventCreate(w http.ResponseWriter, r *http.Request) {
-	app.Form = EventForm{}
-	app.render(w, r, "events/create.tmpl", app.data, http.StatusOK)
+func (app *App) eventCreate(w http.ResponseWriter, r *http.Request) error {
+	venues, err := app.venueModel.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = EventForm{}
+	data.Venues = venues
+	app.render(w, r, "events/create.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// eventPreview renders the Markdown description posted by the event forms
+// and responds with the sanitized HTML fragment, displayed as a preview.
+func (app *App) eventPreview(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	description := r.PostForm.Get("description")
+	if !validator.MaxChars(description, maxDescriptionLength) {
+		return statusError(http.StatusRequestEntityTooLarge, fmt.Errorf("description longer than %d characters", maxDescriptionLength))
+	}
+
+	preview, err := markdown.Render(description)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, string(preview))
+	return nil
 }
 
 // eventCreatePost handles the POST request for creating an event, parses the form data, and validates the request.
-func (app *App) eventCreatePost(w http.ResponseWriter, r *http.Request) {
+func (app *App) eventCreatePost(w http.ResponseWriter, r *http.Request) error {
 	err := r.ParseForm()
 	if err != nil {
-		app.clientError(w, r, http.StatusBadRequest, err)
-		return
+		return statusError(http.StatusBadRequest, err)
 	}
 
 	var form EventForm
 
-	err = app.formDecoder.Decode(&form, r.PostForm)
+	err = app.decodePostForm(r, &form)
 	if err != nil {
-		app.clientError(w, r, http.StatusBadRequest, err)
-		return
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	venues, err := app.venueModel.List(r.Context())
+	if err != nil {
+		return err
 	}
 
 	form.CheckField(validator.NotBlank(form.Title), "title", "This field is required.")
-	form.CheckField(validator.NotBlank(form.Location), "location", "This field is required.")
-	form.CheckField(validator.MaxChars(form.Description, 255), "description", "The description must be less than 255 characters.")
+	checkEventVenue(&form, venues)
+	form.CheckField(validator.MaxChars(form.Description, maxDescriptionLength), "description", "The description must be at most %d characters.", maxDescriptionLength)
 	form.CheckField(validator.ValidDate(form.EventDate), "eventDate", "This field is required.")
+	form.CheckField(form.EndDate.IsZero() || !form.EndDate.Before(form.EventDate), "endDate", "The end date must not be before the event date.")
 
-	if !form.Valid() {
-		fmt.Println(form)
-		app.Form = form
-		app.render(w, r, "events/create.tmpl", app.data, http.StatusUnprocessableEntity)
-		return
+	if form.Valid() {
+		_, err = app.eventModel.Create(r.Context(), app.authenticatedUserID(r), form.Title, form.Description, form.EventDate, form.EndDate, form.Location, form.VenueID)
+		if errors.Is(err, models.ErrVenueConflict) {
+			form.AddFieldError("venueID", "The venue is already booked on these dates.")
+		} else if err != nil {
+			return err
+		}
 	}
 
-	id, err := app.eventModel.Create(form.Title, form.Description, form.EventDate, form.Location)
-	fmt.Println(id, err)
+	if !form.Valid() {
+		// Scripted clients get the errors of the fields in a JSON problem.
+		if wantsJSON(r) {
+			return &validationError{form.Validator}
+		}
+		data := app.newTemplateData(r)
+		data.Form = form
+		data.Venues = venues
+		app.render(w, r, "events/create.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
 
 	http.Redirect(w, r, "/events", http.StatusFound)
+	return nil
 }
 
 // eventEdit handles the editing of an event by retrieving its details
 // and rendering the edit form with pre-filled data.
-func (app *App) eventEdit(w http.ResponseWriter, r *http.Request) {
+func (app *App) eventEdit(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.NotFound(w, r)
-		return
+		return errNotFound
 	}
 
-	event, err := app.eventModel.Retrieve(id)
+	event, err := app.eventModel.Retrieve(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, models.ErrNoRecord) {
-			http.NotFound(w, r)
-		} else {
-			app.serverError(w, r, err)
-		}
+		return err
+	}
+
+	if !app.canEditEvent(r, event) {
+		return statusError(http.StatusForbidden, errors.New("only the event owner can edit the event"))
+	}
+
+	venues, err := app.venueModel.List(r.Context())
+	if err != nil {
+		return err
 	}
-	app.Form = EventForm{}
-	app.data.Event = event
-	app.render(w, r, "events/edit.tmpl", app.data, http.StatusOK)
+
+	data := app.newTemplateData(r)
+	data.Form = EventForm{}
+	data.Event = event
+	data.Venues = venues
+	app.render(w, r, "events/edit.tmpl", data, http.StatusOK)
+	return nil
 }
 
 // eventEditPost handles the logic for editing an event, including form decoding,
 // validation, and updating the database.
-func (app *App) eventEditPost(w http.ResponseWriter, r *http.Request) {
+func (app *App) eventEditPost(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.NotFound(w, r)
-		return
+		return errNotFound
+	}
+
+	event, err := app.eventModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if !app.canEditEvent(r, event) {
+		return statusError(http.StatusForbidden, errors.New("only the event owner can edit the event"))
+	}
+
+	// The form is not parsed by the CSRF middleware for requests
+	// authenticated with an API token.
+	err = r.ParseForm()
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
 	}
 
 	var form EventForm
 
-	err = app.formDecoder.Decode(&form, r.PostForm)
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	venues, err := app.venueModel.List(r.Context())
 	if err != nil {
-		app.clientError(w, r, http.StatusBadRequest, err)
-		return
+		return err
 	}
 
 	form.CheckField(validator.NotBlank(form.Title), "title", "This field is required.")
-	form.CheckField(validator.NotBlank(form.Location), "location", "This field is required.")
-	form.CheckField(validator.MaxChars(form.Description, 255), "description", "The description must be less than 255 characters.")
+	checkEventVenue(&form, venues)
+	form.CheckField(validator.MaxChars(form.Description, maxDescriptionLength), "description", "The description must be at most %d characters.", maxDescriptionLength)
 	form.CheckField(validator.ValidDate(form.EventDate), "eventDate", "This field is required.")
+	form.CheckField(form.EndDate.IsZero() || !form.EndDate.Before(form.EventDate), "endDate", "The end date must not be before the event date.")
 
-	if !form.Valid() {
-		app.Form = form
-		app.render(w, r, "events/edit.tmpl", app.data, http.StatusUnprocessableEntity)
-		return
+	if form.Valid() {
+		err = app.eventModel.Update(r.Context(), id, form.Title, form.Description, form.EventDate, form.EndDate, form.Location, form.VenueID)
+		if errors.Is(err, models.ErrVenueConflict) {
+			form.AddFieldError("venueID", "The venue is already booked on these dates.")
+		} else if err != nil {
+			return err
+		}
 	}
 
-	err = app.eventModel.Update(id, form.Title, form.Description, form.EventDate, form.Location)
-	if err != nil {
-		app.serverError(w, r, err)
+	if !form.Valid() {
+		// Scripted clients get the errors of the fields in a JSON problem.
+		if wantsJSON(r) {
+			return &validationError{form.Validator}
+		}
+		data := app.newTemplateData(r)
+		data.Form = form
+		data.Event = event
+		data.Event.VenueID = form.VenueID
+		data.Venues = venues
+		app.render(w, r, "events/edit.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
 	}
+
 	http.Redirect(w, r, "/events", http.StatusSeeOther)
+	return nil
 }
 
 // eventDelete handles the deletion of an event record based on the ID extracted from the URL path.
-// It returns a 404 Not Found error if the ID is invalid or the event does not exist.
-// Logs internal errors and redirects to the home page upon successful deletion.
-func (app *App) eventDelete(w http.ResponseWriter, r *http.Request) {
+// It returns a 404 Not Found error if the ID is invalid or the event does not exist, and a 403
+// Forbidden error unless the user owns the event or is an administrator.
+// Redirects to the events page upon successful deletion.
+func (app *App) eventDelete(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		http.NotFound(w, r)
-		return
+		return errNotFound
+	}
+
+	event, err := app.eventModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if !app.canEditEvent(r, event) {
+		return statusError(http.StatusForbidden, errors.New("only the event owner can delete the event"))
+	}
+
+	// The attachment records are deleted together with the event, so look
+	// them up first to remove their content from the storage afterwards.
+	attachments, err := app.attachmentModel.List(r.Context(), id)
+	if err != nil {
+		return err
 	}
 
-	err = app.eventModel.Delete(id)
+	err = app.eventModel.Delete(r.Context(), id)
 	if err != nil {
-		app.logger.Error(err.Error())
-		app.serverError(w, r, err)
+		return err
+	}
+
+	for _, a := range attachments {
+		app.deleteStoredAttachment(r, a)
 	}
+
 	http.Redirect(w, r, "/events", http.StatusSeeOther)
+	return nil
+}
+
+// eventAttachmentPost handles the upload of a cover image or a file attached to an event. Only the
+// owner of the event and administrators may upload. The content type is sniffed from the uploaded
+// data rather than trusted from the client, and images get a thumbnail generated.
+func (app *App) eventAttachmentPost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	event, err := app.eventModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if !app.canEditEvent(r, event) {
+		return statusError(http.StatusForbidden, errors.New("only the event owner can upload attachments"))
+	}
+
+	redirect := fmt.Sprintf("/events/%d", id)
+
+	kind := r.PostFormValue("kind")
+	permittedTypes, ok := attachmentContentTypes[kind]
+	if !ok {
+		return statusError(http.StatusBadRequest, fmt.Errorf("unknown attachment kind %q", kind))
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Please choose a file to upload."))
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !validator.PermittedValue(contentType, permittedTypes...) {
+		app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("This type of file is not allowed."))
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return nil
+	}
+
+	suffix, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+
+	attachment := models.Attachment{
+		EventID:     id,
+		Kind:        kind,
+		Filename:    attachmentFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		StorageKey:  fmt.Sprintf("events/%d/%s", id, suffix),
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	err = app.storage.Put(r.Context(), attachment.StorageKey, file, contentType)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			app.deleteStoredAttachment(r, attachment)
+			return err
+		}
+
+		thumb, err := thumbnail.Generate(file, thumbnailSize)
+		if err != nil {
+			app.loggerFor(r).Warn("thumbnail generation failed", "event", id, "error", err.Error())
+			app.deleteStoredAttachment(r, attachment)
+			app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("The image could not be processed."))
+			http.Redirect(w, r, redirect, http.StatusSeeOther)
+			return nil
+		}
+
+		attachment.ThumbnailKey = attachment.StorageKey + "-thumb"
+		err = app.storage.Put(r.Context(), attachment.ThumbnailKey, bytes.NewReader(thumb), thumbnail.ContentType)
+		if err != nil {
+			app.deleteStoredAttachment(r, attachment)
+			return err
+		}
+	}
+
+	// An event has a single cover image, so replace the previous one.
+	var previous []models.Attachment
+	if kind == models.AttachmentCover {
+		attachments, err := app.attachmentModel.List(r.Context(), id)
+		if err != nil {
+			app.deleteStoredAttachment(r, attachment)
+			return err
+		}
+		for _, a := range attachments {
+			if a.Kind == models.AttachmentCover {
+				previous = append(previous, a)
+			}
+		}
+	}
+
+	_, err = app.attachmentModel.Create(r.Context(), attachment)
+	if err != nil {
+		app.deleteStoredAttachment(r, attachment)
+		return err
+	}
+
+	for _, a := range previous {
+		err = app.attachmentModel.Delete(r.Context(), a.ID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			return err
+		}
+		app.deleteStoredAttachment(r, a)
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Attachment uploaded."))
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+	return nil
+}
+
+// attachmentDownload serves the content of an attachment. Cover images are public, while the
+// files attached to an event are only available to authenticated users.
+func (app *App) attachmentDownload(w http.ResponseWriter, r *http.Request) error {
+	return app.serveAttachment(w, r, false)
+}
+
+// attachmentThumbnail serves the thumbnail of an image attachment, with the same access rules
+// as attachmentDownload.
+func (app *App) attachmentThumbnail(w http.ResponseWriter, r *http.Request) error {
+	return app.serveAttachment(w, r, true)
+}
+
+// attachmentDelete removes an attachment. Only the owner of the event and administrators may delete.
+func (app *App) attachmentDelete(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	attachment, err := app.attachmentModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	event, err := app.eventModel.Retrieve(r.Context(), attachment.EventID)
+	if err != nil {
+		return err
+	}
+
+	if !app.canEditEvent(r, event) {
+		return statusError(http.StatusForbidden, errors.New("only the event owner can delete attachments"))
+	}
+
+	err = app.attachmentModel.Delete(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	app.deleteStoredAttachment(r, attachment)
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Attachment deleted."))
+	http.Redirect(w, r, fmt.Sprintf("/events/%d", event.Id), http.StatusSeeOther)
+	return nil
+}
+
+// eventRSVP registers the authenticated user as an attendee of the event identified by the ID in the URL path.
+func (app *App) eventRSVP(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	_, err = app.eventModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	err = app.rsvpModel.Create(r.Context(), id, app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/events/%d", id), http.StatusSeeOther)
+	return nil
+}
+
+// eventRSVPCancel removes the authenticated user from the attendees of the event identified by the ID in the URL path.
+func (app *App) eventRSVPCancel(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	err = app.rsvpModel.Delete(r.Context(), id, app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/events/%d", id), http.StatusSeeOther)
+	return nil
 }
 
 // userRegister serves the user registration page by rendering the "register.tmpl"
 // template with the application data.
-func (app *App) userRegister(w http.ResponseWriter, r *http.Request) {
-	app.data.Form = UserRegisterForm{}
-	app.data.CSRFToken = nosurf.Token(r)
-	app.render(w, r, "auth/register.tmpl", app.data, http.StatusOK)
+func (app *App) userRegister(w http.ResponseWriter, r *http.Request) error {
+	data := app.newTemplateData(r)
+	data.Form = UserRegisterForm{}
+	app.render(w, r, "auth/register.tmpl", data, http.StatusOK)
+	return nil
 }
 
 // userRegisterPost handles HTTP POST requests for user registration
 // and renders the registration template with the given data.
-func (app *App) userRegisterPost(w http.ResponseWriter, r *http.Request) {
-	app.data.CSRFToken = nosurf.Token(r)
-
+func (app *App) userRegisterPost(w http.ResponseWriter, r *http.Request) error {
 	var form UserRegisterForm
 
-	err := app.formDecoder.Decode(&form, r.PostForm)
+	err := app.decodePostForm(r, &form)
 	if err != nil {
-		app.logger.Error(err.Error())
-		app.clientError(w, r, http.StatusBadRequest, err)
-		return
+		app.loggerFor(r).Error(err.Error())
+		return statusError(http.StatusBadRequest, err)
 	}
 
 	form.CheckField(validator.NotBlank(form.Name), "name", "This field is required.")
@@ -225,46 +643,44 @@ func (app *App) userRegisterPost(w http.ResponseWriter, r *http.Request) {
 	form.CheckField(validator.MinChars(form.Password, 8), "password", "Password must be at least 8 characters.")
 
 	if !form.Valid() {
-		app.Form = form
-		fmt.Println(form.FieldErrors)
-		app.render(w, r, "auth/register.tmpl", app.data, http.StatusUnprocessableEntity)
-		return
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "auth/register.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
 	}
 
-	err = app.userModel.Create(form.Name, form.Email, form.Password)
+	err = app.userModel.Create(r.Context(), form.Name, form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateEmail) {
 			form.AddFieldError("email", "This email address is already registered.")
-			app.Form = form
-			app.render(w, r, "register.tmpl", app.data, http.StatusUnprocessableEntity)
-		} else {
-			app.logger.Error(err.Error())
-			app.serverError(w, r, err)
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, "auth/register.tmpl", data, http.StatusUnprocessableEntity)
+			return nil
 		}
-		return
+		return err
 	}
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	return nil
 }
 
 // userLogin handles the user login page rendering by serving the login template
 // with the appropriate data and status.
-func (app *App) userLogin(w http.ResponseWriter, r *http.Request) {
-	app.data.Form = UserLoginForm{}
-	app.data.CSRFToken = nosurf.Token(r)
-	app.render(w, r, "auth/login.tmpl", app.data, http.StatusOK)
+func (app *App) userLogin(w http.ResponseWriter, r *http.Request) error {
+	data := app.newTemplateData(r)
+	data.Form = UserLoginForm{}
+	app.render(w, r, "auth/login.tmpl", data, http.StatusOK)
+	return nil
 }
 
 // userLoginPost handles POST requests for user login, rendering the login page
 // with the provided data and HTTP status OK.
-func (app *App) userLoginPost(w http.ResponseWriter, r *http.Request) {
-	app.data.CSRFToken = nosurf.Token(r)
-
+func (app *App) userLoginPost(w http.ResponseWriter, r *http.Request) error {
 	form := UserLoginForm{}
-	err := app.formDecoder.Decode(&form, r.PostForm)
+	err := app.decodePostForm(r, &form)
 	if err != nil {
-		app.logger.Error(err.Error())
-		app.clientError(w, r, http.StatusBadRequest, err)
-		return
+		app.loggerFor(r).Error(err.Error())
+		return statusError(http.StatusBadRequest, err)
 	}
 
 	form.CheckField(validator.NotBlank(form.Email), "email", "This field is required.")
@@ -272,45 +688,1251 @@ func (app *App) userLoginPost(w http.ResponseWriter, r *http.Request) {
 	form.CheckField(validator.NotBlank(form.Password), "password", "This field is required.")
 
 	if !form.Valid() {
-		app.Form = form
-		app.render(w, r, "auth/login.tmpl", app.data, http.StatusUnprocessableEntity)
-		return
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "auth/login.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
 	}
 
-	id, err := app.userModel.Authenticate(form.Email, form.Password)
+	// Throttled attempts and locked accounts get the same response as wrong
+	// credentials, so that it does not reveal which condition triggered.
+	loginFailed := func() {
+		form.AddNonFieldError("Invalid email or password, or too many attempts. Please try again later.")
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "auth/login.tmpl", data, http.StatusUnprocessableEntity)
+	}
+
+	// The limits are checked before the password, as bcrypt is costly.
+	ip := clientIP(r)
+	allowed, err := app.loginIPLimiter.Allow(r.Context(), ip)
 	if err != nil {
-		if errors.Is(err, models.ErrInvalidCredentials) {
-			form.AddNonFieldError("Invalid email or password.")
-			app.Form = form
-			app.render(w, r, "auth/login.tmpl", app.data, http.StatusUnprocessableEntity)
-		} else {
-			app.serverError(w, r, err)
+		return err
+	}
+	if allowed {
+		allowed, err = app.loginEmailLimiter.Allow(r.Context(), strings.ToLower(form.Email))
+		if err != nil {
+			return err
 		}
-		return
+	}
+	if !allowed {
+		loginFailed()
+		return nil
 	}
 
-	err = app.sessionManager.RenewToken(r.Context())
+	locked, err := app.userModel.IsLocked(r.Context(), form.Email)
+	if err != nil {
+		return err
+	}
+	if locked {
+		loginFailed()
+		return nil
+	}
+
+	id, err := app.userModel.Authenticate(r.Context(), form.Email, form.Password)
+	if err != nil {
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			return err
+		}
+
+		err = app.recordLoginFailure(r, form.Email)
+		if err != nil {
+			return err
+		}
+
+		loginFailed()
+		return nil
+	}
+
+	secret, err := app.userModel.TOTPSecret(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	// With two-factor authentication enabled, the session is only
+	// authenticated once the code is verified by userLoginTOTPPost.
+	if secret != "" {
+		app.startPendingLogin(r, id)
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return nil
+	}
+
+	err = app.userModel.ResetLoginFailures(r.Context(), id)
 	if err != nil {
-		app.serverError(w, r, err)
-		return
+		return err
 	}
 
-	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	err = app.startSession(r, id)
+	if err != nil {
+		return err
+	}
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
 }
 
-func (app *App) userLogoutPost(w http.ResponseWriter, r *http.Request) {
+// userLoginTOTP renders the second login step, asking for the code of the
+// authenticator app or a recovery code.
+func (app *App) userLoginTOTP(w http.ResponseWriter, r *http.Request) error {
+	if app.pendingUserID(r) == 0 {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil
+	}
 
-	// Renew session token
-	err := app.sessionManager.RenewToken(r.Context())
+	data := app.newTemplateData(r)
+	data.Form = TOTPForm{}
+	app.render(w, r, "auth/totp.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// userLoginTOTPPost verifies the code of the second login step and completes
+// the login started by userLoginPost or userLoginOIDCCallback. Failed codes
+// count towards the lockout of the account, like wrong passwords.
+func (app *App) userLoginTOTPPost(w http.ResponseWriter, r *http.Request) error {
+	id := app.pendingUserID(r)
+	if id == 0 {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil
+	}
+
+	var form TOTPForm
+	err := app.decodePostForm(r, &form)
 	if err != nil {
-		app.serverError(w, r, err)
-		return
+		return statusError(http.StatusBadRequest, err)
 	}
 
-	// Remove authenticated user id
-	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	form.CheckField(validator.NotBlank(form.Code), "code", "This field is required.")
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "auth/totp.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
 
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	loginFailed := func() {
+		form.AddNonFieldError("Invalid code, or too many attempts. Please try again later.")
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "auth/totp.tmpl", data, http.StatusUnprocessableEntity)
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := app.loginIPLimiter.Allow(r.Context(), clientIP(r))
+	if err != nil {
+		return err
+	}
+	if allowed {
+		allowed, err = app.loginEmailLimiter.Allow(r.Context(), strings.ToLower(user.Email))
+		if err != nil {
+			return err
+		}
+	}
+	if !allowed {
+		loginFailed()
+		return nil
+	}
+
+	locked, err := app.userModel.IsLocked(r.Context(), user.Email)
+	if err != nil {
+		return err
+	}
+	if locked {
+		loginFailed()
+		return nil
+	}
+
+	secret, err := app.userModel.TOTPSecret(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	// Codes of authenticator apps are digits only; anything else is tried
+	// as a recovery code.
+	var verified, usedRecoveryCode bool
+	if step, ok := totp.Validate(secret, form.Code, time.Now()); ok {
+		verified, err = app.userModel.UseTOTPStep(r.Context(), id, step)
+	} else if code := totp.NormalizeRecoveryCode(form.Code); len(code) == recoveryCodeLength {
+		verified, err = app.userModel.UseRecoveryCode(r.Context(), id, code)
+		usedRecoveryCode = verified
+	}
+	if err != nil {
+		return err
+	}
+
+	if !verified {
+		err = app.recordLoginFailure(r, user.Email)
+		if err != nil {
+			return err
+		}
+
+		loginFailed()
+		return nil
+	}
+
+	err = app.userModel.ResetLoginFailures(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	err = app.startSession(r, id)
+	if err != nil {
+		return err
+	}
+
+	if usedRecoveryCode {
+		left, err := app.userModel.RecoveryCodesLeft(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		app.sessionManager.Put(r.Context(), "flash", app.printer(r).Plural(left, "You logged in with a recovery code, %d left.", "You logged in with a recovery code, %d left."))
+		http.Redirect(w, r, "/account/security", http.StatusSeeOther)
+		return nil
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+// userLoginOIDC starts a single sign-on login by redirecting to the OpenID Connect
+// provider. The state, nonce and PKCE verifier are kept in the session until the
+// user comes back to userLoginOIDCCallback.
+func (app *App) userLoginOIDC(w http.ResponseWriter, r *http.Request) error {
+	if app.oidc == nil {
+		return errNotFound
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	app.sessionManager.Put(r.Context(), "oidcState", state)
+	app.sessionManager.Put(r.Context(), "oidcNonce", nonce)
+	app.sessionManager.Put(r.Context(), "oidcVerifier", verifier)
+
+	http.Redirect(w, r, app.oidc.AuthCodeURL(state, nonce, oidc.Challenge(verifier)), http.StatusFound)
+	return nil
+}
+
+// userLoginOIDCCallback completes a single sign-on login. The authorization code is
+// exchanged for an ID token, whose subject is looked up, linked to the user with the
+// same verified email, or used to provision a new user.
+func (app *App) userLoginOIDCCallback(w http.ResponseWriter, r *http.Request) error {
+	if app.oidc == nil {
+		return errNotFound
+	}
+
+	// The values are single use, whatever the outcome.
+	state := app.sessionManager.PopString(r.Context(), "oidcState")
+	nonce := app.sessionManager.PopString(r.Context(), "oidcNonce")
+	verifier := app.sessionManager.PopString(r.Context(), "oidcVerifier")
+
+	q := r.URL.Query()
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 {
+		return statusError(http.StatusBadRequest, errors.New("oidc: state mismatch"))
+	}
+
+	loginFailed := func(message string, err error) {
+		app.loggerFor(r).Warn("single sign-on failed", "error", err, "ip", clientIP(r))
+
+		form := UserLoginForm{}
+		form.SetTranslator(app.printer(r))
+		form.AddNonFieldError(message)
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "auth/login.tmpl", data, http.StatusUnauthorized)
+	}
+
+	if q.Get("error") != "" {
+		loginFailed("Single sign-on was cancelled or refused.", fmt.Errorf("oidc: %s: %s", q.Get("error"), q.Get("error_description")))
+		return nil
+	}
+
+	rawIDToken, err := app.oidc.Exchange(r.Context(), q.Get("code"), verifier)
+	if err != nil {
+		loginFailed("Single sign-on failed. Please try again.", err)
+		return nil
+	}
+
+	claims, err := app.oidc.Provider.Verify(r.Context(), rawIDToken, app.oidc.ClientID, nonce, time.Now())
+	if err != nil {
+		loginFailed("Single sign-on failed. Please try again.", err)
+		return nil
+	}
+
+	id, err := app.oidcUser(r, claims)
+	if err != nil {
+		if errors.Is(err, errUnverifiedEmail) {
+			loginFailed("Your identity provider did not confirm your email address.", err)
+			return nil
+		}
+		return err
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	if user.Disabled {
+		loginFailed("Your account is disabled.", fmt.Errorf("user %d is disabled", id))
+		return nil
+	}
+
+	// Single sign-on stands in for the password only: the second factor is
+	// still required.
+	if user.TOTPEnabled {
+		app.startPendingLogin(r, id)
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return nil
+	}
+
+	err = app.startSession(r, id)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+func (app *App) userLogoutPost(w http.ResponseWriter, r *http.Request) error {
+
+	// Forget the session in the list of active sessions
+	err := app.sessionModel.RevokeToken(r.Context(), app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return err
+	}
+
+	// Renew session token
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	// Remove authenticated user id
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+// webhookList renders the administration page listing the webhook subscriptions
+// together with the form to create a new one.
+func (app *App) webhookList(w http.ResponseWriter, r *http.Request) error {
+	webhooks, err := app.webhookModel.ListSubscriptions(r.Context())
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Webhooks = webhooks
+	data.EventTypes = models.WebhookEventTypes
+	data.Form = WebhookForm{}
+	app.render(w, r, "admin/webhooks.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// webhookCreatePost validates the webhook form and creates a new subscription. When no secret
+// is provided, a random one is generated.
+func (app *App) webhookCreatePost(w http.ResponseWriter, r *http.Request) error {
+	var form WebhookForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	form.CheckField(validator.NotBlank(form.URL), "url", "This field is required.")
+	form.CheckField(validator.ValidURL(form.URL), "url", "The URL must be an absolute http or https URL.")
+	form.CheckField(len(form.EventTypes) > 0, "eventTypes", "Select at least one event type.")
+	for _, eventType := range form.EventTypes {
+		form.CheckField(validator.PermittedValue(eventType, models.WebhookEventTypes...), "eventTypes", "Unknown event type.")
+	}
+
+	if !form.Valid() {
+		webhooks, err := app.webhookModel.ListSubscriptions(r.Context())
+		if err != nil {
+			return err
+		}
+
+		data := app.newTemplateData(r)
+		data.Webhooks = webhooks
+		data.EventTypes = models.WebhookEventTypes
+		data.Form = form
+		app.render(w, r, "admin/webhooks.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	if form.Secret == "" {
+		form.Secret, err = randomHex(32)
+		if err != nil {
+			return err
+		}
+	}
+
+	id, err := app.webhookModel.CreateSubscription(r.Context(), form.URL, form.Secret, form.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/webhooks/%d", id), http.StatusSeeOther)
+	return nil
+}
+
+// webhookView renders a webhook subscription with its recent deliveries and delivery attempts.
+func (app *App) webhookView(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	webhook, err := app.webhookModel.RetrieveSubscription(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := app.webhookModel.ListDeliveries(r.Context(), id, 50)
+	if err != nil {
+		return err
+	}
+
+	attempts, err := app.webhookModel.ListAttempts(r.Context(), id, 100)
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Webhook = webhook
+	data.Deliveries = deliveries
+	data.Attempts = attempts
+	app.render(w, r, "admin/webhook.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// webhookDelete removes a webhook subscription and its queued deliveries.
+func (app *App) webhookDelete(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	err = app.webhookModel.DeleteSubscription(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+	return nil
+}
+
+// venueList renders the venues together with the form to add a new one.
+func (app *App) venueList(w http.ResponseWriter, r *http.Request) error {
+	venues, err := app.venueModel.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Venues = venues
+	data.Form = VenueForm{}
+	app.render(w, r, "admin/venues.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// venueCreatePost validates the venue form and creates a new venue.
+func (app *App) venueCreatePost(w http.ResponseWriter, r *http.Request) error {
+	var form VenueForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	venue := checkVenueForm(&form)
+	if form.Valid() {
+		_, err = app.venueModel.Create(r.Context(), venue)
+		if errors.Is(err, models.ErrDuplicateVenue) {
+			form.AddFieldError("name", "A venue with this name already exists.")
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if !form.Valid() {
+		venues, err := app.venueModel.List(r.Context())
+		if err != nil {
+			return err
+		}
+
+		data := app.newTemplateData(r)
+		data.Venues = venues
+		data.Form = form
+		app.render(w, r, "admin/venues.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	http.Redirect(w, r, "/admin/venues", http.StatusSeeOther)
+	return nil
+}
+
+// venueEdit renders the form to edit a venue, pre-filled with its details.
+func (app *App) venueEdit(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	venue, err := app.venueModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Venue = venue
+	data.Form = newVenueForm(venue)
+	app.render(w, r, "admin/venue.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// venueEditPost validates the venue form and updates the venue.
+func (app *App) venueEditPost(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	var form VenueForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	venue := checkVenueForm(&form)
+	venue.ID = id
+	if form.Valid() {
+		err = app.venueModel.Update(r.Context(), venue)
+		if errors.Is(err, models.ErrDuplicateVenue) {
+			form.AddFieldError("name", "A venue with this name already exists.")
+		} else if errors.Is(err, models.ErrNoRecord) {
+			return errNotFound
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Venue = venue
+		data.Form = form
+		app.render(w, r, "admin/venue.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	http.Redirect(w, r, "/admin/venues", http.StatusSeeOther)
+	return nil
+}
+
+// venueDelete removes a venue. The events booked there keep their location.
+func (app *App) venueDelete(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	err = app.venueModel.Delete(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/admin/venues", http.StatusSeeOther)
+	return nil
+}
+
+// auditList renders the latest audit events, such as account lockouts.
+func (app *App) auditList(w http.ResponseWriter, r *http.Request) error {
+	events, err := app.auditModel.List(r.Context(), 200)
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.AuditEvents = events
+	app.render(w, r, "admin/audit.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// accountSecurity renders the security settings of the user, from which
+// two-factor authentication is enabled or disabled.
+func (app *App) accountSecurity(w http.ResponseWriter, r *http.Request) error {
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	left, err := app.userModel.RecoveryCodesLeft(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.RecoveryCodesLeft = left
+	data.Form = PasswordForm{}
+	app.render(w, r, "account/security.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// totpSetupPost starts the enrollment in two-factor authentication by
+// generating a secret, kept in the session until it is confirmed.
+func (app *App) totpSetupPost(w http.ResponseWriter, r *http.Request) error {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "totpSecret", secret)
+	http.Redirect(w, r, "/account/2fa/setup", http.StatusSeeOther)
+	return nil
+}
+
+// totpSetup renders the QR code of the pending secret together with the form
+// confirming that the authenticator app was set up.
+func (app *App) totpSetup(w http.ResponseWriter, r *http.Request) error {
+	secret := app.sessionManager.GetString(r.Context(), "totpSecret")
+	if secret == "" {
+		http.Redirect(w, r, "/account/security", http.StatusSeeOther)
+		return nil
+	}
+
+	data := app.newTemplateData(r)
+	data.TOTPSecret = secret
+	data.Form = TOTPForm{}
+	app.render(w, r, "account/totp_setup.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// totpQRCode renders the QR code of the pending secret as a PNG image. It is
+// generated on the server, so that the secret is never sent to a third party.
+func (app *App) totpQRCode(w http.ResponseWriter, r *http.Request) error {
+	secret := app.sessionManager.GetString(r.Context(), "totpSecret")
+	if secret == "" {
+		return errNotFound
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	png, err := totp.QRCode(totp.URL(totpIssuer, user.Email, secret), 256)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(png)
+	return nil
+}
+
+// totpConfirmPost verifies a code of the pending secret, enables two-factor
+// authentication and shows the recovery codes, which are only displayed once.
+func (app *App) totpConfirmPost(w http.ResponseWriter, r *http.Request) error {
+	secret := app.sessionManager.GetString(r.Context(), "totpSecret")
+	if secret == "" {
+		http.Redirect(w, r, "/account/security", http.StatusSeeOther)
+		return nil
+	}
+
+	var form TOTPForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	step, ok := totp.Validate(secret, form.Code, time.Now())
+	form.CheckField(ok, "code", "The code is not valid, check the time of your device and try again.")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.TOTPSecret = secret
+		data.Form = form
+		app.render(w, r, "account/totp_setup.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	codes, err := totp.RecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return err
+	}
+
+	normalized := make([]string, len(codes))
+	for i, code := range codes {
+		normalized[i] = totp.NormalizeRecoveryCode(code)
+	}
+
+	id := app.authenticatedUserID(r)
+	err = app.userModel.EnableTOTP(r.Context(), id, secret, normalized)
+	if err != nil {
+		return err
+	}
+
+	// The confirmation code must not be usable to log in.
+	_, err = app.userModel.UseTOTPStep(r.Context(), id, step)
+	if err != nil {
+		return err
+	}
+	app.sessionManager.Remove(r.Context(), "totpSecret")
+
+	data := app.newTemplateData(r)
+	data.RecoveryCodes = codes
+	app.render(w, r, "account/recovery_codes.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// totpDisablePost turns off two-factor authentication after checking the
+// password of the user.
+func (app *App) totpDisablePost(w http.ResponseWriter, r *http.Request) error {
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	var form PasswordForm
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	err = app.checkPassword(r, &form.Validator, "password", user, form.Password)
+	if err != nil {
+		return err
+	}
+
+	if !form.Valid() {
+		left, err := app.userModel.RecoveryCodesLeft(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+
+		data := app.newTemplateData(r)
+		data.User = user
+		data.RecoveryCodesLeft = left
+		data.Form = form
+		app.render(w, r, "account/security.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	err = app.userModel.DisableTOTP(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Two-factor authentication is disabled."))
+	http.Redirect(w, r, "/account/security", http.StatusSeeOther)
+	return nil
+}
+
+// tokenList renders the API tokens of the user together with the form creating a new one.
+func (app *App) tokenList(w http.ResponseWriter, r *http.Request) error {
+	tokens, err := app.apiTokenModel.List(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.APITokens = tokens
+	data.APITokenScopes = models.APITokenScopes
+	data.Form = APITokenForm{ExpiresIn: 30}
+	app.render(w, r, "account/tokens.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// tokenCreatePost validates the API token form and creates a new token. The token is
+// rendered in the response, as it cannot be retrieved once the page is left.
+func (app *App) tokenCreatePost(w http.ResponseWriter, r *http.Request) error {
+	var form APITokenForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field is required.")
+	form.CheckField(validator.MaxChars(form.Name, maxTokenNameLength), "name", "The name must be at most %d characters.", maxTokenNameLength)
+	form.CheckField(len(form.Scopes) > 0, "scopes", "Select at least one scope.")
+	for _, scope := range form.Scopes {
+		form.CheckField(validator.PermittedValue(scope, models.APITokenScopes...), "scopes", "Unknown scope.")
+	}
+	form.CheckField(validator.PermittedValue(form.ExpiresIn, apiTokenLifetimes...), "expiresIn", "Unknown expiration.")
+
+	userID := app.authenticatedUserID(r)
+
+	var token string
+	if form.Valid() {
+		var expiresAt time.Time
+		if form.ExpiresIn > 0 {
+			expiresAt = time.Now().AddDate(0, 0, form.ExpiresIn)
+		}
+
+		token, err = app.apiTokenModel.Create(r.Context(), userID, form.Name, form.Scopes, expiresAt)
+		if err != nil {
+			return err
+		}
+		form = APITokenForm{ExpiresIn: 30}
+	}
+
+	tokens, err := app.apiTokenModel.List(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if token == "" {
+		status = http.StatusUnprocessableEntity
+	}
+
+	data := app.newTemplateData(r)
+	data.APITokens = tokens
+	data.APITokenScopes = models.APITokenScopes
+	data.NewAPIToken = token
+	data.Form = form
+	app.render(w, r, "account/tokens.tmpl", data, status)
+	return nil
+}
+
+// tokenDelete revokes an API token of the user.
+func (app *App) tokenDelete(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	err = app.apiTokenModel.Revoke(r.Context(), app.authenticatedUserID(r), id)
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("The token was revoked."))
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+	return nil
+}
+
+// sessionList renders the active sessions of the user, from which other devices can be
+// signed out.
+func (app *App) sessionList(w http.ResponseWriter, r *http.Request) error {
+	sessions, err := app.sessionModel.List(r.Context(), app.authenticatedUserID(r), app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.Sessions = sessions
+	app.render(w, r, "account/sessions.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// sessionDelete signs out one of the sessions of the user. The device is logged out on its
+// next request.
+func (app *App) sessionDelete(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	err = app.sessionModel.Revoke(r.Context(), app.authenticatedUserID(r), id)
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("The device was signed out."))
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+	return nil
+}
+
+// sessionDeleteAll signs out every session of the user, including the current one.
+func (app *App) sessionDeleteAll(w http.ResponseWriter, r *http.Request) error {
+	_, err := app.sessionModel.RevokeAll(r.Context(), app.authenticatedUserID(r), "")
+	if err != nil {
+		return err
+	}
+
+	err = app.sessionManager.Destroy(r.Context())
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	return nil
+}
+
+// passwordChange renders the form changing the password of the user.
+func (app *App) passwordChange(w http.ResponseWriter, r *http.Request) error {
+	data := app.newTemplateData(r)
+	data.Form = PasswordChangeForm{}
+	app.render(w, r, "account/password.tmpl", data, http.StatusOK)
+	return nil
+}
+
+// passwordChangePost changes the password of the user once the current one is confirmed.
+// Every other session of the user is signed out, in case the old password leaked.
+func (app *App) passwordChangePost(w http.ResponseWriter, r *http.Request) error {
+	var form PasswordChangeForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	form.CheckField(validator.NotBlank(form.CurrentPassword), "currentPassword", "This field is required.")
+	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "This field is required.")
+	form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "Password must be at least 8 characters.")
+
+	if form.Valid() {
+		err = app.checkPassword(r, &form.Validator, "currentPassword", user, form.CurrentPassword)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, "account/password.tmpl", data, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	err = app.userModel.UpdatePassword(r.Context(), user.ID, form.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := app.sessionModel.RevokeAll(r.Context(), user.ID, app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return err
+	}
+
+	err = app.auditModel.Record(r.Context(), user.ID, models.AuditPasswordChanged, clientIP(r), fmt.Sprintf("%d other sessions signed out", revoked))
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Your password was changed and your other sessions were signed out."))
+	http.Redirect(w, r, "/account/security", http.StatusSeeOther)
+	return nil
+}
+
+// account renders the profile page of the user, where they change their
+// name and email or delete their account.
+func (app *App) account(w http.ResponseWriter, r *http.Request) error {
+	return app.renderAccount(w, r, ProfileForm{}, http.StatusOK)
+}
+
+// renderAccount renders the profile page of the authenticated user with the
+// form holding the errors of the last submission.
+func (app *App) renderAccount(w http.ResponseWriter, r *http.Request, form any, status int) error {
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	latest, err := app.exportModel.Latest(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return err
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Export = latest
+	data.Form = form
+	app.render(w, r, "account/profile.tmpl", data, status)
+	return nil
+}
+
+// accountProfilePost changes the name of the user.
+func (app *App) accountProfilePost(w http.ResponseWriter, r *http.Request) error {
+	var form ProfileForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field is required.")
+	form.CheckField(validator.MaxChars(form.Name, maxNameLength), "name", "This field cannot be more than %d characters long.", maxNameLength)
+
+	if !form.Valid() {
+		return app.renderAccount(w, r, form, http.StatusUnprocessableEntity)
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	user.Name = form.Name
+	err = app.userModel.Update(r.Context(), user)
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Your name was changed."))
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+// accountEmailPost starts the change of the email of the user. The new email
+// is only used once confirmed with the link sent to it, and the current
+// address is told about the request in case the account was taken over.
+func (app *App) accountEmailPost(w http.ResponseWriter, r *http.Request) error {
+	var form EmailChangeForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	form.Email = strings.TrimSpace(form.Email)
+	form.CheckField(validator.NotBlank(form.Email), "newEmail", "This field is required.")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "newEmail", "The email address is not valid.")
+	form.CheckField(form.Email != user.Email, "newEmail", "This is already your email address.")
+	if user.HasPassword {
+		form.CheckField(validator.NotBlank(form.Password), "emailPassword", "This field is required.")
+	}
+
+	if form.Valid() && user.HasPassword {
+		err = app.checkPassword(r, &form.Validator, "emailPassword", user, form.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	var token string
+	if form.Valid() {
+		token, err = app.userModel.RequestEmailChange(r.Context(), user.ID, form.Email, time.Now().Add(emailChangeLifetime))
+		if err != nil && !errors.Is(err, models.ErrDuplicateEmail) {
+			return err
+		}
+		form.CheckField(err == nil, "newEmail", "This email address is already registered.")
+	}
+
+	if !form.Valid() {
+		return app.renderAccount(w, r, form, http.StatusUnprocessableEntity)
+	}
+
+	link := baseURL + "/account/email/verify?token=" + token
+	err = app.mailer.Send(r.Context(), mail.Message{
+		To:      form.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link within %d hours to use this address for your Event Planner account:\n\n%s\n",
+			user.Name, int(emailChangeLifetime.Hours()), link),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = app.mailer.Send(r.Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nA change of the email address of your Event Planner account to %s was requested. "+
+			"If this was not you, change your password and sign out your other sessions.\n", user.Name, form.Email),
+	})
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("We sent a confirmation link to %s.", form.Email))
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+// accountEmailVerify confirms a change of email with the token of the link
+// sent to the new address. The link works whether or not the user is logged
+// in, as it may be opened on another device.
+func (app *App) accountEmailVerify(w http.ResponseWriter, r *http.Request) error {
+	redirect := "/login"
+	if app.isAuthenticated(r) {
+		redirect = "/account"
+	}
+
+	userID, oldEmail, newEmail, err := app.userModel.ConfirmEmailChange(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("The confirmation link is not valid or has expired."))
+		case errors.Is(err, models.ErrDuplicateEmail):
+			app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("This email address is already registered."))
+		default:
+			return err
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return nil
+	}
+
+	err = app.auditModel.Record(r.Context(), userID, models.AuditEmailChanged, clientIP(r), fmt.Sprintf("%s to %s", oldEmail, newEmail))
+	if err != nil {
+		return err
+	}
+
+	err = app.mailer.Send(r.Context(), mail.Message{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body:    fmt.Sprintf("The email address of your Event Planner account was changed to %s.\n", newEmail),
+	})
+	if err != nil {
+		app.loggerFor(r).Error(err.Error(), "user", userID)
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Your email address is now %s.", newEmail))
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+	return nil
+}
+
+// accountDeletePost deletes the account of the user once confirmed with their
+// email and password, following the policy of UserModel.Delete: events others
+// attend are kept without an owner, the rest of the user's data is deleted.
+func (app *App) accountDeletePost(w http.ResponseWriter, r *http.Request) error {
+	var form AccountDeleteForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		return statusError(http.StatusBadRequest, err)
+	}
+
+	user, err := app.userModel.Retrieve(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		return err
+	}
+
+	form.CheckField(strings.EqualFold(strings.TrimSpace(form.Email), user.Email), "confirmEmail", "Type the email address of your account.")
+	if user.HasPassword {
+		form.CheckField(validator.NotBlank(form.Password), "deletePassword", "This field is required.")
+	}
+
+	if form.Valid() && user.HasPassword {
+		err = app.checkPassword(r, &form.Validator, "deletePassword", user, form.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !form.Valid() {
+		return app.renderAccount(w, r, form, http.StatusUnprocessableEntity)
+	}
+
+	exports, err := app.exportModel.Prune(r.Context(), time.Now(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := app.userModel.Delete(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		app.deleteStoredAttachment(r, a)
+	}
+	for _, key := range exports {
+		err := app.storage.Delete(r.Context(), key)
+		if err != nil {
+			app.loggerFor(r).Error(err.Error(), "key", key)
+		}
+	}
+
+	// The account is gone, so the audit event is not linked to it.
+	err = app.auditModel.Record(r.Context(), 0, models.AuditAccountDeleted, clientIP(r), fmt.Sprintf("user %d", user.ID))
+	if err != nil {
+		app.loggerFor(r).Error(err.Error(), "user", user.ID)
+	}
+
+	err = app.sessionManager.Destroy(r.Context())
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+// accountExportPost queues an export of the personal data of the user. The
+// archive is built in the background and its download link sent by email.
+func (app *App) accountExportPost(w http.ResponseWriter, r *http.Request) error {
+	userID := app.authenticatedUserID(r)
+
+	_, err := app.exportModel.Request(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, models.ErrExportInProgress) {
+			app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("Your data is already being prepared."))
+			http.Redirect(w, r, "/account", http.StatusSeeOther)
+			return nil
+		}
+		return err
+	}
+
+	err = app.auditModel.Record(r.Context(), userID, models.AuditExportRequested, clientIP(r), "")
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("We are preparing your data. You will receive an email with a download link."))
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+// accountExportDownload serves the archive of a data export through the link
+// sent to the user. The link only works for the user it was sent to, until
+// the export expires. The archive is streamed from the storage, under the
+// longer deadline of file transfers.
+func (app *App) accountExportDownload(w http.ResponseWriter, r *http.Request) error {
+	e, err := app.exportModel.Download(r.Context(), app.authenticatedUserID(r), r.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", app.printer(r).Sprintf("The download link is not valid or has expired."))
+			http.Redirect(w, r, "/account", http.StatusSeeOther)
+			return nil
+		}
+		return err
+	}
+
+	content, err := app.storage.Get(r.Context(), e.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	filename := "event-planner-data-" + e.CompletedAt.Format("2006-01-02") + ".zip"
+
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Content-Length", strconv.FormatInt(e.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		app.loggerFor(r).Error(err.Error(), "export", e.ID)
+	}
+	return nil
 }
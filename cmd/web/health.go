@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/database"
+	"github.com/madalinpopa/go-event-planner/internal/backup"
+	"github.com/madalinpopa/go-event-planner/internal/health"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// healthTimeout bounds the time of each health check.
+const healthTimeout = 2 * time.Second
+
+// heartbeatGrace is added to three intervals of a worker to get the time after
+// which its heartbeat is stale, so that a slow pass, such as webhook deliveries
+// waiting on their timeouts, is not taken for a stuck worker.
+const heartbeatGrace = 10 * time.Minute
+
+// newHeartbeat returns the heartbeat of a worker running every interval.
+func newHeartbeat(interval time.Duration) *health.Heartbeat {
+	return health.NewHeartbeat(3*interval + heartbeatGrace)
+}
+
+// errShuttingDown fails the readiness check once the server is shutting down.
+var errShuttingDown = errors.New("the server is shutting down")
+
+// readinessChecks returns the checks of /readyz: whether the server is shutting
+// down, whether the database can be reached and has every migration applied,
+// and, for SQLite, whether the volume of the database has minDisk bytes left.
+func (app *App) readinessChecks(postgres bool, minDisk uint64) ([]health.Check, error) {
+	dir := "migrations"
+	if postgres {
+		dir = "migrations/postgres"
+	}
+	versions, err := backup.Versions(database.Migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []health.Check{
+		{Name: "shutdown", Run: func(context.Context) error {
+			if app.shuttingDown.Load() {
+				return errShuttingDown
+			}
+			return nil
+		}},
+		{Name: "database", Run: app.db.PingContext},
+		{Name: "migrations", Run: func(ctx context.Context) error {
+			version, err := backup.SchemaVersion(ctx, app.db)
+			if err != nil {
+				return err
+			}
+			pending, err := backup.Pending(version, versions)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations are pending", pending)
+			}
+			return nil
+		}},
+	}
+
+	if !postgres {
+		checks = append(checks, health.Check{Name: "disk", Run: health.DiskSpace(filepath.Dir(dbDSN), minDisk)})
+	}
+	return checks, nil
+}
+
+// healthz serves the liveness of the application: whether its background workers
+// are still running. It does not depend on the database, whose outage a restart
+// would not fix.
+func (app *App) healthz() http.Handler {
+	return health.Handler(healthTimeout, app.liveness...)
+}
+
+// readyz serves the readiness of the application to handle requests.
+func (app *App) readyz() http.Handler {
+	return health.Handler(healthTimeout, app.readiness...)
+}
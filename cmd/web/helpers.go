@@ -1,30 +1,136 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/justinas/nosurf"
+	"github.com/madalinpopa/go-event-planner/internal/i18n"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/oidc"
+	"github.com/madalinpopa/go-event-planner/internal/validator"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	"path"
 	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-// serverError logs an internal server error and sends a 500 status response with a generic error message to the client.
+const (
+	// maxUploadSize is the largest request body accepted when uploading attachments.
+	maxUploadSize = 10 << 20
+
+	// thumbnailSize is the largest width and height of generated thumbnails, in pixels.
+	thumbnailSize = 640
+
+	// maxDescriptionLength is the longest Markdown source accepted for event descriptions.
+	maxDescriptionLength = 10_000
+
+	// totpIssuer names the application in authenticator apps.
+	totpIssuer = "Event Planner"
+
+	// recoveryCodeCount is the number of recovery codes generated when
+	// enabling two-factor authentication, and recoveryCodeLength their
+	// length once normalized.
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+
+	// totpLoginTimeout bounds the time between the two steps of a login.
+	totpLoginTimeout = 5 * time.Minute
+
+	// maxTokenNameLength is the longest name accepted for API tokens.
+	maxTokenNameLength = 100
+
+	// maxNameLength is the longest name accepted for users.
+	maxNameLength = 100
+
+	// emailChangeLifetime bounds the time to confirm a new email with the
+	// link sent to it.
+	emailChangeLifetime = 24 * time.Hour
+)
+
+// apiTokenLifetimes lists the lifetimes in days offered for API tokens, 0 for no expiry.
+var apiTokenLifetimes = []int{7, 30, 90, 365, 0}
+
+// attachmentContentTypes lists the sniffed content types accepted for each kind of attachment.
+var attachmentContentTypes = map[string][]string{
+	models.AttachmentCover: {"image/png", "image/jpeg", "image/gif"},
+	models.AttachmentFile:  {"application/pdf", "image/png", "image/jpeg"},
+}
+
+// statusClientClosedRequest is the status logged for requests canceled by the
+// client before the response was written, as nginx does. Nothing is sent.
+const statusClientClosedRequest = 499
+
+// serverError logs an internal server error and sends a 500 status response with the error page to the client.
+// Errors caused by the end of the request context are not server faults: requests which ran
+// past their deadline get a 503 response, and requests canceled by the client a 499 status.
 func (app *App) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	if status := contextStatus(r, err); status != 0 {
+		app.loggerFor(r).Warn(err.Error(), "method", r.Method, "url", r.URL.RequestURI(), "status", status)
+		if status == http.StatusServiceUnavailable {
+			app.errorResponse(w, r, status, err)
+		} else {
+			w.WriteHeader(status)
+		}
+		return
+	}
+
 	var (
 		method = r.Method
 		url    = r.URL.RequestURI()
 		trace  = string(debug.Stack())
 	)
 
-	app.logger.Error(err.Error(), "method", method, "url", url, "trace", trace)
-	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	app.loggerFor(r).Error(err.Error(), "method", method, "url", url, "trace", trace)
+	app.errorResponse(w, r, http.StatusInternalServerError, err)
+}
+
+// contextStatus returns the status of a request which failed because its context ended:
+// 503 once its deadline passed and 499 once the client went away, or 0 otherwise. The
+// drivers do not always return the error of the context when they interrupt a query, so
+// the context of the request is checked as well.
+func contextStatus(r *http.Request, err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled), errors.Is(r.Context().Err(), context.Canceled):
+		return statusClientClosedRequest
+	default:
+		return 0
+	}
 }
 
-// clientError logs client-side errors and sends the corresponding HTTP status code and message to the client.
+// clientError logs client-side errors and sends the corresponding HTTP status code and error page to the client.
+// Requests for pages which do not exist are common enough, from crawlers and stale links, to
+// be logged as information only.
 func (app *App) clientError(w http.ResponseWriter, r *http.Request, status int, err error) {
 	var (
 		method = r.Method
 		url    = r.URL.RequestURI()
+		level  = slog.LevelError
 	)
-	app.logger.Error(err.Error(), "method", method, "url", url, "status", status)
-	http.Error(w, http.StatusText(status), status)
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		level = slog.LevelInfo
+	}
+	app.loggerFor(r).Log(r.Context(), level, err.Error(), "method", method, "url", url, "status", status)
+	app.errorResponse(w, r, status, err)
+}
+
+// tokenError rejects a request authenticated with an API token, with the error code of
+// RFC 6750 in the WWW-Authenticate header of the response.
+func (app *App) tokenError(w http.ResponseWriter, r *http.Request, status int, code string, err error) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", code))
+	app.clientError(w, r, status, err)
 }
 
 func (app *App) isAuthenticated(r *http.Request) bool {
@@ -35,3 +141,390 @@ func (app *App) isAuthenticated(r *http.Request) bool {
 
 	return isAuthenticated
 }
+
+// isAdmin reports whether the request comes from an authenticated administrator.
+func (app *App) isAdmin(r *http.Request) bool {
+	isAdmin, ok := r.Context().Value(isAdminContextKey).(bool)
+	if !ok {
+		return false
+	}
+
+	return isAdmin
+}
+
+// canEditEvent reports whether the request comes from the owner of the event or an administrator.
+func (app *App) canEditEvent(r *http.Request, event models.Event) bool {
+	if app.isAdmin(r) {
+		return true
+	}
+	return event.UserID != 0 && event.UserID == app.authenticatedUserID(r)
+}
+
+// loggerFor returns the logger of the request, whose records carry the ID of the request
+// and, once authenticated, the ID of its user.
+func (app *App) loggerFor(r *http.Request) *slog.Logger {
+	info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo)
+	if !ok {
+		return app.logger
+	}
+	if info.userID != 0 {
+		return app.logger.With("request_id", info.id, "user_id", info.userID)
+	}
+	return app.logger.With("request_id", info.id)
+}
+
+// authenticatedUserID returns the ID of the user authenticated by the session or by an API
+// token, or 0 if no user is logged in.
+func (app *App) authenticatedUserID(r *http.Request) int {
+	id, ok := r.Context().Value(userIDContextKey).(int)
+	if !ok {
+		return 0
+	}
+
+	return id
+}
+
+// apiToken returns the API token which authenticated the request, if any.
+func (app *App) apiToken(r *http.Request) (models.APIToken, bool) {
+	token, ok := r.Context().Value(apiTokenContextKey).(models.APIToken)
+	return token, ok
+}
+
+// authenticatedUser returns the user authenticated by the session or by an API token, or the
+// zero user if no user is logged in.
+func (app *App) authenticatedUser(r *http.Request) models.User {
+	user, _ := r.Context().Value(userContextKey).(models.User)
+	return user
+}
+
+// newTemplateData returns the data shared by every page rendered for the request: the flash
+// message, removed from the session, the CSRF token and the authenticated user. Handlers add
+// the data of their page to it.
+func (app *App) newTemplateData(r *http.Request) templateData {
+	data := templateData{
+		Title:           "Event Planner",
+		CurrentYear:     time.Now().Year(),
+		CSRFToken:       nosurf.Token(r),
+		IsAuthenticated: app.isAuthenticated(r),
+		User:            app.authenticatedUser(r),
+		OIDCEnabled:     app.oidc != nil,
+	}
+
+	// Error pages are also rendered for requests which never reached the session middleware.
+	if loaded, _ := r.Context().Value(sessionContextKey).(bool); loaded {
+		data.Flash = app.sessionManager.PopString(r.Context(), "flash")
+	}
+
+	return data
+}
+
+// localeCookie is the cookie remembering the locale chosen by visitors.
+const localeCookie = "lang"
+
+// printer returns the printer of the locale of the request: the one chosen by
+// the authenticated user, then the one remembered by the locale cookie, then
+// the one preferred by the Accept-Language header.
+func (app *App) printer(r *http.Request) *i18n.Printer {
+	if locale, ok := r.Context().Value(localeContextKey).(string); ok && i18n.Supported(locale) {
+		return i18n.NewPrinter(locale)
+	}
+	if cookie, err := r.Cookie(localeCookie); err == nil && i18n.Supported(cookie.Value) {
+		return i18n.NewPrinter(cookie.Value)
+	}
+	return i18n.NewPrinter(i18n.Match(r.Header.Get("Accept-Language")))
+}
+
+// decodePostForm decodes the parsed form of the request into dst, and sets the
+// printer of the request as the translator of the validation errors of forms.
+func (app *App) decodePostForm(r *http.Request, dst any) error {
+	err := app.formDecoder.Decode(dst, r.PostForm)
+	if err != nil {
+		return err
+	}
+
+	if form, ok := dst.(interface{ SetTranslator(validator.Translator) }); ok {
+		form.SetTranslator(app.printer(r))
+	}
+	return nil
+}
+
+// checkEventVenue validates the venue selected in the event form against the
+// known venues. The location is required for events without a venue, and
+// defaults to the name of the venue otherwise.
+func checkEventVenue(form *EventForm, venues []models.Venue) {
+	if form.VenueID == 0 {
+		form.CheckField(validator.NotBlank(form.Location), "location", "This field is required.")
+		return
+	}
+
+	i := slices.IndexFunc(venues, func(v models.Venue) bool { return v.ID == form.VenueID })
+	if i < 0 {
+		form.AddFieldError("venueID", "Select a venue from the list.")
+		return
+	}
+
+	if !validator.NotBlank(form.Location) {
+		form.Location = venues[i].Name
+	}
+}
+
+// checkVenueForm validates the venue form and returns the venue it describes.
+// Amenities are entered as a comma separated list.
+func checkVenueForm(form *VenueForm) models.Venue {
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field is required.")
+	form.CheckField(validator.MaxChars(form.Name, 255), "name", "The name must be at most 255 characters.")
+	form.CheckField(validator.MaxChars(form.Address, 255), "address", "The address must be at most 255 characters.")
+	form.CheckField(form.Capacity >= 0, "capacity", "The capacity must not be negative.")
+
+	venue := models.Venue{
+		Name:     strings.TrimSpace(form.Name),
+		Address:  strings.TrimSpace(form.Address),
+		Capacity: form.Capacity,
+	}
+
+	for _, amenity := range strings.Split(form.Amenities, ",") {
+		amenity = strings.TrimSpace(amenity)
+		if amenity != "" && !slices.Contains(venue.Amenities, amenity) {
+			venue.Amenities = append(venue.Amenities, amenity)
+		}
+	}
+
+	if form.Latitude == "" && form.Longitude == "" {
+		return venue
+	}
+
+	latitude, err := strconv.ParseFloat(form.Latitude, 64)
+	form.CheckField(err == nil && latitude >= -90 && latitude <= 90, "latitude", "The latitude must be a number between -90 and 90.")
+	longitude, err := strconv.ParseFloat(form.Longitude, 64)
+	form.CheckField(err == nil && longitude >= -180 && longitude <= 180, "longitude", "The longitude must be a number between -180 and 180.")
+	venue.Coordinates = &models.Coordinates{Latitude: latitude, Longitude: longitude}
+
+	return venue
+}
+
+// newVenueForm returns the venue form pre-filled with the details of the venue.
+func newVenueForm(v models.Venue) VenueForm {
+	form := VenueForm{
+		Name:      v.Name,
+		Address:   v.Address,
+		Capacity:  v.Capacity,
+		Amenities: strings.Join(v.Amenities, ", "),
+	}
+	if v.Coordinates != nil {
+		form.Latitude = strconv.FormatFloat(v.Coordinates.Latitude, 'f', -1, 64)
+		form.Longitude = strconv.FormatFloat(v.Coordinates.Longitude, 'f', -1, 64)
+	}
+	return form
+}
+
+// startSession authenticates the session as the user after a successful login. The token is
+// renewed to prevent session fixation, and the session is recorded so that the user can see
+// and revoke it from the sessions page.
+func (app *App) startSession(r *http.Request, id int) error {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Remove(r.Context(), "pendingUserID")
+	app.sessionManager.Remove(r.Context(), "pendingUserSince")
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+
+	expiresAt := time.Now().Add(app.sessionManager.Lifetime)
+	return app.sessionModel.Create(r.Context(), app.sessionManager.Token(r.Context()), id, r.UserAgent(), clientIP(r), expiresAt)
+}
+
+// startPendingLogin records the user as having passed the first login step. The session is only
+// authenticated by userLoginTOTPPost, once the two-factor authentication code is verified.
+func (app *App) startPendingLogin(r *http.Request, id int) {
+	app.sessionManager.Put(r.Context(), "pendingUserID", id)
+	app.sessionManager.Put(r.Context(), "pendingUserSince", time.Now().Unix())
+}
+
+// pendingUserID returns the ID of the user who passed the first login step
+// and still has to enter a two-factor authentication code, or 0 if there is
+// none or the login timed out.
+func (app *App) pendingUserID(r *http.Request) int {
+	id := app.sessionManager.GetInt(r.Context(), "pendingUserID")
+	since := app.sessionManager.GetInt64(r.Context(), "pendingUserSince")
+
+	if id != 0 && time.Since(time.Unix(since, 0)) > totpLoginTimeout {
+		app.sessionManager.Remove(r.Context(), "pendingUserID")
+		app.sessionManager.Remove(r.Context(), "pendingUserSince")
+		return 0
+	}
+	return id
+}
+
+// recordLoginFailure counts a failed login for the account with the email,
+// and records an audit event when it gets locked as a result.
+func (app *App) recordLoginFailure(r *http.Request, email string) error {
+	userID, locked, err := app.userModel.RecordLoginFailure(r.Context(), email, loginMaxFailures, loginLockout)
+	if err != nil || !locked {
+		return err
+	}
+
+	ip := clientIP(r)
+	detail := fmt.Sprintf("locked for %s after %d failed logins", loginLockout, loginMaxFailures)
+	err = app.auditModel.Record(r.Context(), userID, models.AuditAccountLocked, ip, detail)
+	if err != nil {
+		return err
+	}
+
+	app.loggerFor(r).Warn("account locked", "user", userID, "ip", ip)
+	return nil
+}
+
+// checkPassword checks the password of the user, asked again before changes to the account, and
+// adds an error for the field of the form when it is wrong. The attempts share the limit of the
+// logins with the email of the user, so that a session left open cannot be used to guess the
+// password.
+func (app *App) checkPassword(r *http.Request, form *validator.Validator, field string, user models.User, password string) error {
+	allowed, err := app.loginEmailLimiter.Allow(r.Context(), strings.ToLower(user.Email))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		form.AddFieldError(field, "Too many attempts. Please try again later.")
+		return nil
+	}
+
+	_, err = app.userModel.Authenticate(r.Context(), user.Email, password)
+	if err != nil && !errors.Is(err, models.ErrInvalidCredentials) {
+		return err
+	}
+	form.CheckField(err == nil, field, "The password is not correct.")
+	return nil
+}
+
+// clientIP returns the IP address of the client, without the port. Proxy
+// headers are not trusted, as clients could use them to evade limits.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// errUnverifiedEmail is returned by oidcUser when the identity provider did not verify
+// the email of a user seen for the first time.
+var errUnverifiedEmail = errors.New("oidc: email not verified")
+
+// oidcUser returns the ID of the user logging in with the claims of a verified ID token.
+// A subject seen for the first time is linked to the user with the same email, or a new
+// user is provisioned; both require an email verified by the provider.
+func (app *App) oidcUser(r *http.Request, claims oidc.Claims) (int, error) {
+	id, err := app.userModel.RetrieveByIdentity(r.Context(), claims.Issuer, claims.Subject)
+	if !errors.Is(err, models.ErrNoRecord) {
+		return id, err
+	}
+
+	if !claims.EmailVerified || !validator.Matches(claims.Email, validator.EmailRX) {
+		return 0, fmt.Errorf("%w: %q", errUnverifiedEmail, claims.Email)
+	}
+
+	detail := fmt.Sprintf("%s %s", claims.Issuer, claims.Subject)
+
+	id, err = app.userModel.LinkIdentity(r.Context(), claims.Email, claims.Issuer, claims.Subject)
+	if err == nil {
+		return id, app.auditModel.Record(r.Context(), id, models.AuditIdentityLinked, clientIP(r), detail)
+	}
+	if !errors.Is(err, models.ErrNoRecord) {
+		return 0, err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	id, err = app.userModel.CreateFromIdentity(r.Context(), name, claims.Email, claims.Issuer, claims.Subject)
+	if err != nil {
+		return 0, err
+	}
+	return id, app.auditModel.Record(r.Context(), id, models.AuditIdentityProvisioned, clientIP(r), detail)
+}
+
+// randomHex returns n random bytes encoded as a hexadecimal string.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// attachmentFilename reduces a client supplied file name to its base name, limited to 255 characters.
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "attachment"
+	}
+	for utf8.RuneCountInString(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// deleteStoredAttachment removes the content and thumbnail of an attachment from the storage.
+// Failures are only logged, as the attachment record is already gone or was never created.
+func (app *App) deleteStoredAttachment(r *http.Request, a models.Attachment) {
+	for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		err := app.storage.Delete(r.Context(), key)
+		if err != nil {
+			app.loggerFor(r).Error(err.Error(), "attachment", a.ID, "key", key)
+		}
+	}
+}
+
+// serveAttachment writes the content, or the thumbnail, of the attachment identified by the ID in
+// the URL path. Files other than cover images require an authenticated user.
+func (app *App) serveAttachment(w http.ResponseWriter, r *http.Request, thumb bool) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errNotFound
+	}
+
+	a, err := app.attachmentModel.Retrieve(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if a.Kind != models.AttachmentCover && !app.isAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil
+	}
+
+	key, contentType, disposition := a.StorageKey, a.ContentType, "attachment"
+	if thumb {
+		if a.ThumbnailKey == "" {
+			return errNotFound
+		}
+		key, contentType = a.ThumbnailKey, "image/jpeg"
+	}
+	if a.Kind == models.AttachmentCover || thumb {
+		disposition = "inline"
+	}
+
+	content, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		app.loggerFor(r).Error(err.Error(), "attachment", a.ID)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"github.com/madalinpopa/go-event-planner/internal/ratelimit"
+	"github.com/madalinpopa/go-event-planner/internal/validator"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckPassword(t *testing.T) {
+	ctx := context.Background()
+	db := modeltest.OpenDB(t)
+	users := &models.UserModel{DB: db}
+
+	err := users.Create(ctx, "Alice", "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := users.Authenticate(ctx, "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := users.Retrieve(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{
+		userModel: users,
+		config: config{
+			loginEmailLimiter: &ratelimit.Memory{Bucket: ratelimit.Bucket{Burst: 3, Every: time.Hour}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		{name: "Correct", password: "password123"},
+		{name: "Wrong", password: "wrong-password", want: "The password is not correct."},
+		{name: "Last attempt", password: "password123"},
+		{name: "Throttled", password: "password123", want: "Too many attempts. Please try again later."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form validator.Validator
+			r := httptest.NewRequest("POST", "/account/password", nil)
+
+			err := app.checkPassword(r, &form, "password", user, tt.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := form.FieldErrors["password"]; got != tt.want {
+				t.Errorf("got error %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
-	"github.com/alexedwards/scs/sqlite3store"
+	"fmt"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
+	"github.com/madalinpopa/go-event-planner/internal/backup"
+	"github.com/madalinpopa/go-event-planner/internal/calendar"
+	"github.com/madalinpopa/go-event-planner/internal/export"
+	"github.com/madalinpopa/go-event-planner/internal/health"
+	"github.com/madalinpopa/go-event-planner/internal/mail"
 	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/oidc"
+	"github.com/madalinpopa/go-event-planner/internal/ratelimit"
+	"github.com/madalinpopa/go-event-planner/internal/scheduler"
+	"github.com/madalinpopa/go-event-planner/internal/storage"
+	"github.com/madalinpopa/go-event-planner/internal/tracing"
+	"github.com/madalinpopa/go-event-planner/internal/webhook"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -20,38 +38,205 @@ var (
 	// port defines the default port the server listens on,
 	// typically overridden via a command-line flag.
 	port string
+
+	// reminderOffsets lists how long before an event the attendees are
+	// reminded, as a comma separated list of durations.
+	reminderOffsets string
+
+	// reminderInterval defines how often the scheduler scans for due reminders.
+	reminderInterval time.Duration
+
+	// webhookInterval defines how often the webhook delivery queue is scanned.
+	webhookInterval time.Duration
+
+	// webhookMaxAttempts is the number of attempts before a delivery is dead.
+	webhookMaxAttempts int
+
+	// storageBackend selects where uploaded files are kept, "local" or "s3".
+	storageBackend string
+
+	// storageDir is the directory of the local storage backend.
+	storageDir string
+
+	// s3Endpoint, s3Bucket and s3Region configure the s3 storage backend.
+	// The credentials are read from the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY environment variables.
+	s3Endpoint string
+	s3Bucket   string
+	s3Region   string
+
+	// loginIPBurst and loginEmailBurst are the login attempts allowed in a
+	// row from an IP address and for an email; one attempt is regained
+	// every loginRefill.
+	loginIPBurst    int
+	loginEmailBurst int
+	loginRefill     time.Duration
+
+	// loginMaxFailures is the number of consecutive failed logins after
+	// which an account is locked for loginLockout.
+	loginMaxFailures int
+	loginLockout     time.Duration
+
+	// oidcIssuer, oidcClientID and oidcRedirectURL enable the single sign-on
+	// login with an OpenID Connect provider. The client secret is read from
+	// the OIDC_CLIENT_SECRET environment variable.
+	oidcIssuer      string
+	oidcClientID    string
+	oidcRedirectURL string
+
+	// oidcMock starts an in-process mock provider for the single sign-on
+	// login, which authenticates anyone. Only meant for development, and
+	// only available in builds with the dev tag.
+	oidcMock bool
+
+	// baseURL is the public URL of the application, used to build the
+	// links sent by email.
+	baseURL string
+
+	// exportInterval defines how often the queue of data exports is scanned,
+	// and exportLifetime how long a built export can be downloaded.
+	exportInterval time.Duration
+	exportLifetime time.Duration
+
+	// backupDir is where the database is backed up every backupInterval,
+	// keeping the backupKeep newest backups. A zero interval disables the
+	// scheduled backups.
+	backupDir      string
+	backupInterval time.Duration
+	backupKeep     int
+
+	// requestTimeout bounds the time spent on a request, after which its
+	// queries are interrupted and a 503 response is sent. It is shorter
+	// than the write timeout of the server, so that the response can still
+	// be written.
+	requestTimeout time.Duration
+
+	// metricsAddr is the address serving /metrics instead of the port of
+	// the application, when set.
+	metricsAddr string
+
+	// minDiskMB is the free space, in megabytes, below which the volume of
+	// the SQLite database fails the readiness check.
+	minDiskMB uint64
+
+	// shutdownDelay is how long the server keeps serving once asked to stop,
+	// with its readiness check failing, so that load balancers stop sending
+	// it requests before the listener is closed.
+	shutdownDelay time.Duration
+
+	// traceExporter selects where the traces are exported: "none", "stdout"
+	// or "otlp".
+	traceExporter string
+
+	// logFormat selects the format of the logs, "text" or "json".
+	logFormat string
+
+	// dbDSN selects the database: the path of an SQLite file, or a
+	// postgres:// URL for PostgreSQL.
+	dbDSN string
 )
 
+// writeTimeout bounds the time to handle a request and write its response.
+const writeTimeout = 10 * time.Second
+
+// transferTimeout bounds the time to upload or download a file, which can take
+// longer than the write timeout on slow connections.
+const transferTimeout = 2 * time.Minute
+
+// shutdownTimeout bounds the time to complete the requests in flight when the
+// server shuts down.
+const shutdownTimeout = 15 * time.Second
+
 // config is a struct that encapsulates application-wide dependencies,
 // such as logging and template rendering.
 type config struct {
 	db             *sql.DB
 	logger         *slog.Logger
-	templates      map[string]*template.Template
+	templates      map[string]map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
+	storage        storage.Storage
+
+	// loginIPLimiter and loginEmailLimiter throttle login attempts.
+	loginIPLimiter    ratelimit.Limiter
+	loginEmailLimiter ratelimit.Limiter
+
+	// oidc is the registration with the OpenID Connect provider, nil when
+	// single sign-on is disabled.
+	oidc *oidc.Config
+
+	// mailer delivers the emails sent to users.
+	mailer mail.Mailer
+
+	// requestTimeout bounds the time spent on a request, see
+	// addRequestDeadline.
+	requestTimeout time.Duration
+
+	// metrics are served at /metrics, to requests with metricsToken when
+	// it is set.
+	metrics      *appMetrics
+	metricsToken string
+
+	// liveness and readiness are the checks served at /healthz and /readyz.
+	liveness  []health.Check
+	readiness []health.Check
+
+	// shuttingDown is set once the server is asked to stop.
+	shuttingDown atomic.Bool
 }
 
-// data holds common data shared between application layers for
-// rendering templates or handling requests.
-type data struct {
-	Title           string
-	CurrentYear     int
-	Form            any
-	User            models.User
-	Event           models.Event
-	Events          []models.Event
-	Flash           string
-	CSRFToken       string
-	IsAuthenticated bool
+// templateData holds the data of a page rendered for a request. Every render
+// starts from App.newTemplateData, so that nothing leaks between requests.
+type templateData struct {
+	Title             string
+	CurrentYear       int
+	Form              any
+	User              models.User
+	Event             models.Event
+	Venue             models.Venue
+	Venues            []models.Venue
+	Events            []models.Event
+	Calendar          calendar.View
+	Attachments       []models.Attachment
+	Cover             models.Attachment
+	CanEdit           bool
+	AttendeeCount     int
+	IsAttending       bool
+	Webhook           models.WebhookSubscription
+	Webhooks          []models.WebhookSubscription
+	Deliveries        []models.WebhookDelivery
+	Attempts          []models.WebhookAttempt
+	EventTypes        []string
+	AuditEvents       []models.AuditEvent
+	TOTPSecret        string
+	RecoveryCodes     []string
+	RecoveryCodesLeft int
+	APITokens         []models.APIToken
+	APITokenScopes    []string
+	NewAPIToken       string
+	Sessions          []models.Session
+	Export            models.Export
+	Error             errorPage
+	Flash             string
+	CSRFToken         string
+	IsAuthenticated   bool
+	OIDCEnabled       bool
 }
 
 // App is a struct that embeds configuration dependencies required across the application.
 type App struct {
-	eventModel *models.EventModel
-	userModel  *models.UserModel
+	eventModel      models.EventRepository
+	userModel       models.UserRepository
+	rsvpModel       models.RSVPRepository
+	reminderModel   models.ReminderRepository
+	webhookModel    models.WebhookRepository
+	attachmentModel models.AttachmentRepository
+	venueModel      models.VenueRepository
+	auditModel      models.AuditRepository
+	apiTokenModel   models.APITokenRepository
+	sessionModel    models.SessionRepository
+	exportModel     models.ExportRepository
 	config
-	data
 }
 
 // main initializes the application, sets up dependencies, and starts
@@ -59,11 +244,85 @@ type App struct {
 func main() {
 
 	flag.StringVar(&port, "port", "4000", "port to listen on")
+	flag.StringVar(&dbDSN, "db", "database/events.db", "path of the SQLite database, or postgres:// URL of a PostgreSQL database")
+	flag.StringVar(&reminderOffsets, "reminder-offsets", "24h,1h", "comma separated durations before an event to send reminders")
+	flag.DurationVar(&reminderInterval, "reminder-interval", time.Minute, "interval between scans for due reminders")
+	flag.DurationVar(&webhookInterval, "webhook-interval", 10*time.Second, "interval between scans of the webhook delivery queue")
+	flag.IntVar(&webhookMaxAttempts, "webhook-max-attempts", 8, "attempts before a webhook delivery is marked dead")
+	flag.StringVar(&storageBackend, "storage", "local", "storage backend for uploaded files: local or s3")
+	flag.StringVar(&storageDir, "storage-dir", "database/uploads", "directory of the local storage backend")
+	flag.StringVar(&s3Endpoint, "s3-endpoint", "", "base URL of the S3 compatible service")
+	flag.StringVar(&s3Bucket, "s3-bucket", "", "bucket of the s3 storage backend")
+	flag.StringVar(&s3Region, "s3-region", "us-east-1", "region of the s3 storage backend")
+	flag.IntVar(&loginIPBurst, "login-ip-burst", 20, "login attempts allowed in a row from an IP address")
+	flag.IntVar(&loginEmailBurst, "login-email-burst", 5, "login attempts allowed in a row for an email")
+	flag.DurationVar(&loginRefill, "login-refill", time.Minute, "interval in which one login attempt is regained")
+	flag.IntVar(&loginMaxFailures, "login-max-failures", 5, "consecutive failed logins before an account is locked")
+	flag.DurationVar(&loginLockout, "login-lockout", 15*time.Minute, "how long accounts stay locked after too many failed logins")
+	flag.StringVar(&oidcIssuer, "oidc-issuer", "", "issuer URL of the OpenID Connect provider for single sign-on")
+	flag.StringVar(&oidcClientID, "oidc-client-id", "", "client ID registered with the OpenID Connect provider")
+	flag.StringVar(&oidcRedirectURL, "oidc-redirect-url", "", "URL of /login/oidc/callback registered with the OpenID Connect provider")
+	if startMockOIDC != nil {
+		flag.BoolVar(&oidcMock, "oidc-mock", false, "start an in-process mock OpenID Connect provider (development only)")
+	}
+	flag.StringVar(&baseURL, "base-url", "", "public URL of the application used in emailed links (default http://localhost:<port>)")
+	flag.DurationVar(&exportInterval, "export-interval", 30*time.Second, "interval between scans of the data export queue")
+	flag.DurationVar(&exportLifetime, "export-lifetime", 72*time.Hour, "how long the download link of a data export stays valid")
+	flag.StringVar(&backupDir, "backup-dir", "database/backups", "directory of the scheduled database backups")
+	flag.DurationVar(&backupInterval, "backup-interval", 24*time.Hour, "interval between database backups, 0 to disable them")
+	flag.IntVar(&backupKeep, "backup-keep", 7, "number of database backups kept, 0 to keep them all")
+	flag.DurationVar(&requestTimeout, "request-timeout", 8*time.Second, "time allowed to handle a request before its queries are interrupted")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "address serving /metrics, such as localhost:9090, instead of the application port")
+	flag.StringVar(&traceExporter, "trace-exporter", tracing.ExporterNone, "exporter of the traces: none, stdout or otlp (configured by the OTEL_EXPORTER_OTLP_* variables)")
+	flag.StringVar(&logFormat, "log-format", "text", "format of the logs: text or json")
+	flag.Uint64Var(&minDiskMB, "min-disk-mb", 100, "free megabytes on the volume of the SQLite database below which /readyz fails")
+	flag.DurationVar(&shutdownDelay, "shutdown-delay", 5*time.Second, "time /readyz fails before the server stops accepting requests on shutdown")
 	flag.Parse()
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	logger, err := newLogger(logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if requestTimeout <= 0 || requestTimeout >= writeTimeout {
+		logger.Error("-request-timeout must be positive and shorter than the write timeout", "write_timeout", writeTimeout)
+		os.Exit(1)
+	}
+
+	offsets, err := parseDurations(reminderOffsets)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	postgres := isPostgres(dbDSN)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), traceExporter, "go-event-planner", os.Stdout)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	dbSystem := semconv.DBSystemSqlite
+	if postgres {
+		dbSystem = semconv.DBSystemPostgreSQL
+	}
+
+	appMetrics := newAppMetrics()
+
+	db, sessionStore, err := openDatabase(dbDSN, appMetrics.observeQuery, traceQuery(dbSystem))
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
 
-	db, err := openDB("database/events.db")
+	store, err := openStorage()
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
@@ -77,55 +336,328 @@ func main() {
 
 	formDecoder := form.NewDecoder()
 	formDecoder.RegisterCustomTypeFunc(func(vals []string) (interface{}, error) {
+		// Optional date inputs are submitted empty, leave them as the zero time.
+		if vals[0] == "" {
+			return time.Time{}, nil
+		}
 		return time.Parse("2006-01-02", vals[0])
 	}, time.Time{})
 
+	oidcConfig, err := openOIDC(logger)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	sessionManager := scs.New()
-	sessionManager.Store = sqlite3store.New(db)
+	sessionManager.Store = sessionStore
 	sessionManager.Lifetime = 12 * time.Hour
 
+	// The workers run until the server has shut down, so that the requests
+	// in flight can still queue work.
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// The limiters share the rate_limits table, so that the limits survive
+	// restarts and apply across instances.
+	loginIPLimiter := newLimiter(db, postgres, ratelimit.Bucket{Burst: loginIPBurst, Every: loginRefill}, "login-ip:")
+	loginEmailLimiter := newLimiter(db, postgres, ratelimit.Bucket{Burst: loginEmailBurst, Every: loginRefill}, "login-email:")
+	go pruneRateLimits(workers, logger, time.Hour, loginIPLimiter, loginEmailLimiter)
+
 	app := App{
-		eventModel: &models.EventModel{DB: db},
-		userModel:  &models.UserModel{DB: db},
+		eventModel:      &models.EventModel{DB: db},
+		userModel:       &models.UserModel{DB: db},
+		rsvpModel:       &models.RSVPModel{DB: db},
+		reminderModel:   &models.ReminderModel{DB: db},
+		webhookModel:    &models.WebhookModel{DB: db},
+		attachmentModel: &models.AttachmentModel{DB: db},
+		venueModel:      &models.VenueModel{DB: db},
+		auditModel:      &models.AuditModel{DB: db},
+		apiTokenModel:   &models.APITokenModel{DB: db},
+		sessionModel:    &models.SessionModel{DB: db},
+		exportModel:     &models.ExportModel{DB: db},
 		config: config{
-			logger:         logger,
-			templates:      templates,
-			db:             db,
-			formDecoder:    formDecoder,
-			sessionManager: sessionManager,
-		},
-		data: data{
-			Title:       "Event Planner",
-			CurrentYear: time.Now().Year(),
+			logger:            logger,
+			templates:         templates,
+			db:                db,
+			formDecoder:       formDecoder,
+			sessionManager:    sessionManager,
+			storage:           store,
+			loginIPLimiter:    loginIPLimiter,
+			loginEmailLimiter: loginEmailLimiter,
+			oidc:              oidcConfig,
+			mailer:            &mail.LogMailer{Logger: logger},
+			requestTimeout:    requestTimeout,
+			metrics:           appMetrics,
+			metricsToken:      os.Getenv("METRICS_TOKEN"),
 		},
 	}
 
+	if postgres {
+		app.eventModel = &models.PostgresEventModel{DB: db}
+		app.userModel = &models.PostgresUserModel{DB: db}
+		app.sessionModel = &models.PostgresSessionModel{DB: db}
+		app.rsvpModel = &models.PostgresRSVPModel{DB: db}
+		app.reminderModel = &models.PostgresReminderModel{DB: db}
+		app.webhookModel = &models.PostgresWebhookModel{DB: db}
+		app.attachmentModel = &models.PostgresAttachmentModel{DB: db}
+		app.venueModel = &models.PostgresVenueModel{DB: db}
+		app.auditModel = &models.PostgresAuditModel{DB: db}
+		app.apiTokenModel = &models.PostgresAPITokenModel{DB: db}
+		app.exportModel = &models.PostgresExportModel{DB: db}
+	}
+
+	app.readiness, err = app.readinessChecks(postgres, minDiskMB<<20)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	go pruneSessions(workers, logger, time.Hour, app.sessionModel)
+
+	app.registerGauges(db)
+
+	reminders := &scheduler.Scheduler{
+		Reminders: app.reminderModel,
+		Notifier:  &scheduler.LogNotifier{Logger: logger},
+		Logger:    logger,
+		Offsets:   offsets,
+		Interval:  reminderInterval,
+		Heartbeat: newHeartbeat(reminderInterval),
+	}
+	go reminders.Run(workers)
+
+	dispatcher := &webhook.Dispatcher{
+		Webhooks:    app.webhookModel,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Logger:      logger,
+		Interval:    webhookInterval,
+		MaxAttempts: webhookMaxAttempts,
+		Backoff:     30 * time.Second,
+		Heartbeat:   newHeartbeat(webhookInterval),
+	}
+	go dispatcher.Run(workers)
+
+	exports := &export.Worker{
+		Exports:     app.exportModel,
+		Storage:     store,
+		Mailer:      app.mailer,
+		Logger:      logger,
+		Interval:    exportInterval,
+		Lifetime:    exportLifetime,
+		DownloadURL: baseURL + "/account/export/download?token=",
+		Heartbeat:   newHeartbeat(exportInterval),
+	}
+	go exports.Run(workers)
+
+	app.liveness = []health.Check{
+		{Name: "reminders", Run: reminders.Heartbeat.Check},
+		{Name: "webhooks", Run: dispatcher.Heartbeat.Check},
+		{Name: "exports", Run: exports.Heartbeat.Check},
+	}
+
+	// PostgreSQL databases are backed up with the tools of the server.
+	if backupInterval > 0 && !postgres {
+		backups := &backup.Scheduler{
+			DB:        db,
+			Logger:    logger,
+			Dir:       backupDir,
+			Interval:  backupInterval,
+			Keep:      backupKeep,
+			Heartbeat: newHeartbeat(time.Minute),
+		}
+		go backups.Run(workers)
+
+		app.liveness = append(app.liveness, health.Check{Name: "backups", Run: backups.Heartbeat.Check})
+	}
+
 	server := &http.Server{
 		Addr:         ":" + port,
 		Handler:      app.routes(),
 		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  time.Second * 5,
-		WriteTimeout: time.Second * 10,
+		WriteTimeout: writeTimeout,
 	}
 
-	logger.Info("Starting server", "port", port)
+	if metricsAddr != "" {
+		go app.serveMetrics(metricsAddr)
+	}
 
-	if err := server.ListenAndServe(); err != nil {
-		logger.Error(err.Error())
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.Info("Starting server", "port", port)
+
+		err := server.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	// A second signal stops the application at once.
+	stop()
+
+	app.shuttingDown.Store(true)
+	logger.Info("Shutting down", "delay", shutdownDelay)
+	time.Sleep(shutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("Shutdown failed", "error", err.Error())
 	}
-}
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dsn)
+	stopWorkers()
+	_ = db.Close()
+
+	err = shutdownTracing(shutdownCtx)
 	if err != nil {
-		return nil, err
+		logger.Error("Flushing the traces failed", "error", err.Error())
+	}
+	logger.Info("Stopped")
+}
+
+// newLogger returns a logger writing to the standard output in the format, "text" or "json".
+func newLogger(format string) (*slog.Logger, error) {
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stdout, nil)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// openStorage returns the storage backend for uploaded files selected by the storage flags.
+func openStorage() (storage.Storage, error) {
+	switch storageBackend {
+	case "local":
+		return &storage.Local{Root: storageDir}, nil
+	case "s3":
+		if s3Endpoint == "" || s3Bucket == "" {
+			return nil, fmt.Errorf("the s3 storage requires -s3-endpoint and -s3-bucket")
+		}
+		return &storage.S3{
+			Endpoint:  s3Endpoint,
+			Bucket:    s3Bucket,
+			Region:    s3Region,
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Client:    &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", storageBackend)
+	}
+}
+
+// startMockOIDC starts a mock OpenID Connect provider and returns its issuer, and the client
+// ID and secret registered with it. It is set by oidc_mock.go, which is only built with the
+// dev build tag.
+var startMockOIDC func() (issuer, clientID, clientSecret string, err error)
+
+// openOIDC discovers the OpenID Connect provider selected by the oidc flags and returns
+// the registration of the application, or nil when single sign-on is disabled.
+func openOIDC(logger *slog.Logger) (*oidc.Config, error) {
+	clientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+
+	if oidcMock {
+		var err error
+		oidcIssuer, oidcClientID, clientSecret, err = startMockOIDC()
+		if err != nil {
+			return nil, err
+		}
+		if oidcRedirectURL == "" {
+			oidcRedirectURL = "http://localhost:" + port + "/login/oidc/callback"
+		}
+		logger.Warn("Started mock OpenID Connect provider, anyone can log in", "issuer", oidcIssuer)
+	}
+
+	if oidcIssuer == "" {
+		return nil, nil
 	}
-	err = db.Ping()
+	if oidcClientID == "" || oidcRedirectURL == "" {
+		return nil, fmt.Errorf("single sign-on requires -oidc-client-id and -oidc-redirect-url")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	provider, err := oidc.Discover(ctx, &http.Client{Timeout: 10 * time.Second}, oidcIssuer)
 	if err != nil {
-		_ = db.Close()
 		return nil, err
 	}
-	return db, nil
+
+	return &oidc.Config{
+		Provider:     provider,
+		ClientID:     oidcClientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  oidcRedirectURL,
+	}, nil
+}
+
+// pruneRateLimits periodically deletes the buckets of the limiters which are
+// full again, until the context is canceled.
+func pruneRateLimits(ctx context.Context, logger *slog.Logger, interval time.Duration, limiters ...storedLimiter) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, l := range limiters {
+				err := l.Prune(ctx)
+				if err != nil {
+					logger.Error(err.Error())
+				}
+			}
+		}
+	}
+}
+
+// pruneSessions periodically deletes the expired sessions from the list of active
+// sessions, until the context is canceled.
+func pruneSessions(ctx context.Context, logger *slog.Logger, interval time.Duration, sessions models.SessionRepository) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := sessions.Prune(ctx)
+			if err != nil {
+				logger.Error(err.Error())
+			}
+		}
+	}
+}
+
+// parseDurations parses a comma separated list of durations such as "24h,1h".
+func parseDurations(s string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return nil, fmt.Errorf("parsing duration %q: %w", field, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("duration %q must be positive", field)
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/metrics"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// appMetrics are the metrics served at /metrics.
+type appMetrics struct {
+	registry *metrics.Registry
+
+	requests        *metrics.Counter
+	requestDuration *metrics.Histogram
+	queryDuration   *metrics.Histogram
+	renderDuration  *metrics.Histogram
+}
+
+// newAppMetrics registers the metrics recorded while the application runs.
+// The ones read when scraped are added by registerGauges.
+func newAppMetrics() *appMetrics {
+	r := &metrics.Registry{}
+
+	return &appMetrics{
+		registry: r,
+		requests: r.NewCounter("http_requests_total",
+			"HTTP requests handled, by method, route pattern and status.",
+			"method", "route", "status"),
+		requestDuration: r.NewHistogram("http_request_duration_seconds",
+			"Time to handle HTTP requests, by method, route pattern and status.",
+			metrics.DefaultBuckets, "method", "route", "status"),
+		queryDuration: r.NewHistogram("db_query_duration_seconds",
+			"Time to run database statements, by operation.",
+			metrics.DefaultBuckets, "operation"),
+		renderDuration: r.NewHistogram("template_render_duration_seconds",
+			"Time to render page templates, by template.",
+			metrics.DefaultBuckets, "template"),
+	}
+}
+
+// observeQuery is the sqlhook.Hook recording the duration of database statements.
+func (m *appMetrics) observeQuery(_ context.Context, query string, start time.Time, _ error) {
+	m.queryDuration.Observe(time.Since(start).Seconds(), queryOperation(query))
+}
+
+// queryOperation returns the keyword starting the query, such as SELECT, so that
+// the statements are partitioned in a few series.
+func queryOperation(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.IndexAny(query, " \t\r\n("); i >= 0 {
+		query = query[:i]
+	}
+
+	op := strings.ToUpper(query)
+	switch op {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "BEGIN", "COMMIT", "ROLLBACK":
+		return op
+	default:
+		return "OTHER"
+	}
+}
+
+// registerGauges adds the metrics read when scraped: the connection pool statistics
+// of the database and the counts of the repositories.
+func (app *App) registerGauges(db *sql.DB) {
+	r := app.metrics.registry
+
+	pool := func(value func(s sql.DBStats) float64) func(context.Context) (float64, error) {
+		return func(context.Context) (float64, error) {
+			return value(db.Stats()), nil
+		}
+	}
+	r.NewGaugeFunc("db_connections_max_open", "Maximum number of open database connections, 0 for no limit.",
+		pool(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewGaugeFunc("db_connections_open", "Open database connections.",
+		pool(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("db_connections_in_use", "Database connections in use.",
+		pool(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("db_connections_idle", "Idle database connections.",
+		pool(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewCounterFunc("db_connection_waits_total", "Times a database connection was waited for.",
+		pool(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("db_connection_wait_seconds_total", "Time spent waiting for database connections.",
+		pool(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+
+	count := func(n func(ctx context.Context) (int, error)) func(context.Context) (float64, error) {
+		return func(ctx context.Context) (float64, error) {
+			v, err := n(ctx)
+			return float64(v), err
+		}
+	}
+	r.NewGaugeFunc("sessions_active", "Sessions which have not expired.", count(app.sessionModel.CountActive))
+	r.NewGaugeFunc("users_registered", "Registered users, including the disabled ones.", count(app.userModel.Count))
+	r.NewGaugeFunc("events_upcoming", "Events starting in the future.", count(func(ctx context.Context) (int, error) {
+		return app.eventModel.CountUpcoming(ctx, time.Now())
+	}))
+}
+
+// metricsHandler serves the metrics. When a token is set, the requests must carry it
+// in an "Authorization: Bearer" header.
+func (app *App) metricsHandler() http.Handler {
+	h := app.metrics.registry.Handler(app.logger)
+	if app.metricsToken == "" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(app.metricsToken)) != 1 {
+			app.tokenError(w, r, http.StatusUnauthorized, "invalid_token", errors.New("invalid metrics token"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// serveMetrics serves /metrics alone on the address, so that it can be kept off the
+// public network. The application exits if the address cannot be listened on.
+func (app *App) serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", app.metricsHandler())
+
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: writeTimeout,
+	}
+
+	app.logger.Info("Serving metrics", "addr", addr)
+
+	err := server.ListenAndServe()
+	app.logger.Error(err.Error())
+	os.Exit(1)
+}
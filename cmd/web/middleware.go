@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/justinas/nosurf"
+	"github.com/madalinpopa/go-event-planner/internal/models"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // addCommonHeaders is a middleware that adds common security-related HTTP headers to the response.
@@ -40,19 +45,169 @@ func (app *App) addCommonHeaders(next http.Handler) http.Handler {
 	})
 }
 
-// addRequestLogger logs details of incoming HTTP requests, such as IP, protocol, method, and URL, before passing to the next handler.
+// requestIDHeader carries the ID of a request, in the request when a proxy or client set
+// it, and in the response.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the IDs propagated from the requests.
+const maxRequestIDLength = 128
+
+// requestInfo identifies a request in the logs. It is shared by pointer through the context
+// of the request, so that the user authenticated further down the chain is known to the
+// middleware running before.
+type requestInfo struct {
+	id     string
+	userID int
+}
+
+// addRequestID is a middleware that gives every request an ID, stored in its context and sent
+// back in the X-Request-ID response header. The ID of the X-Request-ID request header is kept,
+// so that the logs of a proxy and of the application can be correlated, and a random one is
+// generated when the header is missing or invalid.
+func (app *App) addRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			var err error
+			id, err = randomHex(16)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestInfoContextKey, &requestInfo{id: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID reports whether a request ID received from a client can be logged as it is:
+// it is not too long and only made of letters, digits and the characters -_.:+/=.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-_.:+/=", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// setRequestUser records the user authenticating the request in its logs.
+func setRequestUser(r *http.Request, userID int) {
+	if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// addRequestLogger logs every request once it was handled, with its IP, protocol, method and
+// URL, and the status, size and duration of its response. It must run after addRequestID,
+// for the records to carry the request ID, and before addPanicRecover, for the responses of
+// panicking handlers to be logged with their 500 status.
 func (app *App) addRequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var (
-			ip     = r.RemoteAddr
-			proto  = r.Proto
-			method = r.Method
-			url    = r.URL.RequestURI()
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		app.loggerFor(r).Info("request",
+			"ip", r.RemoteAddr,
+			"proto", r.Proto,
+			"method", r.Method,
+			"url", r.URL.RequestURI(),
+			"status", rw.status,
+			"bytes", rw.size,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
 		)
+	})
+}
 
-		app.logger.Info("request", "ip", ip, "proto", proto, "method", method, "url", url)
+// addRequestDeadline is a middleware that bounds the time spent on a request, so that the
+// queries of a slow request are interrupted instead of holding on to the database.
+func (app *App) addRequestDeadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
 
-		next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// responseWriter records the status and size of the response written through it.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying response writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// addMetrics is a middleware that counts the requests and records their duration by route
+// pattern and status. It must be the last middleware before the ServeMux, which sets the
+// pattern on the request it is given.
+func (app *App) addMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		observe := func(status int) {
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			labels := []string{r.Method, route, strconv.Itoa(status)}
+
+			app.metrics.requests.Inc(labels...)
+			app.metrics.requestDuration.Observe(time.Since(start).Seconds(), labels...)
+		}
+
+		// Panics are counted as the 500 responses addPanicRecover sends.
+		defer func() {
+			if err := recover(); err != nil {
+				observe(http.StatusInternalServerError)
+				panic(err)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		observe(rw.status)
 	})
 }
 
@@ -71,9 +226,27 @@ func (app *App) addPanicRecover(next http.Handler) http.Handler {
 	})
 }
 
+// loadAndSave is a middleware that loads and saves the session of the request, as the
+// LoadAndSave middleware of scs does, and records in the context that the request has a
+// session, which the pages rendered outside of the session middleware do not.
+func (app *App) loadAndSave(next http.Handler) http.Handler {
+	return app.sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), sessionContextKey, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
 // csrfToken is a middleware that adds CSRF protection to HTTP handlers using the nosurf package.
 func csrfToken(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
+
+	// Browsers never send API tokens by themselves, so requests authenticated
+	// with one cannot be forged by another site.
+	csrfHandler.ExemptFunc(func(r *http.Request) bool {
+		_, ok := r.Context().Value(apiTokenContextKey).(models.APIToken)
+		return ok
+	})
+
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
@@ -91,7 +264,7 @@ func (app *App) loginRequired(next http.Handler) http.Handler {
 		// from the middleware chain so that no subsequent handlers in the chain are
 		// executed.
 		if !app.isAuthenticated(r) {
-			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
 
@@ -105,6 +278,19 @@ func (app *App) loginRequired(next http.Handler) http.Handler {
 	})
 }
 
+// adminRequired is a middleware that restricts access to administrators. It must run after
+// loginRequired, so that anonymous users are sent to the login page instead.
+func (app *App) adminRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAdmin(r) {
+			app.clientError(w, r, http.StatusForbidden, errors.New("administrator role required"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // redirectAuthenticatedUsers is a middleware that redirects authenticated users attempting
 // to access the login or register pages to the events page.
 func (app *App) redirectAuthenticatedUsers(next http.Handler) http.Handler {
@@ -138,16 +324,35 @@ func (app *App) authenticate(next http.Handler) http.Handler {
 		//This will return the zero value for an int (0) if no
 		// "authenticatedUserID" value is in the session -- in which case we call the
 		// next handler in the chain as normal and return.
+		// Requests authenticated with an API token are left as they are.
+		if _, ok := app.apiToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 		if id == 0 {
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// Sessions signed out from another device, or by a password change,
+		// are no longer recorded and are treated as anonymous.
+		sessionUserID, err := app.sessionModel.Touch(r.Context(), app.sessionManager.Token(r.Context()), clientIP(r))
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
+		}
+		if sessionUserID != id {
+			app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Otherwise, we check to see if a user with that ID exists in our
 		// database.
-		exists, err := app.userModel.Exists(id)
-		if err != nil {
+		user, err := app.userModel.Retrieve(r.Context(), id)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
 			app.serverError(w, r, err)
 			return
 		}
@@ -157,12 +362,126 @@ func (app *App) authenticate(next http.Handler) http.Handler {
 		//We create a new copy of the
 		// request (with an isAuthenticatedContextKey value of true in the request data)
 		// and assign it to r.
-		if exists {
+		if err == nil {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, isAdminContextKey, user.IsAdmin())
+			ctx = context.WithValue(ctx, userIDContextKey, user.ID)
+			ctx = context.WithValue(ctx, userContextKey, user)
+			ctx = context.WithValue(ctx, localeContextKey, user.Locale)
 			r = r.WithContext(ctx)
+			setRequestUser(r, user.ID)
 		}
 
 		// Call the next handler in the chain.
 		next.ServeHTTP(w, r)
 	})
 }
+
+// limitRequestSize is a middleware that rejects request bodies larger than n bytes. It must run
+// before csrfToken, which parses the whole form to look for the CSRF token.
+func (app *App) limitRequestSize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				app.clientError(w, r, http.StatusRequestEntityTooLarge, fmt.Errorf("request body of %d bytes exceeds %d", r.ContentLength, n))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// extendDeadline is a middleware that gives the uploads and downloads of files more time than
+// other requests: it moves the read and write deadlines of the connection, and replaces the
+// request deadline set by addRequestDeadline. The request is still canceled when the client
+// goes away.
+func (app *App) extendDeadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(timeout)
+
+			rc := http.NewResponseController(w)
+			if err := rc.SetReadDeadline(deadline); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			if err := rc.SetWriteDeadline(deadline); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			parent := r.Context()
+			ctx, cancel := context.WithDeadline(context.WithoutCancel(parent), deadline)
+			defer cancel()
+
+			stop := context.AfterFunc(parent, func() {
+				if !errors.Is(parent.Err(), context.DeadlineExceeded) {
+					cancel()
+				}
+			})
+			defer stop()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticateToken returns a middleware authenticating requests which carry an API token in
+// an "Authorization: Bearer" header, instead of a session. It sets the same request context as
+// authenticate, and must run before csrfToken, which lets these requests through. The token
+// must have been granted the scope; requests without the header are left to the session.
+func (app *App) authenticateToken(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			secret, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				app.tokenError(w, r, http.StatusUnauthorized, "invalid_request", errors.New("unsupported authorization scheme"))
+				return
+			}
+
+			token, err := app.apiTokenModel.Authenticate(r.Context(), strings.TrimSpace(secret))
+			if err != nil {
+				if errors.Is(err, models.ErrInvalidCredentials) {
+					app.tokenError(w, r, http.StatusUnauthorized, "invalid_token", err)
+				} else {
+					app.serverError(w, r, err)
+				}
+				return
+			}
+
+			if !token.HasScope(scope) {
+				app.tokenError(w, r, http.StatusForbidden, "insufficient_scope", fmt.Errorf("token %d lacks scope %s", token.ID, scope))
+				return
+			}
+
+			// The token of a deleted user is no longer valid.
+			user, err := app.userModel.Retrieve(r.Context(), token.UserID)
+			if err != nil {
+				if errors.Is(err, models.ErrNoRecord) {
+					app.tokenError(w, r, http.StatusUnauthorized, "invalid_token", fmt.Errorf("token %d: user %d: %w", token.ID, token.UserID, err))
+				} else {
+					app.serverError(w, r, err)
+				}
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), apiTokenContextKey, token)
+			ctx = context.WithValue(ctx, isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, isAdminContextKey, user.IsAdmin())
+			ctx = context.WithValue(ctx, userIDContextKey, user.ID)
+			ctx = context.WithValue(ctx, userContextKey, user)
+			ctx = context.WithValue(ctx, localeContextKey, user.Locale)
+			setRequestUser(r, user.ID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
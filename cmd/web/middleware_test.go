@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExtendDeadline(t *testing.T) {
+	app := &App{}
+
+	// The handler outlives the request deadline, which the uploads and
+	// downloads of files replace with their own.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok || time.Until(deadline) < 30*time.Second {
+			http.Error(w, "request deadline not extended", http.StatusInternalServerError)
+			return
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		if err := r.Context().Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	ts := httptest.NewServer(app.addRequestDeadline(10 * time.Millisecond)(app.extendDeadline(time.Minute)(handler)))
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("got status %d; want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestExtendDeadlineClientGone(t *testing.T) {
+	app := &App{}
+
+	canceled := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		canceled <- r.Context().Err()
+	})
+
+	ts := httptest.NewServer(app.addRequestDeadline(time.Second)(app.extendDeadline(time.Minute)(handler)))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := ts.Client().Do(req); err == nil {
+		resp.Body.Close()
+	}
+
+	select {
+	case err := <-canceled:
+		if err != context.Canceled {
+			t.Errorf("got error %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not canceled when the client went away")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build dev
+
+package main
+
+import (
+	"github.com/madalinpopa/go-event-planner/internal/oidc/oidctest"
+)
+
+// The mock OpenID Connect provider, which authenticates anyone, is behind
+// the dev build tag, so that production builds cannot start it.
+func init() {
+	startMockOIDC = func() (string, string, string, error) {
+		mock, err := oidctest.NewProvider("event-planner", "event-planner-secret")
+		if err != nil {
+			return "", "", "", err
+		}
+		return mock.Issuer, mock.ClientID, mock.ClientSecret, nil
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"github.com/madalinpopa/go-event-planner/internal/oidc"
+	"github.com/madalinpopa/go-event-planner/internal/oidc/oidctest"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oidcTestServer serves the application, registered with a mock OpenID
+// Connect provider, over a test SQLite database.
+type oidcTestServer struct {
+	*httptest.Server
+	app      *App
+	provider *oidctest.Provider
+	client   *http.Client
+}
+
+func newOIDCTestServer(t *testing.T) *oidcTestServer {
+	t.Helper()
+
+	provider, err := oidctest.NewProvider("event-planner", "event-planner-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = provider.Close() })
+
+	templates, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := modeltest.OpenDB(t)
+
+	app := &App{
+		eventModel:      &models.EventModel{DB: db},
+		userModel:       &models.UserModel{DB: db},
+		rsvpModel:       &models.RSVPModel{DB: db},
+		reminderModel:   &models.ReminderModel{DB: db},
+		webhookModel:    &models.WebhookModel{DB: db},
+		attachmentModel: &models.AttachmentModel{DB: db},
+		venueModel:      &models.VenueModel{DB: db},
+		auditModel:      &models.AuditModel{DB: db},
+		apiTokenModel:   &models.APITokenModel{DB: db},
+		sessionModel:    &models.SessionModel{DB: db},
+		exportModel:     &models.ExportModel{DB: db},
+		config: config{
+			db:             db,
+			logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+			templates:      templates,
+			formDecoder:    form.NewDecoder(),
+			sessionManager: scs.New(),
+			requestTimeout: 8 * time.Second,
+			metrics:        newAppMetrics(),
+		},
+	}
+
+	ts := httptest.NewServer(app.routes())
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	discovered, err := oidc.Discover(ctx, ts.Client(), provider.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.oidc = &oidc.Config{
+		Provider:     discovered,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  ts.URL + "/login/oidc/callback",
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Redirects are followed by the tests, which check each step.
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &oidcTestServer{Server: ts, app: app, provider: provider, client: client}
+}
+
+// get requests the URL and returns the response, with its body read.
+func (ts *oidcTestServer) get(t *testing.T, urlStr string) (*http.Response, string) {
+	t.Helper()
+
+	res, err := ts.client.Get(urlStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(body)
+}
+
+// authorize starts a single sign-on login and returns the URL of the
+// authorization request sent to the provider.
+func (ts *oidcTestServer) authorize(t *testing.T) *url.URL {
+	t.Helper()
+
+	res, _ := ts.get(t, ts.URL+"/login/oidc")
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusFound)
+	}
+
+	location, err := res.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+// login completes the authorization request at the provider as the user with
+// the email, and returns the callback URL it redirects to.
+func (ts *oidcTestServer) login(t *testing.T, authorize *url.URL, email string) string {
+	t.Helper()
+
+	q := authorize.Query()
+	q.Set("login_email", email)
+	authorize.RawQuery = q.Encode()
+
+	res, _ := ts.get(t, authorize.String())
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("got status %d from the provider; want %d", res.StatusCode, http.StatusFound)
+	}
+	return res.Header.Get("Location")
+}
+
+func TestUserLoginOIDC(t *testing.T) {
+	ts := newOIDCTestServer(t)
+
+	authorize := ts.authorize(t)
+
+	if got, want := authorize.Scheme+"://"+authorize.Host+authorize.Path, ts.provider.Issuer+"/authorize"; got != want {
+		t.Errorf("got redirect to %q; want %q", got, want)
+	}
+
+	q := authorize.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             ts.provider.ClientID,
+		"redirect_uri":          ts.URL + "/login/oidc/callback",
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := q.Get(key); got != value {
+			t.Errorf("got %s %q; want %q", key, got, value)
+		}
+	}
+	for _, key := range []string{"state", "nonce", "code_challenge"} {
+		if q.Get(key) == "" {
+			t.Errorf("missing %s", key)
+		}
+	}
+}
+
+func TestUserLoginOIDCDisabled(t *testing.T) {
+	ts := newOIDCTestServer(t)
+	ts.app.oidc = nil
+
+	for _, path := range []string{"/login/oidc", "/login/oidc/callback"} {
+		res, _ := ts.get(t, ts.URL+path)
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: got status %d; want %d", path, res.StatusCode, http.StatusNotFound)
+		}
+	}
+}
+
+func TestUserLoginOIDCCallback(t *testing.T) {
+	ts := newOIDCTestServer(t)
+
+	callback := ts.login(t, ts.authorize(t), "alice@example.com")
+
+	res, _ := ts.get(t, callback)
+	if res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusSeeOther)
+	}
+	if got := res.Header.Get("Location"); got != "/" {
+		t.Errorf("got redirect to %q; want %q", got, "/")
+	}
+
+	id, err := ts.app.userModel.RetrieveByIdentity(context.Background(), ts.provider.Issuer, oidctest.Subject("alice@example.com"))
+	if err != nil {
+		t.Fatalf("user was not provisioned: %v", err)
+	}
+	user, err := ts.app.userModel.Retrieve(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "alice@example.com" {
+		t.Errorf("got email %q; want %q", user.Email, "alice@example.com")
+	}
+
+	// The session is authenticated.
+	res, _ = ts.get(t, ts.URL+"/account")
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got status %d for the account page; want %d", res.StatusCode, http.StatusOK)
+	}
+}
+
+func TestUserLoginOIDCCallbackTOTP(t *testing.T) {
+	ts := newOIDCTestServer(t)
+	ctx := context.Background()
+
+	// The user of the same email has two-factor authentication enabled.
+	err := ts.app.userModel.Create(ctx, "Alice", "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := ts.app.userModel.Authenticate(ctx, "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ts.app.userModel.EnableTOTP(ctx, id, "JBSWY3DPEHPK3PXP", []string{"abcde-fghij"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback := ts.login(t, ts.authorize(t), "alice@example.com")
+
+	res, _ := ts.get(t, callback)
+	if res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusSeeOther)
+	}
+	if got := res.Header.Get("Location"); got != "/login/2fa" {
+		t.Errorf("got redirect to %q; want %q", got, "/login/2fa")
+	}
+
+	// The session waits for the code, and is not authenticated yet.
+	res, _ = ts.get(t, ts.URL+"/login/2fa")
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got status %d for the code page; want %d", res.StatusCode, http.StatusOK)
+	}
+	res, _ = ts.get(t, ts.URL+"/account")
+	if res.StatusCode != http.StatusSeeOther {
+		t.Errorf("got status %d for the account page; want %d", res.StatusCode, http.StatusSeeOther)
+	}
+}
+
+func TestUserLoginOIDCCallbackStateMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{name: "Different state", state: "forged"},
+		{name: "Missing state", state: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newOIDCTestServer(t)
+
+			callback, err := url.Parse(ts.login(t, ts.authorize(t), "alice@example.com"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := callback.Query()
+			q.Set("state", tt.state)
+			callback.RawQuery = q.Encode()
+
+			res, _ := ts.get(t, callback.String())
+			if res.StatusCode != http.StatusBadRequest {
+				t.Errorf("got status %d; want %d", res.StatusCode, http.StatusBadRequest)
+			}
+
+			_, err = ts.app.userModel.RetrieveByIdentity(context.Background(), ts.provider.Issuer, oidctest.Subject("alice@example.com"))
+			if err != models.ErrNoRecord {
+				t.Errorf("got error %v; want %v", err, models.ErrNoRecord)
+			}
+		})
+	}
+}
+
+func TestUserLoginOIDCCallbackWithoutLogin(t *testing.T) {
+	ts := newOIDCTestServer(t)
+
+	// The callback is only accepted after a login was started in the session.
+	res, _ := ts.get(t, ts.URL+"/login/oidc/callback?code=code&state=state")
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %d; want %d", res.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestUserLoginOIDCCallbackNonceMismatch(t *testing.T) {
+	ts := newOIDCTestServer(t)
+
+	// The provider signs the ID token with the nonce of the request, which is
+	// no longer the one stored in the session.
+	authorize := ts.authorize(t)
+	q := authorize.Query()
+	q.Set("nonce", "forged")
+	authorize.RawQuery = q.Encode()
+
+	res, body := ts.get(t, ts.login(t, authorize, "alice@example.com"))
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d; want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+	if !strings.Contains(body, "Single sign-on failed. Please try again.") {
+		t.Errorf("login page does not show the error")
+	}
+
+	_, err := ts.app.userModel.RetrieveByIdentity(context.Background(), ts.provider.Issuer, oidctest.Subject("alice@example.com"))
+	if err != models.ErrNoRecord {
+		t.Errorf("got error %v; want %v", err, models.ErrNoRecord)
+	}
+}
+
+func TestUserLoginOIDCCallbackRefused(t *testing.T) {
+	ts := newOIDCTestServer(t)
+
+	state := ts.authorize(t).Query().Get("state")
+
+	res, body := ts.get(t, ts.URL+"/login/oidc/callback?error=access_denied&state="+url.QueryEscape(state))
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d; want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+	if !strings.Contains(body, "Single sign-on was cancelled or refused.") {
+		t.Errorf("login page does not show the error")
+	}
+}
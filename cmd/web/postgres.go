@@ -0,0 +1,25 @@
+//go:build postgres
+
+package main
+
+import (
+	"context"
+	"github.com/alexedwards/scs/pgxstore"
+	"github.com/alexedwards/scs/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// PostgreSQL support is behind the postgres build tag, so that SQLite
+// deployments do not carry the pgx driver.
+func init() {
+	postgresDriver = "pgx"
+	newPostgresSessionStore = func(dsn string) (scs.Store, error) {
+		pool, err := pgxpool.New(context.Background(), dsn)
+		if err != nil {
+			return nil, err
+		}
+		return pgxstore.New(pool), nil
+	}
+}
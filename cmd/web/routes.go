@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/justinas/alice"
+	"github.com/madalinpopa/go-event-planner/internal/models"
 	"github.com/madalinpopa/go-event-planner/ui"
 	"net/http"
 )
@@ -16,32 +17,110 @@ func (app *App) routes() http.Handler {
 	mux.Handle("GET /static/", http.FileServerFS(ui.Files))
 
 	// Use the nosurf middleware on all 'csrfProtect' routes.
-	dynamic := alice.New(app.sessionManager.LoadAndSave, csrfToken, app.authenticate, app.redirectAuthenticatedUsers)
+	dynamic := alice.New(app.loadAndSave, csrfToken, app.authenticate, app.redirectAuthenticatedUsers)
 
 	protected := dynamic.Append(app.loginRequired)
 
+	admin := protected.Append(app.adminRequired)
+
+	// Event routes also accept an API token with the given scope in place of a session.
+	withToken := func(scope string) alice.Chain {
+		return alice.New(app.loadAndSave, app.authenticateToken(scope), csrfToken, app.authenticate, app.redirectAuthenticatedUsers)
+	}
+
+	read := withToken(models.ScopeEventsRead)
+
+	write := withToken(models.ScopeEventsWrite).Append(app.loginRequired)
+
+	// Uploads bound the request size before the CSRF middleware parses the form. Uploads and
+	// downloads of files, including the archives of data exports, get more time than other
+	// requests.
+	uploads := alice.New(app.limitRequestSize(maxUploadSize), app.extendDeadline(transferTimeout)).Extend(write)
+
+	downloads := alice.New(app.extendDeadline(transferTimeout)).Extend(read)
+
+	exports := alice.New(app.extendDeadline(transferTimeout)).Extend(protected)
+
+	// Metrics are served here unless they have their own listen address.
+	if metricsAddr == "" {
+		mux.Handle("GET /metrics", app.metricsHandler())
+	}
+
+	// Health checks need neither a session nor a CSRF token.
+	mux.HandleFunc("GET /ping", app.ping)
+	mux.Handle("GET /healthz", app.healthz())
+	mux.Handle("GET /readyz", app.readyz())
+
 	// Public routes
-	mux.Handle("GET /{$}", dynamic.ThenFunc(app.home))
-	mux.Handle("GET /ping", dynamic.ThenFunc(app.ping))
-	mux.Handle("GET /events/{id}", dynamic.ThenFunc(app.eventView))
-	mux.Handle("GET /events", dynamic.ThenFunc(app.eventList))
+	mux.Handle("GET /{$}", dynamic.Then(app.handle(app.home)))
+	mux.Handle("POST /locale", dynamic.Then(app.handle(app.localePost)))
+	mux.Handle("GET /events/{id}", read.Then(app.handle(app.eventView)))
+	mux.Handle("GET /events", read.Then(app.handle(app.eventList)))
+	mux.Handle("GET /calendar", read.Then(app.handle(app.eventCalendar)))
+	mux.Handle("GET /attachments/{id}", downloads.Then(app.handle(app.attachmentDownload)))
+	mux.Handle("GET /attachments/{id}/thumbnail", downloads.Then(app.handle(app.attachmentThumbnail)))
 
 	// Protected routes
-	mux.Handle("GET /events/create", protected.ThenFunc(app.eventCreate))
-	mux.Handle("POST /events/create", protected.ThenFunc(app.eventCreatePost))
-	mux.Handle("GET /events/{id}/edit", protected.ThenFunc(app.eventEdit))
-	mux.Handle("POST /events/{id}/edit", protected.ThenFunc(app.eventEditPost))
-	mux.Handle("POST /events/{id}/delete", protected.ThenFunc(app.eventDelete))
+	mux.Handle("GET /events/create", protected.Then(app.handle(app.eventCreate)))
+	mux.Handle("POST /events/create", write.Then(app.handle(app.eventCreatePost)))
+	mux.Handle("POST /events/preview", protected.Then(app.handle(app.eventPreview)))
+	mux.Handle("GET /events/{id}/edit", protected.Then(app.handle(app.eventEdit)))
+	mux.Handle("POST /events/{id}/edit", write.Then(app.handle(app.eventEditPost)))
+	mux.Handle("POST /events/{id}/delete", write.Then(app.handle(app.eventDelete)))
+	mux.Handle("POST /events/{id}/rsvp", write.Then(app.handle(app.eventRSVP)))
+	mux.Handle("POST /events/{id}/rsvp/cancel", write.Then(app.handle(app.eventRSVPCancel)))
+	mux.Handle("POST /events/{id}/attachments", uploads.Then(app.handle(app.eventAttachmentPost)))
+	mux.Handle("POST /attachments/{id}/delete", write.Then(app.handle(app.attachmentDelete)))
+
+	// Account settings routes
+	mux.Handle("GET /account", protected.Then(app.handle(app.account)))
+	mux.Handle("POST /account/profile", protected.Then(app.handle(app.accountProfilePost)))
+	mux.Handle("POST /account/email", protected.Then(app.handle(app.accountEmailPost)))
+	mux.Handle("GET /account/email/verify", dynamic.Then(app.handle(app.accountEmailVerify)))
+	mux.Handle("POST /account/delete", protected.Then(app.handle(app.accountDeletePost)))
+	mux.Handle("POST /account/export", protected.Then(app.handle(app.accountExportPost)))
+	mux.Handle("GET /account/export/download", exports.Then(app.handle(app.accountExportDownload)))
+	mux.Handle("GET /account/security", protected.Then(app.handle(app.accountSecurity)))
+	mux.Handle("GET /account/password", protected.Then(app.handle(app.passwordChange)))
+	mux.Handle("POST /account/password", protected.Then(app.handle(app.passwordChangePost)))
+	mux.Handle("GET /account/sessions", protected.Then(app.handle(app.sessionList)))
+	mux.Handle("POST /account/sessions/{id}/delete", protected.Then(app.handle(app.sessionDelete)))
+	mux.Handle("POST /account/sessions/delete", protected.Then(app.handle(app.sessionDeleteAll)))
+	mux.Handle("GET /account/tokens", protected.Then(app.handle(app.tokenList)))
+	mux.Handle("POST /account/tokens", protected.Then(app.handle(app.tokenCreatePost)))
+	mux.Handle("POST /account/tokens/{id}/delete", protected.Then(app.handle(app.tokenDelete)))
+	mux.Handle("POST /account/2fa/setup", protected.Then(app.handle(app.totpSetupPost)))
+	mux.Handle("GET /account/2fa/setup", protected.Then(app.handle(app.totpSetup)))
+	mux.Handle("GET /account/2fa/qr", protected.Then(app.handle(app.totpQRCode)))
+	mux.Handle("POST /account/2fa/confirm", protected.Then(app.handle(app.totpConfirmPost)))
+	mux.Handle("POST /account/2fa/disable", protected.Then(app.handle(app.totpDisablePost)))
+
+	// Administration routes
+	mux.Handle("GET /admin/webhooks", admin.Then(app.handle(app.webhookList)))
+	mux.Handle("POST /admin/webhooks", admin.Then(app.handle(app.webhookCreatePost)))
+	mux.Handle("GET /admin/webhooks/{id}", admin.Then(app.handle(app.webhookView)))
+	mux.Handle("POST /admin/webhooks/{id}/delete", admin.Then(app.handle(app.webhookDelete)))
+	mux.Handle("GET /admin/audit", admin.Then(app.handle(app.auditList)))
+	mux.Handle("GET /admin/venues", admin.Then(app.handle(app.venueList)))
+	mux.Handle("POST /admin/venues", admin.Then(app.handle(app.venueCreatePost)))
+	mux.Handle("GET /admin/venues/{id}", admin.Then(app.handle(app.venueEdit)))
+	mux.Handle("POST /admin/venues/{id}", admin.Then(app.handle(app.venueEditPost)))
+	mux.Handle("POST /admin/venues/{id}/delete", admin.Then(app.handle(app.venueDelete)))
 
 	// User registration and authentication routes
-	mux.Handle("GET /login", dynamic.ThenFunc(app.userLogin))
-	mux.Handle("POST /login", dynamic.ThenFunc(app.userLoginPost))
-	mux.Handle("GET /register", dynamic.ThenFunc(app.userRegister))
-	mux.Handle("POST /register", dynamic.ThenFunc(app.userRegisterPost))
-	mux.Handle("POST /logout", dynamic.ThenFunc(app.userLogoutPost))
+	mux.Handle("GET /login", dynamic.Then(app.handle(app.userLogin)))
+	mux.Handle("POST /login", dynamic.Then(app.handle(app.userLoginPost)))
+	mux.Handle("GET /login/2fa", dynamic.Then(app.handle(app.userLoginTOTP)))
+	mux.Handle("POST /login/2fa", dynamic.Then(app.handle(app.userLoginTOTPPost)))
+	mux.Handle("GET /login/oidc", dynamic.Then(app.handle(app.userLoginOIDC)))
+	mux.Handle("GET /login/oidc/callback", dynamic.Then(app.handle(app.userLoginOIDCCallback)))
+	mux.Handle("GET /register", dynamic.Then(app.handle(app.userRegister)))
+	mux.Handle("POST /register", dynamic.Then(app.handle(app.userRegisterPost)))
+	mux.Handle("POST /logout", dynamic.Then(app.handle(app.userLogoutPost)))
 
-	// Initialize middleware chain with panic recovery, request logging, and common headers.
-	standardMiddleware := alice.New(app.addPanicRecover, app.addRequestLogger, app.addCommonHeaders)
+	// Initialize middleware chain with request IDs, request logging, panic recovery, common headers, the
+	// request deadline, the request traces and the request metrics.
+	standardMiddleware := alice.New(app.addRequestID, app.addRequestLogger, app.addPanicRecover, app.addCommonHeaders, app.addRequestDeadline(app.requestTimeout), app.addTracing, app.addMetrics)
 
-	return standardMiddleware.Then(mux)
+	return standardMiddleware.Then(app.routeErrors(mux))
 }
@@ -3,7 +3,12 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/i18n"
+	"github.com/madalinpopa/go-event-planner/internal/markdown"
 	"github.com/madalinpopa/go-event-planner/ui"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"html/template"
 	"io/fs"
 	"net/http"
@@ -14,22 +19,47 @@ import (
 
 // functions is a template.FuncMap containing custom template functions for use in HTML templates.
 var functions = template.FuncMap{
-	"humanDate": func(t time.Time) string {
-		if t.IsZero() {
-			return ""
-		}
-		return t.Format("02 Jan 2006 at 15:04")
-	},
 	"formatDate": func(t time.Time) string {
 		if t.IsZero() {
 			return ""
 		}
 		return t.Format("2006-01-02")
 	},
+	"markdown": markdown.Render,
+	"locales":  i18n.Locales,
+}
+
+// localeFunctions returns the template functions writing the text of the locale of the printer:
+// t translates a message, tn the form of a message for a count, and humanDate and formatTime
+// write dates with the names of the months and weekdays of the locale.
+func localeFunctions(p *i18n.Printer) template.FuncMap {
+	return template.FuncMap{
+		"t":          p.Sprintf,
+		"tn":         p.Plural,
+		"humanDate":  p.Date,
+		"formatTime": p.Format,
+		"locale":     p.Locale,
+	}
 }
 
-// newTemplateCache initializes and returns a cache of precompiled templates, or an error if the operation fails.
-func newTemplateCache() (map[string]*template.Template, error) {
+// newTemplateCache initializes and returns a cache of precompiled templates for every supported
+// locale, keyed by locale and then by page, or an error if the operation fails.
+func newTemplateCache() (map[string]map[string]*template.Template, error) {
+	cache := map[string]map[string]*template.Template{}
+
+	for _, locale := range i18n.Locales() {
+		pages, err := newLocaleTemplateCache(i18n.NewPrinter(locale.Code))
+		if err != nil {
+			return nil, err
+		}
+		cache[locale.Code] = pages
+	}
+
+	return cache, nil
+}
+
+// newLocaleTemplateCache returns the precompiled templates of the locale of the printer.
+func newLocaleTemplateCache(p *i18n.Printer) (map[string]*template.Template, error) {
 	cache := map[string]*template.Template{}
 
 	// Walk through all files in the embedded filesystem
@@ -53,13 +83,13 @@ func newTemplateCache() (map[string]*template.Template, error) {
 			path,
 		}
 
-		ts, err := template.New(filepath.Base(path)).Funcs(functions).ParseFS(ui.Files, patterns...)
+		ts, err := template.New(filepath.Base(path)).Funcs(functions).Funcs(localeFunctions(p)).ParseFS(ui.Files, patterns...)
 		if err != nil {
 			return fmt.Errorf("parsing template %s: %w", path, err)
 		}
 
 		cache[name] = ts
-		fmt.Printf("Cached template: %s\n", name)
+		fmt.Printf("Cached template: %s (%s)\n", name, p.Locale())
 		return nil
 	})
 
@@ -74,33 +104,49 @@ func newTemplateCache() (map[string]*template.Template, error) {
 // It checks if the template exists, handles errors, and logs issues appropriately.
 // If the template is successfully rendered, its output is written to the response.
 func (app *App) render(w http.ResponseWriter, r *http.Request, name string, data interface{}, status int) {
+	buf, err := app.execute(r, name, data)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	// Check if the template with the given name exists in the template cache.
-	// If the template is not found, respond with a server error and stop further processing.
-	t, ok := app.templates[name]
+	w.WriteHeader(status)
 
-	if !ok {
-		err := fmt.Errorf("template %s not found", name)
+	_, err = buf.WriteTo(w)
+	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+}
+
+// execute renders the template with the given name in the locale of the request, and returns
+// its output or an error if the template does not exist or fails.
+func (app *App) execute(r *http.Request, name string, data interface{}) (*bytes.Buffer, error) {
+
+	// Check if the template with the given name exists in the template cache of the locale of
+	// the request. If the template is not found, return an error and stop further processing.
+	t, ok := app.templates[app.printer(r).Locale()][name]
+
+	if !ok {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
 
 	// Create a new buffer to hold the rendered template.
 	// The use of a buffer allows for efficient error handling and ensures that
 	// the template output is only written to the response writer after successful processing.
 	buf := new(bytes.Buffer)
 
+	_, span := tracer.Start(r.Context(), "render "+name, trace.WithAttributes(attribute.String("template", name)))
+	start := time.Now()
 	err := t.ExecuteTemplate(buf, "base", data)
+	app.metrics.renderDuration.Observe(time.Since(start).Seconds(), name)
 	if err != nil {
-		app.serverError(w, r, err)
-		return
+		span.SetStatus(codes.Error, err.Error())
 	}
-
-	w.WriteHeader(status)
-
-	_, err = buf.WriteTo(w)
+	span.End()
 	if err != nil {
-		app.serverError(w, r, err)
-		return
+		return nil, err
 	}
+
+	return buf, nil
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/sqlhook"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"time"
+)
+
+// tracer records the spans of the requests, statements and templates.
+var tracer = otel.Tracer("github.com/madalinpopa/go-event-planner/cmd/web")
+
+// addTracing is a middleware that records a span for every request, continuing the trace of
+// the W3C traceparent header when there is one. The span is named after the route pattern,
+// which the ServeMux sets on the request it is given: this middleware must run right before
+// addMetrics, which passes the request on to the ServeMux as it is.
+func (app *App) addTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String(string(semconv.HTTPRequestMethodKey), r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(clientIP(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		r = r.WithContext(ctx)
+		rw := &responseWriter{ResponseWriter: w}
+
+		end := func(status int) {
+			if r.Pattern != "" {
+				span.SetName(r.Pattern)
+				span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+
+		// Panics end the span as the 500 responses addPanicRecover sends.
+		defer func() {
+			if err := recover(); err != nil {
+				span.RecordError(fmt.Errorf("%s", err))
+				end(http.StatusInternalServerError)
+				panic(err)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		end(rw.status)
+	})
+}
+
+// traceQuery is the sqlhook.Hook recording a span for every database statement, as a child of
+// the span of the request running it. The span is recorded once the statement ran, from the
+// time it started.
+func traceQuery(system attribute.KeyValue) sqlhook.Hook {
+	return func(ctx context.Context, query string, start time.Time, err error) {
+		if !trace.SpanFromContext(ctx).IsRecording() {
+			return
+		}
+
+		op := queryOperation(query)
+		_, span := tracer.Start(ctx, op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(start),
+			trace.WithAttributes(system, semconv.DBOperationName(op), semconv.DBQueryText(query)),
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
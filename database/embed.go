@@ -0,0 +1,6 @@
+package database
+
+import "embed"
+
+//go:embed "migrations"
+var Migrations embed.FS
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Each row records a reminder claimed by a scheduler instance. The unique
+-- constraint is what prevents two instances (or a restarted one) from
+-- sending the same reminder twice.
+CREATE TABLE reminders
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id   INTEGER  NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id    INTEGER  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    offset_sec INTEGER  NOT NULL,
+    status     TEXT     NOT NULL DEFAULT 'pending',
+    claimed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at    DATETIME,
+    UNIQUE (event_id, user_id, offset_sec)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reminders;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The end date is optional; single day events leave it NULL.
+ALTER TABLE events ADD COLUMN end_date DATETIME;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN end_date;
+-- +goose StatementEnd
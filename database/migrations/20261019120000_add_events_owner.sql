@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Events created before ownership was introduced have no owner.
+ALTER TABLE events ADD COLUMN user_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN user_id;
+-- +goose StatementEnd
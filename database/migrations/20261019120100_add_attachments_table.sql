@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE attachments
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id      INTEGER      NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    kind          TEXT         NOT NULL,
+    filename      VARCHAR(255) NOT NULL,
+    content_type  TEXT         NOT NULL,
+    size          INTEGER      NOT NULL,
+    storage_key   TEXT         NOT NULL,
+    thumbnail_key TEXT         NOT NULL DEFAULT '',
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX attachments_event_idx ON attachments (event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS attachments_event_idx;
+DROP TABLE IF EXISTS attachments;
+-- +goose StatementEnd
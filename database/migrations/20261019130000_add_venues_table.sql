@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE venues
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       VARCHAR(255) NOT NULL UNIQUE,
+    address    VARCHAR(255) NOT NULL DEFAULT '',
+    capacity   INTEGER      NOT NULL DEFAULT 0,
+    latitude   REAL,
+    longitude  REAL,
+    amenities  TEXT         NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Events keep their free-text location; the venue is optional.
+ALTER TABLE events ADD COLUMN venue_id INTEGER REFERENCES venues (id) ON DELETE SET NULL;
+
+CREATE INDEX events_venue_idx ON events (venue_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS events_venue_idx;
+ALTER TABLE events DROP COLUMN venue_id;
+DROP TABLE IF EXISTS venues;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Token buckets of the rate limiters, refilled lazily when they are used.
+CREATE TABLE rate_limits
+(
+    key        TEXT PRIMARY KEY,
+    tokens     REAL     NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+ALTER TABLE users ADD COLUMN failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until DATETIME;
+
+-- Security relevant events, such as account lockouts, reviewed by admins.
+CREATE TABLE audit_events
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    action     TEXT NOT NULL,
+    ip         TEXT NOT NULL DEFAULT '',
+    detail     TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_created_idx ON audit_events (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_events;
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN failed_logins;
+DROP TABLE IF EXISTS rate_limits;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Personal API tokens authenticating scripts in place of a session. Only the
+-- hash of a token is stored, the token itself is shown once when created.
+CREATE TABLE api_tokens
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    token_hash   TEXT    NOT NULL UNIQUE,
+    scopes       TEXT    NOT NULL DEFAULT '',
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_tokens_user_idx ON api_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Accounts of users at OpenID Connect providers, identified by the issuer and
+-- the subject of their ID tokens. Users provisioned by single sign-on have an
+-- empty password and cannot log in with the password form.
+CREATE TABLE user_identities
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    issuer     TEXT    NOT NULL,
+    subject    TEXT    NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX user_identities_user_idx ON user_identities (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_identities;
+-- +goose StatementEnd
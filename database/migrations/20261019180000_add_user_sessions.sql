@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Authenticated sessions, listed to their users so that they can sign out
+-- other devices. A session is only accepted while it has a row here. The
+-- token of the session is stored hashed, it is only needed for lookups.
+CREATE TABLE user_sessions
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash   TEXT     NOT NULL UNIQUE,
+    user_agent   TEXT     NOT NULL DEFAULT '',
+    ip           TEXT     NOT NULL DEFAULT '',
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at   DATETIME NOT NULL
+);
+
+CREATE INDEX user_sessions_user_idx ON user_sessions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_sessions;
+-- +goose StatementEnd
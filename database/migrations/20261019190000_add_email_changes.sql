@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Pending changes of email addresses, applied once the new address is
+-- confirmed with the link sent to it. A user has at most one pending change.
+CREATE TABLE email_changes
+(
+    user_id    INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    email      TEXT     NOT NULL,
+    token_hash TEXT     NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS email_changes;
+-- +goose StatementEnd
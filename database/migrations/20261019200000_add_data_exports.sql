@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Exports of the personal data of users, built in the background. Once
+-- ready, the archive is kept in the storage under storage_key and can be
+-- downloaded with the token of the link sent to the user until expires_at.
+CREATE TABLE data_exports
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status       TEXT    NOT NULL DEFAULT 'pending',
+    token_hash   TEXT UNIQUE,
+    storage_key  TEXT    NOT NULL DEFAULT '',
+    size         INTEGER NOT NULL DEFAULT 0,
+    error        TEXT    NOT NULL DEFAULT '',
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    claimed_at   DATETIME,
+    completed_at DATETIME,
+    expires_at   DATETIME
+);
+
+CREATE INDEX data_exports_user_idx ON data_exports (user_id);
+CREATE INDEX data_exports_status_idx ON data_exports (status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS data_exports;
+-- +goose StatementEnd
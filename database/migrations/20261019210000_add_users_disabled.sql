@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Disabled accounts cannot log in, and their sessions and API tokens stop
+-- working, until an administrator enables them again.
+ALTER TABLE users ADD COLUMN disabled_at DATETIME;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN disabled_at;
+-- +goose StatementEnd
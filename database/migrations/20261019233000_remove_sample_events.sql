@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The sample events inserted by 20241219190136_add_data.sql are removed, now
+-- that sample data is generated by cmd/seed. They are matched on all of their
+-- columns, and they never had an owner, so that events created by users with
+-- the same titles are kept.
+DELETE
+FROM events
+WHERE user_id IS NULL
+  AND (title, event_date, location) IN (
+    VALUES ('Tech Conference 2025', '2025-03-15 09:00:00', 'Convention Center, San Francisco'),
+           ('Team Building Workshop', '2024-12-28 13:00:00', 'Downtown Business Center'),
+           ('Product Launch Event', '2025-01-20 18:30:00', 'Tech Hub, Austin'),
+           ('Agile Development Seminar', '2025-02-10 10:00:00', 'Online - Virtual Event'),
+           ('End of Year Party', '2024-12-31 19:00:00', 'Grand Hotel Ballroom')
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The sample events are not restored.
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,226 @@
+-- +goose Up
+-- +goose StatementBegin
+-- PostgreSQL schema, equivalent to the SQLite migrations up to this
+-- version. Later migrations are added to both directories under the same
+-- version.
+CREATE TABLE users
+(
+    id             BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name           TEXT        NOT NULL,
+    email          TEXT        NOT NULL UNIQUE,
+    password       TEXT        NOT NULL,
+    role           TEXT        NOT NULL DEFAULT 'user',
+    failed_logins  INTEGER     NOT NULL DEFAULT 0,
+    locked_until   TIMESTAMPTZ,
+    totp_secret    TEXT        NOT NULL DEFAULT '',
+    totp_last_step BIGINT      NOT NULL DEFAULT 0,
+    disabled_at    TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE venues
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name       VARCHAR(255)     NOT NULL UNIQUE,
+    address    VARCHAR(255)     NOT NULL DEFAULT '',
+    capacity   INTEGER          NOT NULL DEFAULT 0,
+    latitude   DOUBLE PRECISION,
+    longitude  DOUBLE PRECISION,
+    amenities  TEXT             NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ      NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE events
+(
+    id          BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id     BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    venue_id    BIGINT REFERENCES venues (id) ON DELETE SET NULL,
+    title       VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL DEFAULT '',
+    event_date  TIMESTAMPTZ  NOT NULL,
+    end_date    TIMESTAMPTZ,
+    location    VARCHAR(255),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX events_user_idx ON events (user_id);
+CREATE INDEX events_venue_idx ON events (venue_id);
+CREATE INDEX events_date_idx ON events (event_date);
+
+CREATE TABLE sessions
+(
+    token  TEXT PRIMARY KEY,
+    data   BYTEA       NOT NULL,
+    expiry TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+
+CREATE TABLE rsvps
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id   BIGINT      NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, user_id)
+);
+CREATE INDEX rsvps_user_idx ON rsvps (user_id);
+
+CREATE TABLE reminders
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id   BIGINT      NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    offset_sec INTEGER     NOT NULL,
+    status     TEXT        NOT NULL DEFAULT 'pending',
+    claimed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at    TIMESTAMPTZ,
+    UNIQUE (event_id, user_id, offset_sec)
+);
+
+CREATE TABLE webhook_subscriptions
+(
+    id          BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    url         TEXT        NOT NULL,
+    secret      TEXT        NOT NULL,
+    event_types TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id              BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_type      TEXT        NOT NULL,
+    payload         TEXT        NOT NULL,
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE webhook_attempts
+(
+    id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    delivery_id  BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    status_code  INTEGER     NOT NULL DEFAULT 0,
+    error        TEXT        NOT NULL DEFAULT '',
+    duration_ms  INTEGER     NOT NULL DEFAULT 0,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE attachments
+(
+    id            BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id      BIGINT       NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    kind          TEXT         NOT NULL,
+    filename      VARCHAR(255) NOT NULL,
+    content_type  TEXT         NOT NULL,
+    size          BIGINT       NOT NULL,
+    storage_key   TEXT         NOT NULL,
+    thumbnail_key TEXT         NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX attachments_event_idx ON attachments (event_id);
+
+CREATE TABLE rate_limits
+(
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL
+);
+
+CREATE TABLE audit_events
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    action     TEXT        NOT NULL,
+    ip         TEXT        NOT NULL DEFAULT '',
+    detail     TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX audit_events_created_idx ON audit_events (created_at);
+
+CREATE TABLE recovery_codes
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  TEXT        NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE api_tokens
+(
+    id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL,
+    token_hash   TEXT        NOT NULL UNIQUE,
+    scopes       TEXT        NOT NULL DEFAULT '',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX api_tokens_user_idx ON api_tokens (user_id);
+
+CREATE TABLE user_identities
+(
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    issuer     TEXT        NOT NULL,
+    subject    TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (issuer, subject)
+);
+CREATE INDEX user_identities_user_idx ON user_identities (user_id);
+
+CREATE TABLE user_sessions
+(
+    id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash   TEXT        NOT NULL UNIQUE,
+    user_agent   TEXT        NOT NULL DEFAULT '',
+    ip           TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX user_sessions_user_idx ON user_sessions (user_id);
+
+CREATE TABLE email_changes
+(
+    user_id    BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    email      TEXT        NOT NULL,
+    token_hash TEXT        NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE data_exports
+(
+    id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id      BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status       TEXT        NOT NULL DEFAULT 'pending',
+    token_hash   TEXT UNIQUE,
+    storage_key  TEXT        NOT NULL DEFAULT '',
+    size         BIGINT      NOT NULL DEFAULT 0,
+    error        TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    claimed_at   TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ
+);
+CREATE INDEX data_exports_user_idx ON data_exports (user_id);
+CREATE INDEX data_exports_status_idx ON data_exports (status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE data_exports, email_changes, user_sessions, user_identities, api_tokens, recovery_codes,
+    audit_events, rate_limits, attachments, webhook_attempts, webhook_deliveries, webhook_subscriptions,
+    reminders, rsvps, sessions, events, venues, users;
+-- +goose StatementEnd
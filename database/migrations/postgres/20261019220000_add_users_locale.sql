@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The language chosen by the user for the interface; empty lets the browser
+-- choose.
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN locale;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A claimed reminder is reserved until locked_until. A pending claim whose
+-- lease has expired, left behind by a scheduler which stopped while sending,
+-- can be claimed again.
+ALTER TABLE reminders ADD COLUMN locked_until TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE reminders DROP COLUMN locked_until;
+-- +goose StatementEnd
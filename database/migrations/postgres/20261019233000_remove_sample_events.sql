@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The sample events were only ever inserted into SQLite databases; the
+-- migration keeps the versions of both directories aligned.
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
@@ -0,0 +1,254 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrNoSchema is returned when a database has no migrations applied.
+var ErrNoSchema = errors.New("backup: the database has no migrations applied")
+
+// Create writes a consistent copy of the live database to path using
+// VACUUM INTO, which does not block the writers of the database for longer
+// than a regular read. The copy is checked before it is moved to path, so
+// that path only ever holds a complete and valid backup.
+func Create(ctx context.Context, db *sql.DB, path string) error {
+	tmp := path + ".tmp"
+
+	// VACUUM INTO refuses to overwrite a file, which may be left by a
+	// backup interrupted before.
+	err := os.Remove(tmp)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "VACUUM INTO ?", tmp)
+	if err != nil {
+		return err
+	}
+
+	err = Verify(ctx, tmp)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Verify checks the integrity of the database file at path and that its
+// foreign keys are consistent.
+func Verify(ctx context.Context, path string) error {
+	db, err := openReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		err = rows.Scan(&result)
+		if err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup: %s failed the integrity check: %s", path, strings.Join(problems, "; "))
+	}
+
+	var table string
+	err = db.QueryRowContext(ctx, "PRAGMA foreign_key_check").Scan(&table, new(any), new(any), new(any))
+	if err == nil {
+		return fmt.Errorf("backup: %s has rows of %s referencing missing rows", path, table)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the last migration applied to the
+// database, as recorded by goose.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	// goose records every migration and rollback as a row; a version is
+	// applied when its last row says so.
+	stmt := `SELECT version_id FROM goose_db_version AS g
+	WHERE version_id > 0 AND is_applied
+	AND id = (SELECT MAX(id) FROM goose_db_version WHERE version_id = g.version_id)
+	ORDER BY version_id DESC LIMIT 1`
+
+	var version int64
+	err := db.QueryRowContext(ctx, stmt).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) || (err != nil && strings.Contains(err.Error(), "no such table")) {
+		return 0, ErrNoSchema
+	}
+	return version, err
+}
+
+// FileSchemaVersion returns the schema version of the database file at path.
+func FileSchemaVersion(ctx context.Context, path string) (int64, error) {
+	db, err := openReadOnly(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = db.Close() }()
+
+	return SchemaVersion(ctx, db)
+}
+
+// Versions returns the sorted versions of the goose migrations found in the
+// directory of fsys.
+func Versions(fsys fs.FS, dir string) ([]int64, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok || entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	slices.Sort(versions)
+	return versions, nil
+}
+
+// Pending returns how many of the migrations come after the schema version.
+// It fails when the version is not one of the migrations, which happens with
+// a database of a newer release or of another application.
+func Pending(version int64, versions []int64) (int, error) {
+	i, found := slices.BinarySearch(versions, version)
+	if !found {
+		if len(versions) > 0 && version > versions[len(versions)-1] {
+			return 0, fmt.Errorf("backup: schema version %d is newer than the latest migration %d", version, versions[len(versions)-1])
+		}
+		return 0, fmt.Errorf("backup: schema version %d is not one of the migrations", version)
+	}
+	return len(versions) - i - 1, nil
+}
+
+// Restore replaces the database at dst with the backup at src. The backup
+// is checked, and its schema version must be one of the migrations; the
+// number of migrations left to apply to it is returned. The replaced
+// database is kept as dst with the ".pre-restore" suffix.
+//
+// The application must be stopped while the database is restored.
+func Restore(ctx context.Context, src, dst string, versions []int64) (int, error) {
+	err := Verify(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := FileSchemaVersion(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := Pending(version, versions)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp := dst + ".restore"
+	err = copyFile(src, tmp)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+
+	err = os.Rename(dst, dst+".pre-restore")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+
+	// A journal left next to the replaced database would be applied to the
+	// restored one when it is next opened.
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		err = os.Remove(dst + suffix)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, err
+		}
+	}
+
+	return pending, os.Rename(tmp, dst)
+}
+
+// copyFile copies the file at src to dst and flushes it to the disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openReadOnly opens the database file at path, which must exist, without
+// allowing any change to it.
+func openReadOnly(path string) (*sql.DB, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,180 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/health"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The backups are named after the time they were taken, so that sorting the
+// names sorts the backups.
+const (
+	namePrefix = "events-"
+	nameSuffix = ".db"
+	nameLayout = "20060102T150405Z"
+)
+
+// checkEvery is the longest time between two checks of whether a backup is
+// due.
+const checkEvery = time.Minute
+
+// Info describes a backup file.
+type Info struct {
+	Path    string
+	TakenAt time.Time
+	Size    int64
+}
+
+// List returns the backups in the directory, from the oldest to the newest.
+// A missing directory has no backups.
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Info
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), namePrefix)
+		stamp, ok2 := strings.CutSuffix(stamp, nameSuffix)
+		if !ok || !ok2 || entry.IsDir() {
+			continue
+		}
+
+		takenAt, err := time.Parse(nameLayout, stamp)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, Info{
+			Path:    filepath.Join(dir, entry.Name()),
+			TakenAt: takenAt,
+			Size:    info.Size(),
+		})
+	}
+
+	slices.SortFunc(backups, func(a, b Info) int { return a.TakenAt.Compare(b.TakenAt) })
+	return backups, nil
+}
+
+// Scheduler periodically backs up the database into a directory and deletes
+// the oldest backups beyond the retention.
+type Scheduler struct {
+	DB     *sql.DB
+	Logger *slog.Logger
+
+	// Dir is the directory of the backups, created when missing.
+	Dir string
+
+	// Interval is the time between two backups.
+	Interval time.Duration
+
+	// Keep is the number of backups kept; zero keeps them all.
+	Keep int
+
+	// Heartbeat, when set, beats after every check of whether a backup is due.
+	Heartbeat *health.Heartbeat
+}
+
+// Run takes a backup whenever the newest one is older than the interval,
+// until the context is cancelled. Restarting the application does not
+// take a backup unless one is due.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(min(s.Interval, checkEvery))
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+
+		due, err := s.due(now)
+		if err != nil {
+			s.Logger.Error(err.Error())
+		}
+		if due {
+			path, deleted, err := s.Backup(ctx, now)
+			if err != nil {
+				s.Logger.Error("Backup failed", "error", err.Error())
+			} else {
+				s.Logger.Info("Backed up the database", "path", path, "deleted", len(deleted))
+			}
+		}
+		s.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Backup backs up the database into the directory, named after the time now,
+// and applies the retention. It returns the path of the backup and the paths
+// of the deleted ones.
+func (s *Scheduler) Backup(ctx context.Context, now time.Time) (string, []string, error) {
+	err := os.MkdirAll(s.Dir, 0o755)
+	if err != nil {
+		return "", nil, err
+	}
+
+	path := filepath.Join(s.Dir, namePrefix+now.UTC().Format(nameLayout)+nameSuffix)
+	err = Create(ctx, s.DB, path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	deleted, err := s.Rotate()
+	return path, deleted, err
+}
+
+// Rotate deletes the oldest backups beyond the number kept and returns their
+// paths.
+func (s *Scheduler) Rotate() ([]string, error) {
+	if s.Keep <= 0 {
+		return nil, nil
+	}
+
+	backups, err := List(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for len(backups) > s.Keep {
+		err = os.Remove(backups[0].Path)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, backups[0].Path)
+		backups = backups[1:]
+	}
+	return deleted, nil
+}
+
+// due reports whether the newest backup is older than the interval.
+func (s *Scheduler) due(now time.Time) (bool, error) {
+	backups, err := List(s.Dir)
+	if err != nil {
+		return false, err
+	}
+	if len(backups) == 0 {
+		return true, nil
+	}
+	return !now.Before(backups[len(backups)-1].TakenAt.Add(s.Interval)), nil
+}
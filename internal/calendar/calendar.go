@@ -0,0 +1,197 @@
+package calendar
+
+import (
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"time"
+)
+
+// Calendar view kinds.
+const (
+	Month = "month"
+	Week  = "week"
+	Day   = "day"
+)
+
+// Query parameter layouts for the date of each view kind.
+const (
+	monthLayout = "2006-01"
+	dayLayout   = "2006-01-02"
+)
+
+// Entry is an event placed on a single day of the calendar. Events spanning
+// several days have one entry per day, flagged so that the cells can be
+// drawn as one continuous bar.
+type Entry struct {
+	Event models.Event
+
+	// Starts and Ends report whether the event starts or ends on this day.
+	Starts bool
+	Ends   bool
+
+	// ShowTitle is set on the first day of the event and on the first day of
+	// every row the event continues into.
+	ShowTitle bool
+}
+
+// Cell is a single day of the calendar.
+type Cell struct {
+	Date    time.Time
+	InRange bool
+	IsToday bool
+	Entries []Entry
+}
+
+// View is a month, week or day calendar ready to be rendered.
+type View struct {
+	Kind  string
+	Title string
+
+	// Date is the day the view was requested for.
+	Date time.Time
+
+	// Start and End delimit the days displayed, End being exclusive.
+	Start time.Time
+	End   time.Time
+
+	// Rows holds the cells of the view grouped by week.
+	Rows [][]Cell
+
+	// Prev, Next and Today hold the date query parameter for navigation.
+	Prev  string
+	Next  string
+	Today string
+}
+
+// Parse validates the view kind and parses the date query parameter, which
+// is formatted as 2006-01 for month views and 2006-01-02 otherwise. An empty
+// value selects the date of today.
+func Parse(kind, value string, today time.Time) (string, time.Time, error) {
+	if kind == "" {
+		kind = Month
+	}
+
+	if kind != Month && kind != Week && kind != Day {
+		return "", time.Time{}, fmt.Errorf("calendar: unknown view %q", kind)
+	}
+
+	if value == "" {
+		return kind, truncate(today), nil
+	}
+
+	layout := dayLayout
+	if kind == Month {
+		layout = monthLayout
+	}
+
+	date, err := time.Parse(layout, value)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("calendar: invalid date %q: %w", value, err)
+	}
+
+	return kind, date, nil
+}
+
+// Range returns the first day displayed by the view of the given kind
+// containing date, and the day after the last one displayed. Month views
+// cover whole weeks, starting on Monday.
+func Range(kind string, date time.Time) (time.Time, time.Time) {
+	date = truncate(date)
+
+	switch kind {
+	case Day:
+		return date, date.AddDate(0, 0, 1)
+	case Week:
+		start := startOfWeek(date)
+		return start, start.AddDate(0, 0, 7)
+	default:
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1)
+		return startOfWeek(first), startOfWeek(last).AddDate(0, 0, 7)
+	}
+}
+
+// Formatter formats a time with a layout of time.Time.Format, such as a
+// localized version of it.
+type Formatter func(t time.Time, layout string) string
+
+// New builds the view of the given kind containing date, placing the events
+// on every day they span. Events are expected to be ordered by date. The
+// title is written with format, or time.Time.Format when it is nil.
+func New(kind string, date, today time.Time, events []models.Event, format Formatter) View {
+	if format == nil {
+		format = time.Time.Format
+	}
+
+	date = truncate(date)
+	today = truncate(today)
+	start, end := Range(kind, date)
+
+	v := View{
+		Kind:  kind,
+		Date:  date,
+		Start: start,
+		End:   end,
+	}
+
+	layout := dayLayout
+	switch kind {
+	case Day:
+		v.Title = format(date, "Monday, 02 January 2006")
+		v.Prev = date.AddDate(0, 0, -1).Format(layout)
+		v.Next = date.AddDate(0, 0, 1).Format(layout)
+	case Week:
+		v.Title = fmt.Sprintf("%s – %s", format(start, "02 Jan"), format(end.AddDate(0, 0, -1), "02 Jan 2006"))
+		v.Prev = start.AddDate(0, 0, -7).Format(layout)
+		v.Next = start.AddDate(0, 0, 7).Format(layout)
+	default:
+		layout = monthLayout
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		v.Title = format(first, "January 2006")
+		v.Prev = first.AddDate(0, -1, 0).Format(layout)
+		v.Next = first.AddDate(0, 1, 0).Format(layout)
+	}
+	v.Today = today.Format(layout)
+
+	var row []Cell
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		cell := Cell{
+			Date:    day,
+			InRange: kind != Month || day.Month() == date.Month(),
+			IsToday: day.Equal(today),
+		}
+
+		for _, e := range events {
+			first, last := truncate(e.EventDate), truncate(e.LastDate())
+			if day.Before(first) || day.After(last) {
+				continue
+			}
+
+			cell.Entries = append(cell.Entries, Entry{
+				Event:     e,
+				Starts:    day.Equal(first),
+				Ends:      day.Equal(last),
+				ShowTitle: day.Equal(first) || len(row) == 0,
+			})
+		}
+
+		row = append(row, cell)
+		if len(row) == 7 || kind == Day {
+			v.Rows = append(v.Rows, row)
+			row = nil
+		}
+	}
+
+	return v
+}
+
+// truncate returns midnight UTC of the day of t.
+func truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// startOfWeek returns the Monday of the week containing the day.
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,232 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/health"
+	"github.com/madalinpopa/go-event-planner/internal/mail"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/storage"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// ContentType is the content type of the archives.
+const ContentType = "application/zip"
+
+// staleAfter is the time after which an export left running, by a worker
+// which stopped, is claimed again.
+const staleAfter = time.Hour
+
+// Worker periodically builds the queued exports of personal data. Each
+// archive is kept in the storage until it expires, and the user is emailed a
+// link to download it.
+type Worker struct {
+	Exports models.ExportRepository
+	Storage storage.Storage
+	Mailer  mail.Mailer
+	Logger  *slog.Logger
+
+	// Interval is the time between two scans of the queue.
+	Interval time.Duration
+
+	// Lifetime is how long an archive can be downloaded once built.
+	Lifetime time.Duration
+
+	// DownloadURL is the URL of the download page, to which the token of
+	// an export is appended.
+	DownloadURL string
+
+	// Heartbeat, when set, beats after every scan of the queue.
+	Heartbeat *health.Heartbeat
+}
+
+// Run builds the queued exports immediately and then on every tick of the
+// interval, until the context is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.Process(ctx, time.Now())
+		w.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process deletes the expired archives and builds every queued export.
+func (w *Worker) Process(ctx context.Context, now time.Time) {
+	keys, err := w.Exports.Prune(ctx, now, 0)
+	if err != nil {
+		w.Logger.Error(err.Error())
+	}
+	for _, key := range keys {
+		err := w.Storage.Delete(ctx, key)
+		if err != nil {
+			w.Logger.Error(err.Error(), "key", key)
+		}
+	}
+
+	for ctx.Err() == nil {
+		e, ok, err := w.Exports.Claim(ctx, now, staleAfter)
+		if err != nil {
+			w.Logger.Error(err.Error())
+			return
+		}
+		if !ok {
+			return
+		}
+
+		err = w.build(ctx, e, now)
+		if err != nil {
+			w.Logger.Error(err.Error(), "export", e.ID, "user", e.UserID)
+
+			// The failure is recorded even when the worker is stopping.
+			err = w.Exports.Fail(context.WithoutCancel(ctx), e.ID, err.Error())
+			if err != nil {
+				w.Logger.Error(err.Error(), "export", e.ID, "user", e.UserID)
+			}
+		}
+	}
+}
+
+// build writes the archive of the export to the storage, marks it ready and
+// emails the download link to the user.
+func (w *Worker) build(ctx context.Context, e models.Export, now time.Time) error {
+	data, err := w.Exports.Collect(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	// The archive is written to a temporary file rather than memory, as
+	// the accounts with the most data are the reason exports are built in
+	// the background.
+	f, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	err = Write(f, data, now)
+	if err != nil {
+		return err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	key, err := storageKey(e.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = w.Storage.Put(ctx, key, f, ContentType)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := now.Add(w.Lifetime)
+	token, err := w.Exports.Complete(ctx, e.ID, key, size, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	w.Logger.Info("data export ready", "export", e.ID, "user", e.UserID, "size", size)
+
+	return w.Mailer.Send(ctx, mail.Message{
+		To:      data.User.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nThe archive of your Event Planner data is ready. Download it before %s:\n\n%s%s\n",
+			data.User.Name, expiresAt.UTC().Format("2 Jan 2006 15:04 MST"), w.DownloadURL, token),
+	})
+}
+
+// storageKey returns a new random key under which an archive of the user is stored.
+func storageKey(userID int) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("exports/%d/%s.zip", userID, hex.EncodeToString(b)), nil
+}
+
+// readme describes the content of the archives.
+const readme = `This archive holds the personal data stored about you by Event Planner,
+as of %s.
+
+user.json          your account
+events.json        the events you own
+rsvps.json         the events you attend
+sessions.json      the devices where you are signed in
+audit_events.json  security events of your account, such as password changes
+identities.json    the single sign-on accounts linked to yours
+api_tokens.json    your API tokens, without the tokens themselves
+
+Passwords, two-factor secrets and tokens are only stored hashed and are not
+included.
+`
+
+// Write writes the data as a ZIP archive of JSON documents, one per kind of
+// data, generated at the time now.
+func Write(w io.Writer, data models.PersonalData, now time.Time) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"user.json", newUser(data.User)},
+		{"events.json", mapSlice(data.Events, newEvent)},
+		{"rsvps.json", mapSlice(data.Attendances, newRSVP)},
+		{"sessions.json", mapSlice(data.Sessions, newSession)},
+		{"audit_events.json", mapSlice(data.AuditEvents, newAuditEvent)},
+		{"identities.json", mapSlice(data.Identities, newIdentity)},
+		{"api_tokens.json", mapSlice(data.APITokens, newAPIToken)},
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "README.txt", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, readme, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(file.v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+
+	return zw.Close()
+}
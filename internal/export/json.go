@@ -0,0 +1,165 @@
+package export
+
+import (
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"time"
+)
+
+// The types below are the JSON representations of the data in the archives.
+// Optional times are nil, encoded as null, when unset.
+
+type user struct {
+	ID                      int       `json:"id"`
+	Name                    string    `json:"name"`
+	Email                   string    `json:"email"`
+	Role                    string    `json:"role"`
+	HasPassword             bool      `json:"has_password"`
+	TwoFactorAuthentication bool      `json:"two_factor_authentication"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+func newUser(u models.User) user {
+	return user{
+		ID:                      u.ID,
+		Name:                    u.Name,
+		Email:                   u.Email,
+		Role:                    u.Role,
+		HasPassword:             u.HasPassword,
+		TwoFactorAuthentication: u.TOTPEnabled,
+		CreatedAt:               u.CreatedAt,
+	}
+}
+
+type event struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	VenueID     *int       `json:"venue_id"`
+	EventDate   time.Time  `json:"event_date"`
+	EndDate     *time.Time `json:"end_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func newEvent(e models.Event) event {
+	return event{
+		ID:          e.Id,
+		Title:       e.Title,
+		Description: e.Description,
+		Location:    e.Location,
+		VenueID:     optionalID(e.VenueID),
+		EventDate:   e.EventDate,
+		EndDate:     optionalTime(e.EndDate),
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+type rsvp struct {
+	EventID    int       `json:"event_id"`
+	EventTitle string    `json:"event_title"`
+	EventDate  time.Time `json:"event_date"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newRSVP(a models.Attendance) rsvp {
+	return rsvp{
+		EventID:    a.EventID,
+		EventTitle: a.EventTitle,
+		EventDate:  a.EventDate,
+		CreatedAt:  a.CreatedAt,
+	}
+}
+
+type session struct {
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func newSession(s models.Session) session {
+	return session{
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
+
+type auditEvent struct {
+	Action    string    `json:"action"`
+	IP        string    `json:"ip"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newAuditEvent(e models.AuditEvent) auditEvent {
+	return auditEvent{
+		Action:    e.Action,
+		IP:        e.IP,
+		Detail:    e.Detail,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+type identity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newIdentity(i models.Identity) identity {
+	return identity{
+		Issuer:    i.Issuer,
+		Subject:   i.Subject,
+		CreatedAt: i.CreatedAt,
+	}
+}
+
+type apiToken struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIToken(t models.APIToken) apiToken {
+	return apiToken{
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  optionalTime(t.ExpiresAt),
+		LastUsedAt: optionalTime(t.LastUsedAt),
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// mapSlice converts every item with f. It returns an empty slice rather than
+// nil, so that empty lists are encoded as [] instead of null.
+func mapSlice[T, U any](items []T, f func(T) U) []U {
+	out := make([]U, 0, len(items))
+	for _, item := range items {
+		out = append(out, f(item))
+	}
+	return out
+}
+
+// optionalTime returns nil for the zero time.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// optionalID returns nil for the zero ID.
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd)
+
+package health
+
+import "errors"
+
+// freeSpace is not supported on this system.
+func freeSpace(string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
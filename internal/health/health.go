@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The statuses of a check and of a report.
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Check is a named check of a dependency of the application, which fails
+// when Run returns an error.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is the outcome of a set of checks. Its status is ok when every
+// check passed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run runs the checks concurrently and reports their outcome. Each check is
+// given at most timeout to complete.
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.Run(ctx)
+			result := Result{Status: StatusOK, DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status = StatusFailing
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.Name] = result
+			if err != nil {
+				report.Status = StatusFailing
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Handler returns a handler serving the report of the checks as JSON, with a
+// 200 status when they pass and a 503 one otherwise.
+func Handler(timeout time.Duration, checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), timeout, checks...)
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Heartbeat records when a background worker last completed a pass. Beating
+// a nil Heartbeat does nothing, so that workers can beat unconditionally.
+type Heartbeat struct {
+	stale time.Duration
+	last  atomic.Int64
+}
+
+// NewHeartbeat returns a heartbeat which is stale when it has not beaten for
+// longer than stale. It counts as having just beaten, so that the worker has
+// the same time to complete its first pass.
+func NewHeartbeat(stale time.Duration) *Heartbeat {
+	h := &Heartbeat{stale: stale}
+	h.Beat()
+	return h
+}
+
+// Beat records that the worker completed a pass.
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns the time of the last beat.
+func (h *Heartbeat) Last() time.Time {
+	return time.Unix(0, h.last.Load())
+}
+
+// Check fails when the heartbeat is stale, which happens when the worker
+// stopped or is stuck.
+func (h *Heartbeat) Check(context.Context) error {
+	last := h.Last()
+	if time.Since(last) > h.stale {
+		return fmt.Errorf("no heartbeat since %s", last.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// DiskSpace returns a check failing when the file system holding path has
+// less than min bytes available. It always passes on the systems whose free
+// space cannot be read.
+func DiskSpace(path string, min uint64) func(ctx context.Context) error {
+	return func(context.Context) error {
+		free, err := freeSpace(path)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if free < min {
+			return fmt.Errorf("%d bytes available, below the minimum of %d", free, min)
+		}
+		return nil
+	}
+}
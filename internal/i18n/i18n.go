@@ -0,0 +1,273 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default is the locale of the messages written in the source code, used
+// when no supported locale matches.
+const Default = "en"
+
+//go:embed "locales"
+var files embed.FS
+
+// Locale is a supported locale.
+type Locale struct {
+	// Code is the language code, such as "ro".
+	Code string
+
+	// Name is the name of the language in that language.
+	Name string
+}
+
+// catalog holds the translations of a locale. Messages are keyed by their
+// English text, and plurals by the English text of their singular form.
+type catalog struct {
+	Name string `json:"name"`
+	Date struct {
+		// Layout is the layout of Printer.Date.
+		Layout string `json:"layout"`
+
+		// The names of the months, from January, and of the weekdays, from
+		// Sunday.
+		Months      []string `json:"months"`
+		ShortMonths []string `json:"shortMonths"`
+		Days        []string `json:"days"`
+		ShortDays   []string `json:"shortDays"`
+	} `json:"date"`
+	Messages map[string]string `json:"messages"`
+
+	// Plurals map the forms of a message to its plural categories, such
+	// as "one" and "other".
+	Plurals map[string]map[string]string `json:"plurals"`
+}
+
+// printers holds the printer of every supported locale.
+var printers = mustLoad()
+
+// mustLoad reads the embedded catalogs, one locales/<code>.json file per
+// locale. It panics when one is not valid, which only happens when the
+// catalogs are edited.
+func mustLoad() map[string]*Printer {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	printers := make(map[string]*Printer)
+	for _, entry := range entries {
+		code, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		b, err := files.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var c catalog
+		err = json.Unmarshal(b, &c)
+		if err != nil {
+			panic(fmt.Sprintf("i18n: catalog %s: %v", entry.Name(), err))
+		}
+		if len(c.Date.Months) != 12 || len(c.Date.ShortMonths) != 12 || len(c.Date.Days) != 7 || len(c.Date.ShortDays) != 7 {
+			panic(fmt.Sprintf("i18n: catalog %s: the names of 12 months and 7 days are required", entry.Name()))
+		}
+
+		printers[code] = &Printer{locale: code, catalog: &c}
+	}
+
+	if printers[Default] == nil {
+		panic("i18n: the catalog of the default locale is missing")
+	}
+	return printers
+}
+
+// Locales returns the supported locales, ordered by code.
+func Locales() []Locale {
+	locales := make([]Locale, 0, len(printers))
+	for code, p := range printers {
+		locales = append(locales, Locale{Code: code, Name: p.catalog.Name})
+	}
+	slices.SortFunc(locales, func(a, b Locale) int { return strings.Compare(a.Code, b.Code) })
+	return locales
+}
+
+// Supported reports whether the locale is supported.
+func Supported(locale string) bool {
+	_, ok := printers[locale]
+	return ok
+}
+
+// Match returns the supported locale preferred by an Accept-Language header,
+// or Default when none of its languages is supported. Regional variants, such
+// as "ro-MD", match their language.
+func Match(acceptLanguage string) string {
+	type choice struct {
+		locale string
+		q      float64
+	}
+
+	var choices []choice
+	for _, field := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(field), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(lang) {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			choices = append(choices, choice{locale: lang, q: q})
+		}
+	}
+
+	// The languages of equal quality keep the order of the header.
+	slices.SortStableFunc(choices, func(a, b choice) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if len(choices) == 0 {
+		return Default
+	}
+	return choices[0].locale
+}
+
+// Printer writes the messages and dates of a locale.
+type Printer struct {
+	locale  string
+	catalog *catalog
+}
+
+// NewPrinter returns the printer of the locale, or of Default when the locale
+// is not supported.
+func NewPrinter(locale string) *Printer {
+	if p, ok := printers[locale]; ok {
+		return p
+	}
+	return printers[Default]
+}
+
+// Locale returns the code of the locale of the printer.
+func (p *Printer) Locale() string {
+	return p.locale
+}
+
+// Sprintf translates the message and formats it with the arguments as
+// fmt.Sprintf does. Messages without a translation are written in English.
+func (p *Printer) Sprintf(message string, args ...any) string {
+	if translated, ok := p.catalog.Messages[message]; ok && translated != "" {
+		message = translated
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Plural translates the form of the message for the count n, given by its
+// English singular and plural forms, and formats it with the arguments, or
+// with n when there are none.
+func (p *Printer) Plural(n int, singular, plural string, args ...any) string {
+	message := plural
+	if n == 1 {
+		message = singular
+	}
+	if forms, ok := p.catalog.Plurals[singular]; ok {
+		if form, ok := forms[pluralCategory(p.locale, n)]; ok {
+			message = form
+		}
+	}
+
+	if len(args) == 0 {
+		args = []any{n}
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Date writes the date and time in the format of the locale, or nothing for
+// the zero time.
+func (p *Printer) Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return p.Format(t, p.catalog.Date.Layout)
+}
+
+// names are the layout elements of the names of the months and weekdays,
+// ordered so that "January" is found before its prefix "Jan".
+var names = []string{"January", "Jan", "Monday", "Mon"}
+
+// Format formats the time as time.Time.Format does, writing the names of the
+// months and weekdays in the language of the locale.
+func (p *Printer) Format(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		i, name := len(layout), ""
+		for _, n := range names {
+			if j := strings.Index(layout, n); j >= 0 && j < i {
+				i, name = j, n
+			}
+		}
+
+		b.WriteString(t.Format(layout[:i]))
+		if name == "" {
+			break
+		}
+
+		switch name {
+		case "January":
+			b.WriteString(p.catalog.Date.Months[t.Month()-1])
+		case "Jan":
+			b.WriteString(p.catalog.Date.ShortMonths[t.Month()-1])
+		case "Monday":
+			b.WriteString(p.catalog.Date.Days[t.Weekday()])
+		case "Mon":
+			b.WriteString(p.catalog.Date.ShortDays[t.Weekday()])
+		}
+		layout = layout[i+len(name):]
+	}
+	return b.String()
+}
+
+// pluralCategory returns the CLDR plural category of the count n in the
+// locale.
+func pluralCategory(locale string, n int) string {
+	switch locale {
+	case "ro":
+		if n == 1 {
+			return "one"
+		}
+		if n == 0 || (n%100 >= 1 && n%100 <= 19) {
+			return "few"
+		}
+		return "other"
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
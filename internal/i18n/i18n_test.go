@@ -0,0 +1,88 @@
+package i18n
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "Empty", acceptLanguage: "", want: Default},
+		{name: "Supported", acceptLanguage: "ro", want: "ro"},
+		{name: "Regional variant", acceptLanguage: "ro-MD", want: "ro"},
+		{name: "Upper case", acceptLanguage: "RO-RO", want: "ro"},
+		{name: "Unsupported", acceptLanguage: "fr-FR, de;q=0.8", want: Default},
+		{name: "First supported", acceptLanguage: "fr, ro;q=0.9, en;q=0.8", want: "ro"},
+		{name: "Highest quality", acceptLanguage: "en;q=0.5, ro;q=0.7", want: "ro"},
+		{name: "Equal quality keeps the order", acceptLanguage: "en-GB, ro", want: "en"},
+		{name: "Default quality is 1", acceptLanguage: "en;q=0.9, ro", want: "ro"},
+		{name: "Refused language", acceptLanguage: "ro;q=0, en;q=0.1", want: "en"},
+		{name: "Only refused languages", acceptLanguage: "ro;q=0", want: Default},
+		{name: "Invalid quality", acceptLanguage: "ro;q=high, en;q=0.1", want: "en"},
+		{name: "Spaces", acceptLanguage: " ro-RO ; q=0.8 ,en;q=0.7", want: "ro"},
+		{name: "Wildcard", acceptLanguage: "*", want: Default},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.acceptLanguage); got != tt.want {
+				t.Errorf("got %q for %q; want %q", got, tt.acceptLanguage, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{locale: "en", n: 0, want: "other"},
+		{locale: "en", n: 1, want: "one"},
+		{locale: "en", n: 2, want: "other"},
+		{locale: "en", n: 101, want: "other"},
+
+		// Romanian uses "few" for 0 and the numbers ending in 01 to 19,
+		// except 1 itself.
+		{locale: "ro", n: 0, want: "few"},
+		{locale: "ro", n: 1, want: "one"},
+		{locale: "ro", n: 2, want: "few"},
+		{locale: "ro", n: 19, want: "few"},
+		{locale: "ro", n: 20, want: "other"},
+		{locale: "ro", n: 21, want: "other"},
+		{locale: "ro", n: 100, want: "other"},
+		{locale: "ro", n: 101, want: "few"},
+		{locale: "ro", n: 119, want: "few"},
+		{locale: "ro", n: 120, want: "other"},
+		{locale: "ro", n: 1001, want: "few"},
+	}
+
+	for _, tt := range tests {
+		if got := pluralCategory(tt.locale, tt.n); got != tt.want {
+			t.Errorf("%s: got category %q for %d; want %q", tt.locale, got, tt.n, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{locale: "en", n: 1, want: "1 day"},
+		{locale: "en", n: 3, want: "3 days"},
+		{locale: "ro", n: 1, want: "1 zi"},
+		{locale: "ro", n: 3, want: "3 zile"},
+		{locale: "ro", n: 20, want: "20 de zile"},
+		{locale: "ro", n: 102, want: "102 zile"},
+	}
+
+	for _, tt := range tests {
+		if got := NewPrinter(tt.locale).Plural(tt.n, "%d day", "%d days"); got != tt.want {
+			t.Errorf("%s: got %q for %d; want %q", tt.locale, got, tt.n, tt.want)
+		}
+	}
+}
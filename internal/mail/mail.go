@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Message is an email sent to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer is a Mailer that writes messages to a logger. It is useful
+// during development and as a fallback when no real delivery is configured.
+type LogMailer struct {
+	Logger *slog.Logger
+}
+
+// Send logs the message and never fails.
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.Logger.Info("mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
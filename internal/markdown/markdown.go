@@ -0,0 +1,68 @@
+package markdown
+
+import (
+	"bytes"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"html/template"
+	"regexp"
+)
+
+// converter turns GitHub flavoured Markdown into HTML. Raw HTML embedded in
+// the source is dropped by goldmark unless the unsafe option is set, and the
+// output is sanitized regardless.
+var converter = goldmark.New(
+	goldmark.WithExtensions(
+		extension.Linkify,
+		extension.Strikethrough,
+		extension.TaskList,
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+	),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// policy is the allowlist of elements and attributes kept in the rendered
+// HTML. It excludes images, scripts, styles and event handler attributes, so
+// descriptions cannot load content from other origins nor run code.
+var policy = newPolicy()
+
+// newPolicy builds the sanitizer policy applied to the rendered HTML.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements(
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"p", "br", "hr", "blockquote", "pre", "code",
+		"em", "strong", "del", "ul", "ol", "li",
+		"table", "thead", "tbody", "tr", "th", "td",
+	)
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+
+	// Task list items are rendered as disabled checkboxes.
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnFullyQualifiedLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+// Render converts the Markdown source to sanitized HTML, safe to be
+// included in a template as is.
+func Render(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	err := converter.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(policy.SanitizeBytes(buf.Bytes())), nil
+}
@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "Emphasis and lists",
+			source: "**bold** and *italic*\n\n- one\n- two",
+			want:   []string{"<strong>bold</strong>", "<em>italic</em>", "<li>one</li>"},
+		},
+		{
+			name:   "HTTPS link",
+			source: "[site](https://example.com)",
+			want:   []string{`href="https://example.com"`, `rel="nofollow noreferrer noopener"`, `target="_blank"`},
+		},
+		{
+			name:    "JavaScript link",
+			source:  "[click](javascript:alert(1))",
+			want:    []string{"click"},
+			notWant: []string{"javascript:", "href"},
+		},
+		{
+			name:    "JavaScript link with mixed case",
+			source:  "[click](JaVaScRiPt:alert(1))",
+			notWant: []string{"javascript:", "JaVaScRiPt:", "href"},
+		},
+		{
+			name:    "Data URL link",
+			source:  "[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+			notWant: []string{"data:", "href"},
+		},
+		{
+			name:    "Raw script",
+			source:  "<script>alert(1)</script>",
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "Raw inline HTML",
+			source:  `Hello <span onclick="alert(1)">world</span>`,
+			want:    []string{"Hello"},
+			notWant: []string{"<span", "onclick"},
+		},
+		{
+			name:    "Image with onerror",
+			source:  `<img src="x" onerror="alert(1)">`,
+			notWant: []string{"<img", "onerror"},
+		},
+		{
+			name:    "Markdown image",
+			source:  "![tracker](https://example.com/pixel.png)",
+			notWant: []string{"<img", "pixel.png"},
+		},
+		{
+			name:    "Raw iframe",
+			source:  `<iframe src="https://example.com"></iframe>`,
+			notWant: []string{"<iframe"},
+		},
+		{
+			name:    "Raw style",
+			source:  "<style>body { display: none }</style>",
+			notWant: []string{"<style", "display"},
+		},
+		{
+			name:   "Task list",
+			source: "- [x] done",
+			want:   []string{`type="checkbox"`, "checked", "disabled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(html), want) {
+					t.Errorf("got %q; want it to contain %q", html, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(html), notWant) {
+					t.Errorf("got %q; want it not to contain %q", html, notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets for durations
+// in seconds, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ContentType is the media type of the Prometheus text format written by
+// Registry.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds metrics and writes them in the Prometheus text format, in
+// the order they were registered.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// metric is a metric family of a registry.
+type metric interface {
+	write(ctx context.Context, w io.Writer) error
+}
+
+// register adds a metric to the registry and returns it.
+func register[M metric](r *Registry, m M) M {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// Counter is a counter with the value of each combination of its labels.
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter partitioned by the labels.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return register(r, &Counter{name: name, help: help, labels: labels, values: make(map[string]float64)})
+}
+
+// Inc adds one to the counter of the label values, given in the order of the
+// labels.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v to the counter of the label values.
+func (c *Counter) Add(v float64, values ...string) {
+	key := seriesKey(c.labels, values)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += v
+}
+
+func (c *Counter) write(_ context.Context, w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		writeSample(w, c.name, key, c.values[key])
+	}
+	return nil
+}
+
+// Histogram counts observations in buckets for each combination of its
+// labels.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// histogramSeries is the state of a histogram for one combination of labels.
+// The counts are per bucket and not cumulative.
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram partitioned by the labels, with the
+// upper bounds of the buckets in increasing order.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return register(r, &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)})
+}
+
+// Observe records v in the histogram of the label values, given in the order
+// of the labels.
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := seriesKey(h.labels, values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	i, _ := slices.BinarySearch(h.buckets, v)
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(_ context.Context, w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, h.name+"_bucket", withLabel(key, "le", formatValue(bound)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", withLabel(key, "le", "+Inf"), float64(s.count))
+		writeSample(w, h.name+"_sum", key, s.sum)
+		writeSample(w, h.name+"_count", key, float64(s.count))
+	}
+	return nil
+}
+
+// funcMetric is a metric without labels whose value is read when the
+// registry is written.
+type funcMetric struct {
+	name, help, kind string
+	value            func(ctx context.Context) (float64, error)
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by the function
+// every time the registry is written.
+func (r *Registry) NewGaugeFunc(name, help string, value func(ctx context.Context) (float64, error)) {
+	register(r, &funcMetric{name: name, help: help, kind: "gauge", value: value})
+}
+
+// NewCounterFunc registers a counter whose value is returned by the function
+// every time the registry is written.
+func (r *Registry) NewCounterFunc(name, help string, value func(ctx context.Context) (float64, error)) {
+	register(r, &funcMetric{name: name, help: help, kind: "counter", value: value})
+}
+
+func (m *funcMetric) write(ctx context.Context, w io.Writer) error {
+	v, err := m.value(ctx)
+	if err != nil {
+		return fmt.Errorf("metric %s: %w", m.name, err)
+	}
+
+	writeHeader(w, m.name, m.help, m.kind)
+	writeSample(w, m.name, "", v)
+	return nil
+}
+
+// WriteTo writes every metric to w. Metrics whose value cannot be read are
+// left out, and their errors returned together.
+func (r *Registry) WriteTo(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, m := range metrics {
+		// Each metric is buffered, so that a failing one writes nothing.
+		var buf bytes.Buffer
+		err := m.write(ctx, &buf)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		_, err = buf.WriteTo(w)
+		if err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handler returns a handler serving the metrics. The errors of the metrics
+// which cannot be read are logged, and the other metrics still served.
+func (r *Registry) Handler(logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		err := r.WriteTo(req.Context(), &buf)
+		if err != nil {
+			logger.Error(err.Error(), "url", req.URL.RequestURI())
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = buf.WriteTo(w)
+	})
+}
+
+// seriesKey returns the labels of a series in the text format, such as
+// `method="GET",status="200"`. Missing values are empty.
+func seriesKey(labels, values []string) string {
+	var b strings.Builder
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		b.WriteString(label)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(value))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// withLabel adds a label to the labels of a series.
+func withLabel(key, label, value string) string {
+	if key != "" {
+		key += ","
+	}
+	return key + label + `="` + escapeLabel(value) + `"`
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, kind)
+}
+
+func writeSample(w io.Writer, name, key string, v float64) {
+	if key != "" {
+		name += "{" + key + "}"
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatValue(v))
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// sortedKeys returns the keys of the series in order, so that the output is
+// stable between scrapes.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
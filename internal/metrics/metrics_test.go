@@ -0,0 +1,148 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"github.com/madalinpopa/go-event-planner/internal/metrics"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the output of the tests")
+
+// checkGolden compares got with the golden file of the name in testdata.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		err := os.WriteFile(path, got, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *metrics.Registry)
+	}{
+		{
+			name: "counter",
+			register: func(r *metrics.Registry) {
+				c := r.NewCounter("http_requests_total", "HTTP requests handled.", "method", "status")
+				c.Inc("GET", "200")
+				c.Inc("GET", "200")
+				c.Add(2.5, "POST", "303")
+				c.Inc("GET", "404")
+			},
+		},
+		{
+			name: "escaping",
+			register: func(r *metrics.Registry) {
+				c := r.NewCounter("escaped_total", "Help with a \\ backslash,\na new line and \"quotes\".", "value")
+				c.Inc(`C:\path`)
+				c.Inc(`say "hi"`)
+				c.Inc("two\nlines")
+			},
+		},
+		{
+			name: "missing labels",
+			register: func(r *metrics.Registry) {
+				c := r.NewCounter("partial_total", "Counter given fewer values than labels.", "method", "route")
+				c.Inc("GET")
+			},
+		},
+		{
+			name: "histogram",
+			register: func(r *metrics.Registry) {
+				h := r.NewHistogram("request_duration_seconds", "Time to handle requests.", []float64{.1, .5, 1}, "route")
+				// The bounds are inclusive, and observations above the
+				// last one are only counted by +Inf.
+				h.Observe(.05, "/")
+				h.Observe(.1, "/")
+				h.Observe(.3, "/")
+				h.Observe(1, "/")
+				h.Observe(4, "/")
+				h.Observe(.2, `/events/{id}`)
+			},
+		},
+		{
+			name: "histogram without labels",
+			register: func(r *metrics.Registry) {
+				h := r.NewHistogram("job_duration_seconds", "Time to run jobs.", metrics.DefaultBuckets)
+				h.Observe(.007)
+				h.Observe(12)
+			},
+		},
+		{
+			name: "functions",
+			register: func(r *metrics.Registry) {
+				r.NewGaugeFunc("sessions_active", "Sessions which have not expired.", func(context.Context) (float64, error) {
+					return 3, nil
+				})
+				r.NewCounterFunc("wait_seconds_total", "Time spent waiting.", func(context.Context) (float64, error) {
+					return 0.125, nil
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &metrics.Registry{}
+			tt.register(r)
+
+			var buf bytes.Buffer
+			err := r.WriteTo(context.Background(), &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Base(t.Name()), buf.Bytes())
+		})
+	}
+}
+
+func TestWriteToFailingMetric(t *testing.T) {
+	errRead := errors.New("database is closed")
+
+	r := &metrics.Registry{}
+	r.NewGaugeFunc("before", "Gauge registered before.", func(context.Context) (float64, error) { return 1, nil })
+	r.NewGaugeFunc("failing", "Gauge which cannot be read.", func(context.Context) (float64, error) { return 0, errRead })
+	r.NewGaugeFunc("after", "Gauge registered after.", func(context.Context) (float64, error) { return 2, nil })
+
+	var buf bytes.Buffer
+	err := r.WriteTo(context.Background(), &buf)
+	if !errors.Is(err, errRead) {
+		t.Errorf("got error %v; want %v", err, errRead)
+	}
+	checkGolden(t, "failing", buf.Bytes())
+}
+
+func TestHandler(t *testing.T) {
+	r := &metrics.Registry{}
+	r.NewCounter("http_requests_total", "HTTP requests handled.", "method").Inc("GET")
+
+	rec := httptest.NewRecorder()
+	r.Handler(slog.New(slog.NewTextHandler(io.Discard, nil))).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != metrics.ContentType {
+		t.Errorf("got content type %q; want %q", got, metrics.ContentType)
+	}
+	checkGolden(t, "handler", rec.Body.Bytes())
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"log"
+	"time"
+)
+
+// Update saves the name and email of the user. It returns ErrDuplicateEmail
+// if another account already uses the email.
+func (m *UserModel) Update(ctx context.Context, u User) error {
+	stmt := "UPDATE users SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, u.Name, u.Email, u.ID)
+	if err != nil {
+		var sqliteError sqlite3.Error
+		if errors.As(err, &sqliteError) && errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// UpdateLocale saves the language chosen by the user, empty to let the
+// browser choose.
+func (m *UserModel) UpdateLocale(ctx context.Context, id int, locale string) error {
+	stmt := "UPDATE users SET locale = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, locale, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// RequestEmailChange records that the user wants to use a new email and
+// returns the token confirming it, to be sent to the new address. It replaces
+// any pending change of the user. It returns ErrDuplicateEmail if another
+// account already uses the email.
+func (m *UserModel) RequestEmailChange(ctx context.Context, userID int, email string, expiresAt time.Time) (string, error) {
+	var taken bool
+	err := m.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT true FROM users WHERE email = ?)", email).Scan(&taken)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrDuplicateEmail
+	}
+
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `INSERT INTO email_changes (user_id, email, token_hash, expires_at) VALUES (?, ?, ?, ?)
+	ON CONFLICT (user_id) DO UPDATE SET email = excluded.email, token_hash = excluded.token_hash,
+	expires_at = excluded.expires_at, created_at = CURRENT_TIMESTAMP`
+
+	_, err = m.DB.ExecContext(ctx, stmt, userID, email, hashToken(token), expiresAt.UTC().Format(sqliteDateTime))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConfirmEmailChange applies the pending email change matching the token and
+// returns the ID of the user with their previous and new emails. It returns
+// ErrNoRecord if the token is unknown or expired, and ErrDuplicateEmail if
+// the email was taken by another account in the meantime.
+func (m *UserModel) ConfirmEmailChange(ctx context.Context, token string) (int, string, string, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userID int
+	var oldEmail, newEmail string
+
+	stmt := `DELETE FROM email_changes WHERE token_hash = ? AND expires_at > ?
+	RETURNING user_id, email, (SELECT email FROM users WHERE id = email_changes.user_id)`
+
+	err = tx.QueryRowContext(ctx, stmt, hashToken(token), time.Now().UTC().Format(sqliteDateTime)).Scan(&userID, &newEmail, &oldEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", "", ErrNoRecord
+		}
+		return 0, "", "", err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", newEmail, userID)
+	if err != nil {
+		var sqliteError sqlite3.Error
+		if errors.As(err, &sqliteError) && errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return 0, "", "", ErrDuplicateEmail
+		}
+		return 0, "", "", err
+	}
+
+	return userID, oldEmail, newEmail, tx.Commit()
+}
+
+// Delete removes the account of the user in a single transaction, following
+// this policy for the data it leaves behind:
+//
+//   - the RSVPs of the user are deleted;
+//   - the events owned by the user that nobody else attends are deleted,
+//     together with their attachments and reminders;
+//   - the events owned by the user that others attend are kept for them,
+//     without an owner;
+//   - sessions, API tokens, identities and other credentials are deleted,
+//     and audit events are kept without a user.
+//
+// Webhooks are queued for every deleted RSVP and event. Delete returns the
+// attachments of the deleted events, whose stored content the caller has to
+// remove.
+func (m *UserModel) Delete(ctx context.Context, id int) ([]Attachment, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	eventIDs, err := queryIDs(ctx, tx, "DELETE FROM rsvps WHERE user_id = ? RETURNING event_id", id)
+	if err != nil {
+		return nil, err
+	}
+	for _, eventID := range eventIDs {
+		err = enqueueWebhook(ctx, tx, WebhookRSVPDeleted, webhookRSVP{EventID: eventID, UserID: id})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stmt := `SELECT a.id, a.event_id, a.kind, a.filename, a.content_type, a.size, a.storage_key, a.thumbnail_key, a.created_at
+	FROM attachments a
+	JOIN events e ON e.id = a.event_id
+	WHERE e.user_id = ? AND NOT EXISTS (SELECT 1 FROM rsvps r WHERE r.event_id = e.id)`
+
+	attachments, err := queryAttachments(ctx, tx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt = `DELETE FROM events
+	WHERE user_id = ? AND NOT EXISTS (SELECT 1 FROM rsvps r WHERE r.event_id = events.id)
+	RETURNING id`
+
+	eventIDs, err = queryIDs(ctx, tx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, eventID := range eventIDs {
+		err = enqueueWebhook(ctx, tx, WebhookEventDeleted, webhookEventRef{ID: eventID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return attachments, tx.Commit()
+}
+
+// queryIDs runs a statement returning a single integer column inside the
+// transaction and returns its values.
+func queryIDs(ctx context.Context, tx *sql.Tx, stmt string, args ...any) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// queryAttachments runs a statement selecting the attachment columns inside
+// the transaction and returns the attachments.
+func queryAttachments(ctx context.Context, tx *sql.Tx, stmt string, args ...any) ([]Attachment, error) {
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var attachments []Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
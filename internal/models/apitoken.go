@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes granted to API tokens. A token can only be used on the endpoints
+// requiring one of its scopes.
+const (
+	ScopeEventsRead  = "events:read"
+	ScopeEventsWrite = "events:write"
+)
+
+// APITokenScopes lists every scope a token can be granted.
+var APITokenScopes = []string{
+	ScopeEventsRead,
+	ScopeEventsWrite,
+}
+
+// apiTokenPrefix starts every token, so that leaked tokens are easy to
+// recognize by secret scanners.
+const apiTokenPrefix = "ept_"
+
+// APIToken represents a personal API token of a user. ExpiresAt and
+// LastUsedAt are zero when the token never expires or was never used.
+type APIToken struct {
+	ID         int
+	UserID     int
+	Name       string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+// HasScope reports whether the token was granted the scope.
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// APITokenModel provides methods to manage API tokens in the database.
+type APITokenModel struct {
+	DB *sql.DB
+}
+
+// hashToken returns the hash under which a random secret token, such as an
+// API or session token, is stored. Tokens are random, so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create adds a token for the user and returns it. The token cannot be
+// retrieved later, as only its hash is stored. A zero expiresAt creates a
+// token which never expires.
+func (m *APITokenModel) Create(ctx context.Context, userID int, name string, scopes []string, expiresAt time.Time) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	var expires sql.NullString
+	if !expiresAt.IsZero() {
+		expires = sql.NullString{String: expiresAt.UTC().Format(sqliteDateTime), Valid: true}
+	}
+
+	stmt := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at)
+	VALUES (?, ?, ?, ?, ?)`
+
+	_, err = m.DB.ExecContext(ctx, stmt, userID, name, hashToken(token), strings.Join(scopes, ","), expires)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// List returns the tokens of the user, most recent first.
+func (m *APITokenModel) List(ctx context.Context, userID int) ([]APIToken, error) {
+	stmt := `SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+	FROM api_tokens WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var tokens []APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Revoke deletes the token of the user. It returns ErrNoRecord if the user
+// has no such token.
+func (m *APITokenModel) Revoke(ctx context.Context, userID, id int) error {
+	stmt := "DELETE FROM api_tokens WHERE id = ? AND user_id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Authenticate returns the token matching the one presented by a client and
+// records its use. It returns ErrInvalidCredentials if the token is unknown
+// or expired, or its user disabled.
+func (m *APITokenModel) Authenticate(ctx context.Context, token string) (APIToken, error) {
+	stmt := `UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP
+	WHERE token_hash = ? AND (expires_at IS NULL OR expires_at > datetime('now'))
+	AND user_id IN (SELECT id FROM users WHERE disabled_at IS NULL)
+	RETURNING id, user_id, name, scopes, expires_at, last_used_at, created_at`
+
+	t, err := scanAPIToken(m.DB.QueryRowContext(ctx, stmt, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIToken{}, ErrInvalidCredentials
+		}
+		return APIToken{}, err
+	}
+
+	return t, nil
+}
+
+// scanAPIToken scans a row of the api_tokens columns selected by the
+// queries above into an APIToken.
+func scanAPIToken(row interface{ Scan(...any) error }) (APIToken, error) {
+	var t APIToken
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &expiresAt, &lastUsedAt, &t.CreatedAt)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	t.ExpiresAt = expiresAt.Time
+	t.LastUsedAt = lastUsedAt.Time
+
+	return t, nil
+}
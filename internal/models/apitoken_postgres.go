@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// PostgresAPITokenModel is the PostgreSQL version of APITokenModel.
+type PostgresAPITokenModel struct {
+	DB *sql.DB
+}
+
+// Create adds a token for the user and returns it. The token cannot be
+// retrieved later, as only its hash is stored. A zero expiresAt creates a
+// token which never expires.
+func (m *PostgresAPITokenModel) Create(ctx context.Context, userID int, name string, scopes []string, expiresAt time.Time) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5)`
+
+	_, err = m.DB.ExecContext(ctx, stmt, userID, name, hashToken(token), strings.Join(scopes, ","), nullTime(expiresAt))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// List returns the tokens of the user, most recent first.
+func (m *PostgresAPITokenModel) List(ctx context.Context, userID int) ([]APIToken, error) {
+	stmt := `SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+	FROM api_tokens WHERE user_id = $1 ORDER BY id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var tokens []APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Revoke deletes the token of the user. It returns ErrNoRecord if the user
+// has no such token.
+func (m *PostgresAPITokenModel) Revoke(ctx context.Context, userID, id int) error {
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Authenticate returns the token matching the one presented by a client and
+// records its use. It returns ErrInvalidCredentials if the token is unknown
+// or expired, or its user disabled.
+func (m *PostgresAPITokenModel) Authenticate(ctx context.Context, token string) (APIToken, error) {
+	stmt := `UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP
+	WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	AND user_id IN (SELECT id FROM users WHERE disabled_at IS NULL)
+	RETURNING id, user_id, name, scopes, expires_at, last_used_at, created_at`
+
+	t, err := scanAPIToken(m.DB.QueryRowContext(ctx, stmt, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIToken{}, ErrInvalidCredentials
+		}
+		return APIToken{}, err
+	}
+
+	return t, nil
+}
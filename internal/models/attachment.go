@@ -0,0 +1,129 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Attachment kinds. An event has at most one cover image, displayed on its
+// page, and any number of files such as agendas.
+const (
+	AttachmentCover = "cover"
+	AttachmentFile  = "file"
+)
+
+// Attachment represents a file uploaded for an event. The content itself is
+// kept in a storage under StorageKey; ThumbnailKey is empty for attachments
+// without a thumbnail.
+type Attachment struct {
+	ID           int
+	EventID      int
+	Kind         string
+	Filename     string
+	ContentType  string
+	Size         int64
+	StorageKey   string
+	ThumbnailKey string
+	CreatedAt    time.Time
+}
+
+// AttachmentModel provides methods to manage the attachments of events in the database.
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+// attachmentColumns lists the columns scanned by scanAttachment, in order.
+const attachmentColumns = "id, event_id, kind, filename, content_type, size, storage_key, thumbnail_key, created_at"
+
+// scanAttachment scans a row selected with attachmentColumns into an Attachment.
+func scanAttachment(row interface{ Scan(...any) error }) (Attachment, error) {
+	var a Attachment
+	err := row.Scan(&a.ID, &a.EventID, &a.Kind, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.ThumbnailKey, &a.CreatedAt)
+	return a, err
+}
+
+// Create records a new attachment and returns its ID.
+func (m *AttachmentModel) Create(ctx context.Context, a Attachment) (int, error) {
+	stmt := `INSERT INTO attachments (event_id, kind, filename, content_type, size, storage_key, thumbnail_key)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := m.DB.ExecContext(ctx, stmt, a.EventID, a.Kind, a.Filename, a.ContentType, a.Size, a.StorageKey, a.ThumbnailKey)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Retrieve returns the attachment with the given ID.
+func (m *AttachmentModel) Retrieve(ctx context.Context, id int) (Attachment, error) {
+	stmt := "SELECT " + attachmentColumns + " FROM attachments WHERE id = ?"
+
+	a, err := scanAttachment(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Attachment{}, ErrNoRecord
+		}
+		return Attachment{}, err
+	}
+
+	return a, nil
+}
+
+// List returns the attachments of an event, oldest first.
+func (m *AttachmentModel) List(ctx context.Context, eventID int) ([]Attachment, error) {
+	stmt := "SELECT " + attachmentColumns + " FROM attachments WHERE event_id = ? ORDER BY id"
+
+	rows, err := m.DB.QueryContext(ctx, stmt, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var attachments []Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes the attachment record. The stored content is left to the caller.
+func (m *AttachmentModel) Delete(ctx context.Context, id int) error {
+	stmt := "DELETE FROM attachments WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// PostgresAttachmentModel is the PostgreSQL version of AttachmentModel.
+type PostgresAttachmentModel struct {
+	DB *sql.DB
+}
+
+// Create records a new attachment and returns its ID.
+func (m *PostgresAttachmentModel) Create(ctx context.Context, a Attachment) (int, error) {
+	stmt := `INSERT INTO attachments (event_id, kind, filename, content_type, size, storage_key, thumbnail_key)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRowContext(ctx, stmt, a.EventID, a.Kind, a.Filename, a.ContentType, a.Size, a.StorageKey, a.ThumbnailKey).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Retrieve returns the attachment with the given ID.
+func (m *PostgresAttachmentModel) Retrieve(ctx context.Context, id int) (Attachment, error) {
+	stmt := "SELECT " + attachmentColumns + " FROM attachments WHERE id = $1"
+
+	a, err := scanAttachment(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Attachment{}, ErrNoRecord
+		}
+		return Attachment{}, err
+	}
+
+	return a, nil
+}
+
+// List returns the attachments of an event, oldest first.
+func (m *PostgresAttachmentModel) List(ctx context.Context, eventID int) ([]Attachment, error) {
+	stmt := "SELECT " + attachmentColumns + " FROM attachments WHERE event_id = $1 ORDER BY id"
+
+	rows, err := m.DB.QueryContext(ctx, stmt, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var attachments []Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes the attachment record. The stored content is left to the caller.
+func (m *PostgresAttachmentModel) Delete(ctx context.Context, id int) error {
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM attachments WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
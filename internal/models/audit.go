@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Audit actions recorded for administrators.
+const (
+	AuditAccountLocked   = "account.locked"
+	AuditPasswordChanged = "password.changed"
+	AuditEmailChanged    = "email.changed"
+	AuditAccountDeleted  = "account.deleted"
+	AuditExportRequested = "export.requested"
+	AuditRoleChanged     = "role.changed"
+	AuditAccountDisabled = "account.disabled"
+	AuditAccountEnabled  = "account.enabled"
+	AuditPasswordReset   = "password.reset"
+)
+
+// AuditEvent is a security relevant event, such as an account lockout.
+// UserID is 0 for events which do not concern a known user.
+type AuditEvent struct {
+	ID        int
+	UserID    int
+	UserEmail string
+	Action    string
+	IP        string
+	Detail    string
+	CreatedAt time.Time
+}
+
+// AuditModel provides methods to record and list audit events.
+type AuditModel struct {
+	DB *sql.DB
+}
+
+// Record adds an audit event.
+func (m *AuditModel) Record(ctx context.Context, userID int, action, ip, detail string) error {
+	stmt := "INSERT INTO audit_events (user_id, action, ip, detail) VALUES (?, ?, ?, ?)"
+
+	_, err := m.DB.ExecContext(ctx, stmt, nullID(userID), action, ip, detail)
+	return err
+}
+
+// List returns the latest audit events, most recent first.
+func (m *AuditModel) List(ctx context.Context, limit int) ([]AuditEvent, error) {
+	stmt := `SELECT a.id, a.user_id, COALESCE(u.email, ''), a.action, a.ip, a.detail, a.created_at
+	FROM audit_events a
+	LEFT JOIN users u ON u.id = a.user_id
+	ORDER BY a.id DESC
+	LIMIT ?`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var events []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		var userID sql.NullInt64
+
+		err := rows.Scan(&e.ID, &userID, &e.UserEmail, &e.Action, &e.IP, &e.Detail, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.UserID = int(userID.Int64)
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// PostgresAuditModel is the PostgreSQL version of AuditModel.
+type PostgresAuditModel struct {
+	DB *sql.DB
+}
+
+// Record adds an audit event.
+func (m *PostgresAuditModel) Record(ctx context.Context, userID int, action, ip, detail string) error {
+	stmt := "INSERT INTO audit_events (user_id, action, ip, detail) VALUES ($1, $2, $3, $4)"
+
+	_, err := m.DB.ExecContext(ctx, stmt, nullID(userID), action, ip, detail)
+	return err
+}
+
+// List returns the latest audit events, most recent first.
+func (m *PostgresAuditModel) List(ctx context.Context, limit int) ([]AuditEvent, error) {
+	stmt := `SELECT a.id, a.user_id, COALESCE(u.email, ''), a.action, a.ip, a.detail, a.created_at
+	FROM audit_events a
+	LEFT JOIN users u ON u.id = a.user_id
+	ORDER BY a.id DESC
+	LIMIT $1`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var events []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		var userID sql.NullInt64
+
+		err := rows.Scan(&e.ID, &userID, &e.UserEmail, &e.Action, &e.IP, &e.Detail, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.UserID = int(userID.Int64)
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -14,4 +14,15 @@ var (
 	// ErrDuplicateEmail indicates that the provided email already exists
 	// in the system and cannot be used again.
 	ErrDuplicateEmail = errors.New("models: duplicate email")
+
+	// ErrDuplicateVenue indicates that a venue with the same name already exists.
+	ErrDuplicateVenue = errors.New("models: duplicate venue")
+
+	// ErrVenueConflict indicates that the venue is already booked by another
+	// event which overlaps the requested dates.
+	ErrVenueConflict = errors.New("models: venue already booked")
+
+	// ErrExportInProgress indicates that an export of the user's data is
+	// already queued or being built.
+	ErrExportInProgress = errors.New("models: export already in progress")
 )
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -9,16 +10,75 @@ import (
 )
 
 // Event represents a scheduled occurrence with a title, description, date, and location.
+// EndDate is the zero time for events which do not span multiple days, and UserID and
+// VenueID are 0 for events without an owner or a venue.
 type Event struct {
 	Id          int
+	UserID      int
+	VenueID     int
 	Title       string
 	Description string
 	Location    string
 	EventDate   time.Time
+	EndDate     time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
+// LastDate returns the date on which the event ends, which is EventDate for
+// single day events.
+func (e Event) LastDate() time.Time {
+	if e.EndDate.IsZero() {
+		return e.EventDate
+	}
+	return e.EndDate
+}
+
+// End returns the end of the booking of the event, which runs from EventDate
+// up to, but excluding, End. An end without a time of day, as the event forms
+// submit dates, books the whole of its day; an event without an end books a
+// day from its start.
+func (e Event) End() time.Time {
+	end := e.LastDate().UTC()
+	if end.Hour() == 0 && end.Minute() == 0 && end.Second() == 0 && end.Nanosecond() == 0 {
+		return end.AddDate(0, 0, 1)
+	}
+	if !end.After(e.EventDate) {
+		return e.EventDate.UTC().Add(24 * time.Hour)
+	}
+	return end
+}
+
+// eventColumns lists the columns scanned by scanEvent, in order.
+const eventColumns = "id, user_id, venue_id, title, description, event_date, end_date, location, created_at, updated_at"
+
+// scanEvent scans a row selected with eventColumns into an Event.
+func scanEvent(row interface{ Scan(...any) error }) (Event, error) {
+	var e Event
+	var userID, venueID sql.NullInt64
+	var endDate sql.NullTime
+
+	err := row.Scan(&e.Id, &userID, &venueID, &e.Title, &e.Description, &e.EventDate, &endDate, &e.Location, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return Event{}, err
+	}
+	e.UserID = int(userID.Int64)
+	e.VenueID = int(venueID.Int64)
+	e.EndDate = endDate.Time
+
+	return e, nil
+}
+
+// nullID converts the zero ID to NULL so that optional references are stored as such.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// nullTime converts the zero time to NULL so that optional dates are stored as such.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 // EventModel provides methods for managing and interacting with events in the database.
 // It includes functionality to create, retrieve, and list event records.
 // The `DB` field holds the database connection used for queries and operations.
@@ -26,14 +86,20 @@ type EventModel struct {
 	DB *sql.DB
 }
 
-// Create adds a new event record to the database with the provided title, description, date, and location.
-// It returns the ID of the newly created event or an error if the operation fails.
-func (m *EventModel) Create(title, description string, eventDate time.Time, location string) (int, error) {
+// Create adds a new event owned by the user with the provided title, description, dates, location, and venue.
+// It returns the ID of the newly created event or an error if the operation fails, and ErrVenueConflict
+// if the venue is already booked during the event. The event.created webhook is queued in the same transaction.
+func (m *EventModel) Create(ctx context.Context, userID int, title, description string, eventDate, endDate time.Time, location string, venueID int) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	stmt := `INSERT INTO events (title, description, event_date, location) 
-	VALUES (?, ?, ?, ?)`
+	stmt := `INSERT INTO events (user_id, venue_id, title, description, event_date, end_date, location) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := m.DB.Exec(stmt, title, description, eventDate, location)
+	result, err := tx.ExecContext(ctx, stmt, userID, nullID(venueID), title, description, eventDate, nullTime(endDate), location)
 	if err != nil {
 		return 0, err
 	}
@@ -43,18 +109,105 @@ func (m *EventModel) Create(title, description string, eventDate time.Time, loca
 		return 0, err
 	}
 
+	// The check runs after the write, so that the transaction already holds
+	// the write lock and concurrent bookings cannot both pass it.
+	err = checkVenueConflict(ctx, tx, venueID, int(id), Event{EventDate: eventDate, EndDate: endDate})
+	if err != nil {
+		return 0, err
+	}
+
+	err = enqueueWebhook(ctx, tx, WebhookEventCreated, webhookEvent{
+		ID:          int(id),
+		Title:       title,
+		Description: description,
+		Location:    location,
+		EventDate:   eventDate,
+		EndDate:     optionalTime(endDate),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
 	return int(id), nil
 }
 
 // Update modifies an existing event in the database using the provided ID and updated event details.
-// Returns an error if the update operation fails or if no record is affected.
-func (m *EventModel) Update(id int, title, description string, eventDate time.Time, location string) error {
-	stmt := `UPDATE events SET title = ?, description = ?, event_date = ?, location = ? WHERE id = ?`
+// Returns an error if the update operation fails or if no record is affected, and ErrVenueConflict
+// if the venue is already booked during the event. The event.updated webhook is queued in the same transaction.
+func (m *EventModel) Update(ctx context.Context, id int, title, description string, eventDate, endDate time.Time, location string, venueID int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := `UPDATE events SET venue_id = ?, title = ?, description = ?, event_date = ?, end_date = ?, location = ? WHERE id = ?`
 
-	result, err := m.DB.Exec(stmt, title, description, eventDate, location, id)
+	result, err := tx.ExecContext(ctx, stmt, nullID(venueID), title, description, eventDate, nullTime(endDate), location, id)
 	if err != nil {
 		return err
 	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	err = checkVenueConflict(ctx, tx, venueID, id, Event{EventDate: eventDate, EndDate: endDate})
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhook(ctx, tx, WebhookEventUpdated, webhookEvent{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		Location:    location,
+		EventDate:   eventDate,
+		EndDate:     optionalTime(endDate),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Reassign transfers the events owned by the user fromUserID to toUserID and
+// returns how many were transferred. A fromUserID of 0 selects the events
+// without an owner, such as those left by deleted accounts.
+func (m *EventModel) Reassign(ctx context.Context, fromUserID, toUserID int) (int, error) {
+	stmt := "UPDATE events SET user_id = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id IS ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, toUserID, nullID(fromUserID))
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// SetOwner transfers the event to the user. It returns ErrNoRecord if there
+// is no such event.
+func (m *EventModel) SetOwner(ctx context.Context, id, userID int) error {
+	stmt := "UPDATE events SET user_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, userID, id)
+	if err != nil {
+		return err
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
@@ -67,15 +220,13 @@ func (m *EventModel) Update(id int, title, description string, eventDate time.Ti
 
 // Retrieve retrieves an event from the database by its unique ID.
 // It returns the matching Event object or an error if the query fails or no event is found.
-func (m *EventModel) Retrieve(id int) (Event, error) {
+func (m *EventModel) Retrieve(ctx context.Context, id int) (Event, error) {
 
-	stmt := "SELECT * FROM events WHERE id = ?"
+	stmt := "SELECT " + eventColumns + " FROM events WHERE id = ?"
 
-	row := m.DB.QueryRow(stmt, id)
+	row := m.DB.QueryRowContext(ctx, stmt, id)
 
-	var e Event
-
-	err := row.Scan(&e.Id, &e.Title, &e.Description, &e.EventDate, &e.Location, &e.CreatedAt, &e.UpdatedAt)
+	e, err := scanEvent(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Event{}, ErrNoRecord
@@ -86,11 +237,18 @@ func (m *EventModel) Retrieve(id int) (Event, error) {
 	return e, nil
 }
 
-// Delete removes an event record from the database by its unique ID and returns an error if the operation fails.
-func (m *EventModel) Delete(id int) error {
+// Delete removes an event record from the database by its unique ID and returns an error if the operation fails,
+// and ErrNoRecord if there is no such event. The event.deleted webhook is queued in the same transaction.
+func (m *EventModel) Delete(ctx context.Context, id int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	stmt := "DELETE FROM events WHERE id = ?"
 
-	result, err := m.DB.Exec(stmt, id)
+	result, err := tx.ExecContext(ctx, stmt, id)
 	if err != nil {
 		return fmt.Errorf("failed to execute delete query: %w", err)
 	}
@@ -101,17 +259,47 @@ func (m *EventModel) Delete(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no event found with id: %d", id)
+		return ErrNoRecord
 	}
 
-	return nil
+	err = enqueueWebhook(ctx, tx, WebhookEventDeleted, webhookEventRef{ID: id})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // List retrieves all event records from the database and returns them as a slice of Event pointers or an error if it fails.
-func (m *EventModel) List() ([]Event, error) {
-	stmt := "SELECT id, title, description, event_date, location, created_at, updated_at FROM events"
+func (m *EventModel) List(ctx context.Context) ([]Event, error) {
+	stmt := "SELECT " + eventColumns + " FROM events"
+
+	return m.query(ctx, stmt)
+}
+
+// Between retrieves the events taking place at least partly in the range from start (inclusive)
+// to end (exclusive), ordered by their date.
+func (m *EventModel) Between(ctx context.Context, start, end time.Time) ([]Event, error) {
+	stmt := "SELECT " + eventColumns + ` FROM events
+	WHERE datetime(event_date) < datetime(?)
+	  AND datetime(COALESCE(end_date, event_date)) >= datetime(?)
+	ORDER BY datetime(event_date), id`
+
+	return m.query(ctx, stmt, end.UTC().Format(sqliteDateTime), start.UTC().Format(sqliteDateTime))
+}
+
+// CountUpcoming returns the number of events starting at or after now.
+func (m *EventModel) CountUpcoming(ctx context.Context, now time.Time) (int, error) {
+	var n int
+	stmt := "SELECT COUNT(*) FROM events WHERE datetime(event_date) >= datetime(?)"
+
+	err := m.DB.QueryRowContext(ctx, stmt, now.UTC().Format(sqliteDateTime)).Scan(&n)
+	return n, err
+}
 
-	rows, err := m.DB.Query(stmt)
+// query runs a statement selecting eventColumns and returns the resulting events.
+func (m *EventModel) query(ctx context.Context, stmt string, args ...any) ([]Event, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -125,8 +313,7 @@ func (m *EventModel) List() ([]Event, error) {
 	var events []Event
 	for rows.Next() {
 
-		var e Event
-		err := rows.Scan(&e.Id, &e.Title, &e.Description, &e.EventDate, &e.Location, &e.CreatedAt, &e.UpdatedAt)
+		e, err := scanEvent(rows)
 		if err != nil {
 			return nil, err
 		}
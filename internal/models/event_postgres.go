@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// PostgresEventModel is the PostgreSQL version of EventModel.
+type PostgresEventModel struct {
+	DB *sql.DB
+}
+
+// Create adds a new event owned by the user and returns its ID, or
+// ErrVenueConflict if the venue is already booked during the event. The
+// event.created webhook is queued in the same transaction.
+func (m *PostgresEventModel) Create(ctx context.Context, userID int, title, description string, eventDate, endDate time.Time, location string, venueID int) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := `INSERT INTO events (user_id, venue_id, title, description, event_date, end_date, location)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, stmt, userID, nullID(venueID), title, description, eventDate, nullTime(endDate), location).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkVenueConflictPostgres(ctx, tx, venueID, id, Event{EventDate: eventDate, EndDate: endDate})
+	if err != nil {
+		return 0, err
+	}
+
+	err = enqueueWebhookPostgres(ctx, tx, WebhookEventCreated, webhookEvent{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		Location:    location,
+		EventDate:   eventDate,
+		EndDate:     optionalTime(endDate),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Update modifies the event. It returns ErrNoRecord if there is no such event
+// and ErrVenueConflict if the venue is already booked during the event.
+// The event.updated webhook is queued in the same transaction.
+func (m *PostgresEventModel) Update(ctx context.Context, id int, title, description string, eventDate, endDate time.Time, location string, venueID int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := `UPDATE events SET venue_id = $1, title = $2, description = $3, event_date = $4, end_date = $5, location = $6,
+	updated_at = CURRENT_TIMESTAMP WHERE id = $7`
+
+	result, err := tx.ExecContext(ctx, stmt, nullID(venueID), title, description, eventDate, nullTime(endDate), location, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	err = checkVenueConflictPostgres(ctx, tx, venueID, id, Event{EventDate: eventDate, EndDate: endDate})
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhookPostgres(ctx, tx, WebhookEventUpdated, webhookEvent{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		Location:    location,
+		EventDate:   eventDate,
+		EndDate:     optionalTime(endDate),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Retrieve returns the event with the ID, or ErrNoRecord if there is none.
+func (m *PostgresEventModel) Retrieve(ctx context.Context, id int) (Event, error) {
+	stmt := "SELECT " + eventColumns + " FROM events WHERE id = $1"
+
+	e, err := scanEvent(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Event{}, ErrNoRecord
+		}
+		return Event{}, err
+	}
+	return e, nil
+}
+
+// Delete removes the event, and returns ErrNoRecord if there is no such
+// event. The event.deleted webhook is queued in the same transaction.
+func (m *PostgresEventModel) Delete(ctx context.Context, id int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM events WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	err = enqueueWebhookPostgres(ctx, tx, WebhookEventDeleted, webhookEventRef{ID: id})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// List returns every event, ordered by ID.
+func (m *PostgresEventModel) List(ctx context.Context) ([]Event, error) {
+	return m.query(ctx, "SELECT "+eventColumns+" FROM events ORDER BY id")
+}
+
+// Between returns the events taking place at least partly in the range from
+// start (inclusive) to end (exclusive), ordered by their date.
+func (m *PostgresEventModel) Between(ctx context.Context, start, end time.Time) ([]Event, error) {
+	stmt := "SELECT " + eventColumns + ` FROM events
+	WHERE event_date < $1 AND COALESCE(end_date, event_date) >= $2
+	ORDER BY event_date, id`
+
+	return m.query(ctx, stmt, end, start)
+}
+
+// CountUpcoming returns the number of events starting at or after now.
+func (m *PostgresEventModel) CountUpcoming(ctx context.Context, now time.Time) (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM events WHERE event_date >= $1", now).Scan(&n)
+	return n, err
+}
+
+// query runs a statement selecting eventColumns and returns the resulting events.
+func (m *PostgresEventModel) query(ctx context.Context, stmt string, args ...any) ([]Event, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
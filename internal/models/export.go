@@ -0,0 +1,356 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+)
+
+// Data export states. A pending export is claimed by a worker, which either
+// completes it, making it ready to download, or records why it failed.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// Export represents a request of a user for an archive of their personal
+// data. StorageKey is only set once the export is ready, and CompletedAt and
+// ExpiresAt are zero until then.
+type Export struct {
+	ID          int
+	UserID      int
+	Status      string
+	StorageKey  string
+	Size        int64
+	Error       string
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	ExpiresAt   time.Time
+}
+
+// Identity is an account at an OpenID Connect provider linked to a user.
+type Identity struct {
+	Issuer    string
+	Subject   string
+	CreatedAt time.Time
+}
+
+// Attendance is an RSVP of a user together with the event it is for.
+type Attendance struct {
+	RSVP
+	EventTitle string
+	EventDate  time.Time
+}
+
+// PersonalData holds everything stored about a user.
+type PersonalData struct {
+	User        User
+	Events      []Event
+	Attendances []Attendance
+	Sessions    []Session
+	AuditEvents []AuditEvent
+	Identities  []Identity
+	APITokens   []APIToken
+}
+
+// ExportModel provides methods to manage the exports of personal data and
+// to collect the data they contain.
+type ExportModel struct {
+	DB *sql.DB
+}
+
+// exportColumns lists the columns scanned by scanExport, in order.
+const exportColumns = "id, user_id, status, storage_key, size, error, created_at, completed_at, expires_at"
+
+// scanExport scans a row selected with exportColumns into an Export.
+func scanExport(row interface{ Scan(...any) error }) (Export, error) {
+	var e Export
+	var completedAt, expiresAt sql.NullTime
+
+	err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.StorageKey, &e.Size, &e.Error, &e.CreatedAt, &completedAt, &expiresAt)
+	if err != nil {
+		return Export{}, err
+	}
+	e.CompletedAt = completedAt.Time
+	e.ExpiresAt = expiresAt.Time
+
+	return e, nil
+}
+
+// Request queues an export of the data of the user and returns its ID. It
+// returns ErrExportInProgress if an export of the user is already queued.
+func (m *ExportModel) Request(ctx context.Context, userID int) (int, error) {
+	stmt := `INSERT INTO data_exports (user_id)
+	SELECT ? WHERE NOT EXISTS (SELECT 1 FROM data_exports WHERE user_id = ? AND status IN (?, ?))`
+
+	result, err := m.DB.ExecContext(ctx, stmt, userID, userID, ExportPending, ExportRunning)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, ErrExportInProgress
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Latest returns the most recent export of the user, or ErrNoRecord if they
+// never requested one.
+func (m *ExportModel) Latest(ctx context.Context, userID int) (Export, error) {
+	stmt := "SELECT " + exportColumns + " FROM data_exports WHERE user_id = ? ORDER BY id DESC LIMIT 1"
+
+	e, err := scanExport(m.DB.QueryRowContext(ctx, stmt, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Export{}, ErrNoRecord
+		}
+		return Export{}, err
+	}
+
+	return e, nil
+}
+
+// Claim marks the oldest pending export as running and returns it. Exports
+// left running for longer than staleAfter, by a worker which stopped, are
+// claimed again. It returns false if there is nothing to claim.
+func (m *ExportModel) Claim(ctx context.Context, now time.Time, staleAfter time.Duration) (Export, bool, error) {
+	stmt := `UPDATE data_exports SET status = ?, claimed_at = ?
+	WHERE id = (
+		SELECT id FROM data_exports
+		WHERE status = ? OR (status = ? AND claimed_at < ?)
+		ORDER BY id LIMIT 1
+	)
+	RETURNING ` + exportColumns
+
+	e, err := scanExport(m.DB.QueryRowContext(ctx, stmt,
+		ExportRunning, now.UTC().Format(sqliteDateTime),
+		ExportPending, ExportRunning, now.Add(-staleAfter).UTC().Format(sqliteDateTime),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Export{}, false, nil
+		}
+		return Export{}, false, err
+	}
+
+	return e, true, nil
+}
+
+// Complete records that the archive of the export is stored under the key
+// and returns the token of its download link, valid until expiresAt. Only the
+// hash of the token is stored.
+func (m *ExportModel) Complete(ctx context.Context, id int, storageKey string, size int64, expiresAt time.Time) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `UPDATE data_exports
+	SET status = ?, token_hash = ?, storage_key = ?, size = ?, completed_at = CURRENT_TIMESTAMP, expires_at = ?
+	WHERE id = ?`
+
+	_, err = m.DB.ExecContext(ctx, stmt, ExportReady, hashToken(token), storageKey, size, expiresAt.UTC().Format(sqliteDateTime), id)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Fail records that the export could not be built.
+func (m *ExportModel) Fail(ctx context.Context, id int, reason string) error {
+	stmt := "UPDATE data_exports SET status = ?, error = ?, completed_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+	_, err := m.DB.ExecContext(ctx, stmt, ExportFailed, reason, id)
+	return err
+}
+
+// Download returns the ready export of the user matching the token of a
+// download link. It returns ErrNoRecord if the token is unknown, belongs to
+// another user or has expired.
+func (m *ExportModel) Download(ctx context.Context, userID int, token string) (Export, error) {
+	stmt := "SELECT " + exportColumns + ` FROM data_exports
+	WHERE token_hash = ? AND user_id = ? AND status = ? AND expires_at > ?`
+
+	e, err := scanExport(m.DB.QueryRowContext(ctx, stmt, hashToken(token), userID, ExportReady, time.Now().UTC().Format(sqliteDateTime)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Export{}, ErrNoRecord
+		}
+		return Export{}, err
+	}
+
+	return e, nil
+}
+
+// Prune deletes the exports which expired before now, and the exports of the
+// user when userID is not 0, returning the storage keys of their archives so
+// that the caller removes them.
+func (m *ExportModel) Prune(ctx context.Context, now time.Time, userID int) ([]string, error) {
+	stmt := `DELETE FROM data_exports
+	WHERE expires_at < ? OR user_id = ?
+	RETURNING storage_key`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, now.UTC().Format(sqliteDateTime), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Collect returns everything stored about the user, read in a single
+// transaction so that the parts are consistent with each other. Credentials
+// such as password hashes and token hashes are left out.
+func (m *ExportModel) Collect(ctx context.Context, userID int) (PersonalData, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return PersonalData{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var d PersonalData
+
+	d.User, err = scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PersonalData{}, ErrNoRecord
+		}
+		return PersonalData{}, err
+	}
+
+	d.Events, err = collect(ctx, tx, "SELECT "+eventColumns+" FROM events WHERE user_id = ? ORDER BY id", scanEvent, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt := `SELECT r.id, r.event_id, r.user_id, r.created_at, e.title, e.event_date
+	FROM rsvps r
+	JOIN events e ON e.id = r.event_id
+	WHERE r.user_id = ?
+	ORDER BY r.id`
+
+	d.Attendances, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (Attendance, error) {
+		var a Attendance
+		err := row.Scan(&a.ID, &a.EventID, &a.UserID, &a.CreatedAt, &a.EventTitle, &a.EventDate)
+		return a, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+	FROM user_sessions WHERE user_id = ? ORDER BY id`
+
+	d.Sessions, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (Session, error) {
+		var s Session
+		err := row.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+		return s, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = `SELECT id, user_id, action, ip, detail, created_at
+	FROM audit_events WHERE user_id = ? ORDER BY id`
+
+	d.AuditEvents, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (AuditEvent, error) {
+		var e AuditEvent
+		err := row.Scan(&e.ID, &e.UserID, &e.Action, &e.IP, &e.Detail, &e.CreatedAt)
+		e.UserEmail = d.User.Email
+		return e, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = "SELECT issuer, subject, created_at FROM user_identities WHERE user_id = ? ORDER BY id"
+
+	d.Identities, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (Identity, error) {
+		var i Identity
+		err := row.Scan(&i.Issuer, &i.Subject, &i.CreatedAt)
+		return i, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = `SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+	FROM api_tokens WHERE user_id = ? ORDER BY id`
+
+	d.APITokens, err = collect(ctx, tx, stmt, scanAPIToken, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	return d, tx.Commit()
+}
+
+// collect runs the query inside the transaction and scans every row with scan.
+func collect[T any](ctx context.Context, tx *sql.Tx, stmt string, scan func(interface{ Scan(...any) error }) (T, error), args ...any) ([]T, error) {
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var items []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+)
+
+// PostgresExportModel is the PostgreSQL version of ExportModel.
+type PostgresExportModel struct {
+	DB *sql.DB
+}
+
+// Request queues an export of the data of the user and returns its ID. It
+// returns ErrExportInProgress if an export of the user is already queued.
+func (m *PostgresExportModel) Request(ctx context.Context, userID int) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The row of the user is locked, so that concurrent requests of the
+	// same user are checked one after the other.
+	_, err = tx.ExecContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `INSERT INTO data_exports (user_id)
+	SELECT $1::bigint WHERE NOT EXISTS (SELECT 1 FROM data_exports WHERE user_id = $1 AND status IN ($2, $3))
+	RETURNING id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, stmt, userID, ExportPending, ExportRunning).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrExportInProgress
+		}
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Latest returns the most recent export of the user, or ErrNoRecord if they
+// never requested one.
+func (m *PostgresExportModel) Latest(ctx context.Context, userID int) (Export, error) {
+	stmt := "SELECT " + exportColumns + " FROM data_exports WHERE user_id = $1 ORDER BY id DESC LIMIT 1"
+
+	e, err := scanExport(m.DB.QueryRowContext(ctx, stmt, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Export{}, ErrNoRecord
+		}
+		return Export{}, err
+	}
+
+	return e, nil
+}
+
+// Claim marks the oldest pending export as running and returns it. Exports
+// left running for longer than staleAfter, by a worker which stopped, are
+// claimed again. It returns false if there is nothing to claim. Exports
+// being claimed by another worker are skipped.
+func (m *PostgresExportModel) Claim(ctx context.Context, now time.Time, staleAfter time.Duration) (Export, bool, error) {
+	stmt := `UPDATE data_exports SET status = $1, claimed_at = $2
+	WHERE id = (
+		SELECT id FROM data_exports
+		WHERE status = $3 OR (status = $1 AND claimed_at < $4)
+		ORDER BY id LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + exportColumns
+
+	e, err := scanExport(m.DB.QueryRowContext(ctx, stmt, ExportRunning, now, ExportPending, now.Add(-staleAfter)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Export{}, false, nil
+		}
+		return Export{}, false, err
+	}
+
+	return e, true, nil
+}
+
+// Complete records that the archive of the export is stored under the key
+// and returns the token of its download link, valid until expiresAt. Only the
+// hash of the token is stored.
+func (m *PostgresExportModel) Complete(ctx context.Context, id int, storageKey string, size int64, expiresAt time.Time) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `UPDATE data_exports
+	SET status = $1, token_hash = $2, storage_key = $3, size = $4, completed_at = CURRENT_TIMESTAMP, expires_at = $5
+	WHERE id = $6`
+
+	_, err = m.DB.ExecContext(ctx, stmt, ExportReady, hashToken(token), storageKey, size, expiresAt, id)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Fail records that the export could not be built.
+func (m *PostgresExportModel) Fail(ctx context.Context, id int, reason string) error {
+	stmt := "UPDATE data_exports SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP WHERE id = $3"
+
+	_, err := m.DB.ExecContext(ctx, stmt, ExportFailed, reason, id)
+	return err
+}
+
+// Download returns the ready export of the user matching the token of a
+// download link. It returns ErrNoRecord if the token is unknown, belongs to
+// another user or has expired.
+func (m *PostgresExportModel) Download(ctx context.Context, userID int, token string) (Export, error) {
+	stmt := "SELECT " + exportColumns + ` FROM data_exports
+	WHERE token_hash = $1 AND user_id = $2 AND status = $3 AND expires_at > $4`
+
+	e, err := scanExport(m.DB.QueryRowContext(ctx, stmt, hashToken(token), userID, ExportReady, time.Now()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Export{}, ErrNoRecord
+		}
+		return Export{}, err
+	}
+
+	return e, nil
+}
+
+// Prune deletes the exports which expired before now, and the exports of the
+// user when userID is not 0, returning the storage keys of their archives so
+// that the caller removes them.
+func (m *PostgresExportModel) Prune(ctx context.Context, now time.Time, userID int) ([]string, error) {
+	stmt := `DELETE FROM data_exports
+	WHERE expires_at < $1 OR user_id = $2
+	RETURNING storage_key`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, now, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Collect returns everything stored about the user, read in a single
+// repeatable read transaction so that the parts are consistent with each
+// other. Credentials such as password hashes and token hashes are left out.
+func (m *PostgresExportModel) Collect(ctx context.Context, userID int) (PersonalData, error) {
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return PersonalData{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var d PersonalData
+
+	d.User, err = scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PersonalData{}, ErrNoRecord
+		}
+		return PersonalData{}, err
+	}
+
+	d.Events, err = collect(ctx, tx, "SELECT "+eventColumns+" FROM events WHERE user_id = $1 ORDER BY id", scanEvent, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt := `SELECT r.id, r.event_id, r.user_id, r.created_at, e.title, e.event_date
+	FROM rsvps r
+	JOIN events e ON e.id = r.event_id
+	WHERE r.user_id = $1
+	ORDER BY r.id`
+
+	d.Attendances, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (Attendance, error) {
+		var a Attendance
+		err := row.Scan(&a.ID, &a.EventID, &a.UserID, &a.CreatedAt, &a.EventTitle, &a.EventDate)
+		return a, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+	FROM user_sessions WHERE user_id = $1 ORDER BY id`
+
+	d.Sessions, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (Session, error) {
+		var s Session
+		err := row.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+		return s, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = `SELECT id, user_id, action, ip, detail, created_at
+	FROM audit_events WHERE user_id = $1 ORDER BY id`
+
+	d.AuditEvents, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (AuditEvent, error) {
+		var e AuditEvent
+		err := row.Scan(&e.ID, &e.UserID, &e.Action, &e.IP, &e.Detail, &e.CreatedAt)
+		e.UserEmail = d.User.Email
+		return e, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = "SELECT issuer, subject, created_at FROM user_identities WHERE user_id = $1 ORDER BY id"
+
+	d.Identities, err = collect(ctx, tx, stmt, func(row interface{ Scan(...any) error }) (Identity, error) {
+		var i Identity
+		err := row.Scan(&i.Issuer, &i.Subject, &i.CreatedAt)
+		return i, err
+	}, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	stmt = `SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+	FROM api_tokens WHERE user_id = $1 ORDER BY id`
+
+	d.APITokens, err = collect(ctx, tx, stmt, scanAPIToken, userID)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	return d, tx.Commit()
+}
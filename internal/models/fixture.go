@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Fixture is a set of sample data to insert into the database. The UserID of
+// the events and the UserID and EventID of the RSVPs are 1-based positions in
+// Users and Events rather than database IDs, which are only known once the
+// rows are inserted.
+type Fixture struct {
+	Users  []User
+	Events []Event
+	RSVPs  []RSVP
+}
+
+// FixtureModel inserts sample data into the database.
+type FixtureModel struct {
+	DB *sql.DB
+}
+
+// Insert adds the fixture in a single transaction, so that either all of it
+// or nothing is inserted. The users are stored with their HashedPassword as
+// is. No webhooks are queued. It returns ErrDuplicateEmail if the email of a
+// user is already registered.
+func (m *FixtureModel) Insert(ctx context.Context, f Fixture) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	userIDs := make([]int64, len(f.Users))
+	for i, u := range f.Users {
+		stmt := "INSERT INTO users (name, email, password, role, created_at) VALUES (?, ?, ?, ?, ?)"
+
+		result, err := tx.ExecContext(ctx, stmt, u.Name, u.Email, u.HashedPassword, u.Role, u.CreatedAt.UTC().Format(sqliteDateTime))
+		if err != nil {
+			var sqliteError sqlite3.Error
+			if errors.As(err, &sqliteError) && errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
+				return fmt.Errorf("%w: %s", ErrDuplicateEmail, u.Email)
+			}
+			return err
+		}
+
+		userIDs[i], err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+	}
+
+	eventIDs := make([]int64, len(f.Events))
+	for i, e := range f.Events {
+		owner, err := fixtureRef(userIDs, e.UserID)
+		if err != nil {
+			return fmt.Errorf("event %d: %w", i+1, err)
+		}
+
+		stmt := `INSERT INTO events (user_id, title, description, event_date, end_date, location, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+		createdAt := e.CreatedAt.UTC().Format(sqliteDateTime)
+
+		result, err := tx.ExecContext(ctx, stmt, owner, e.Title, e.Description, e.EventDate, nullTime(e.EndDate), e.Location, createdAt, createdAt)
+		if err != nil {
+			return err
+		}
+
+		eventIDs[i], err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, r := range f.RSVPs {
+		eventID, err := fixtureRef(eventIDs, r.EventID)
+		if err != nil {
+			return fmt.Errorf("rsvp %d: %w", i+1, err)
+		}
+		userID, err := fixtureRef(userIDs, r.UserID)
+		if err != nil {
+			return fmt.Errorf("rsvp %d: %w", i+1, err)
+		}
+
+		stmt := "INSERT OR IGNORE INTO rsvps (event_id, user_id, created_at) VALUES (?, ?, ?)"
+
+		_, err = tx.ExecContext(ctx, stmt, eventID, userID, r.CreatedAt.UTC().Format(sqliteDateTime))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fixtureRef returns the database ID of the row at the 1-based position in
+// ids, or NULL for position 0.
+func fixtureRef(ids []int64, position int) (sql.NullInt64, error) {
+	if position == 0 {
+		return sql.NullInt64{}, nil
+	}
+	if position < 0 || position > len(ids) {
+		return sql.NullInt64{}, fmt.Errorf("reference %d out of range", position)
+	}
+	return sql.NullInt64{Int64: ids[position-1], Valid: true}, nil
+}
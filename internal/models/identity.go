@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Audit actions recorded for single sign-on.
+const (
+	AuditIdentityLinked      = "identity.linked"
+	AuditIdentityProvisioned = "identity.provisioned"
+)
+
+// RetrieveByIdentity returns the ID of the user linked to the subject at the
+// OpenID Connect issuer, or ErrNoRecord if there is none.
+func (m *UserModel) RetrieveByIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	var id int
+
+	stmt := "SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?"
+
+	err := m.DB.QueryRowContext(ctx, stmt, issuer, subject).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// LinkIdentity links the subject at the issuer to the user with the email and
+// returns the ID of the user, or ErrNoRecord if no user has the email.
+func (m *UserModel) LinkIdentity(ctx context.Context, email, issuer, subject string) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM users WHERE email = ?", email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	err = insertIdentity(ctx, tx, id, issuer, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// CreateFromIdentity adds a user without password, who logs in with the
+// subject at the issuer, and returns the ID of the user. It returns
+// ErrDuplicateEmail if the email is already used.
+func (m *UserModel) CreateFromIdentity(ctx context.Context, name, email, issuer, subject string) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO users (name, email, password) VALUES (?, ?, '')", name, email)
+	if err != nil {
+		var sqliteError sqlite3.Error
+		if errors.As(err, &sqliteError) && errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return 0, ErrDuplicateEmail
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertIdentity(ctx, tx, int(id), issuer, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+// insertIdentity links the subject at the issuer to the user.
+func insertIdentity(ctx context.Context, tx *sql.Tx, userID int, issuer, subject string) error {
+	stmt := "INSERT INTO user_identities (user_id, issuer, subject) VALUES (?, ?, ?)"
+
+	_, err := tx.ExecContext(ctx, stmt, userID, issuer, subject)
+	return err
+}
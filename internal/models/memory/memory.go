@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Store keeps users and events in memory, for tests and development without
+// a database. Its repositories behave like the SQLite ones, except that no
+// webhooks are queued and that events have no RSVPs or attachments. It never
+// blocks, so its repositories ignore the contexts they are given.
+type Store struct {
+	mu sync.Mutex
+
+	users      map[int]*user
+	events     map[int]models.Event
+	identities map[identity]int
+	changes    map[string]emailChange
+
+	lastUserID  int
+	lastEventID int
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{
+		users:      make(map[int]*user),
+		events:     make(map[int]models.Event),
+		identities: make(map[identity]int),
+		changes:    make(map[string]emailChange),
+	}
+}
+
+// Events returns the repository of the events of the store.
+func (s *Store) Events() *EventRepository {
+	return &EventRepository{store: s}
+}
+
+// Users returns the repository of the users of the store.
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{store: s}
+}
+
+// now returns the current time at the precision of the database timestamps.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// EventRepository is an in-memory models.EventRepository.
+type EventRepository struct {
+	store *Store
+}
+
+var _ models.EventRepository = (*EventRepository)(nil)
+
+// Create adds an event and returns its ID, or models.ErrVenueConflict if the
+// venue is already booked during the event.
+func (r *EventRepository) Create(_ context.Context, userID int, title, description string, eventDate, endDate time.Time, location string, venueID int) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := models.Event{
+		Id:          s.lastEventID + 1,
+		UserID:      userID,
+		VenueID:     venueID,
+		Title:       title,
+		Description: description,
+		Location:    location,
+		EventDate:   eventDate.UTC(),
+		EndDate:     endDate.UTC(),
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	}
+	if endDate.IsZero() {
+		e.EndDate = time.Time{}
+	}
+
+	if s.venueConflict(e) {
+		return 0, models.ErrVenueConflict
+	}
+
+	s.lastEventID = e.Id
+	s.events[e.Id] = e
+	return e.Id, nil
+}
+
+// Update modifies the event, and returns models.ErrNoRecord if there is no
+// such event or models.ErrVenueConflict if the venue is already booked
+// during the event.
+func (r *EventRepository) Update(_ context.Context, id int, title, description string, eventDate, endDate time.Time, location string, venueID int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.events[id]
+	if !ok {
+		return models.ErrNoRecord
+	}
+
+	e.VenueID = venueID
+	e.Title = title
+	e.Description = description
+	e.Location = location
+	e.EventDate = eventDate.UTC()
+	e.EndDate = time.Time{}
+	if !endDate.IsZero() {
+		e.EndDate = endDate.UTC()
+	}
+	e.UpdatedAt = now()
+
+	if s.venueConflict(e) {
+		return models.ErrVenueConflict
+	}
+
+	s.events[id] = e
+	return nil
+}
+
+// Retrieve returns the event, or models.ErrNoRecord if there is no such event.
+func (r *EventRepository) Retrieve(_ context.Context, id int) (models.Event, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.events[id]
+	if !ok {
+		return models.Event{}, models.ErrNoRecord
+	}
+	return e, nil
+}
+
+// Delete removes the event, and returns models.ErrNoRecord if there is no
+// such event.
+func (r *EventRepository) Delete(_ context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.events[id]
+	if !ok {
+		return models.ErrNoRecord
+	}
+	delete(s.events, id)
+	return nil
+}
+
+// List returns every event, ordered by ID.
+func (r *EventRepository) List(_ context.Context) ([]models.Event, error) {
+	return r.filter(func(models.Event) bool { return true }), nil
+}
+
+// Between returns the events taking place at least partly in the range from
+// start (inclusive) to end (exclusive), ordered by their date.
+func (r *EventRepository) Between(_ context.Context, start, end time.Time) ([]models.Event, error) {
+	events := r.filter(func(e models.Event) bool {
+		return e.EventDate.Before(end) && !e.LastDate().Before(start)
+	})
+
+	slices.SortStableFunc(events, func(a, b models.Event) int {
+		return a.EventDate.Compare(b.EventDate)
+	})
+	return events, nil
+}
+
+// CountUpcoming returns the number of events starting at or after now.
+func (r *EventRepository) CountUpcoming(_ context.Context, now time.Time) (int, error) {
+	return len(r.filter(func(e models.Event) bool { return !e.EventDate.Before(now) })), nil
+}
+
+// filter returns the events for which keep reports true, ordered by ID.
+func (r *EventRepository) filter(keep func(models.Event) bool) []models.Event {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []models.Event
+	for _, e := range s.events {
+		if keep(e) {
+			events = append(events, e)
+		}
+	}
+
+	slices.SortFunc(events, func(a, b models.Event) int { return a.Id - b.Id })
+	return events
+}
+
+// venueConflict reports whether another event is booked at the venue of the
+// event at any time from its start up to its end. The caller holds the lock.
+func (s *Store) venueConflict(e models.Event) bool {
+	if e.VenueID == 0 {
+		return false
+	}
+
+	for _, other := range s.events {
+		if other.VenueID == e.VenueID && other.Id != e.Id &&
+			other.EventDate.Before(e.End()) && e.EventDate.Before(other.End()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package memory_test
+
+import (
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"testing"
+)
+
+func TestRepositories(t *testing.T) {
+	modeltest.Run(t, modeltest.Memory)
+}
@@ -0,0 +1,460 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+// user is a stored user with the credentials which models.User leaves out.
+type user struct {
+	models.User
+	totpSecret    string
+	totpLastStep  int64
+	recoveryCodes map[string]bool
+	failedLogins  int
+	lockedUntil   time.Time
+}
+
+// identity is a subject at an OpenID Connect issuer.
+type identity struct {
+	issuer, subject string
+}
+
+// emailChange is a pending change of the email of a user.
+type emailChange struct {
+	userID    int
+	email     string
+	expiresAt time.Time
+}
+
+// UserRepository is an in-memory models.UserRepository. Passwords are hashed
+// with the minimum bcrypt cost, to keep tests fast.
+type UserRepository struct {
+	store *Store
+}
+
+var _ models.UserRepository = (*UserRepository)(nil)
+
+// Create adds a user, or returns models.ErrDuplicateEmail if the email is
+// already used.
+func (r *UserRepository) Create(_ context.Context, name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.insertUser(name, email, hashedPassword)
+	return err
+}
+
+// Retrieve returns the user, or models.ErrNoRecord if there is no such user.
+func (r *UserRepository) Retrieve(_ context.Context, id int) (models.User, error) {
+	var u models.User
+	err := r.with(id, func(stored *user) error {
+		u = stored.User
+		u.HashedPassword = nil
+		u.TOTPEnabled = stored.totpSecret != ""
+		return nil
+	})
+	return u, err
+}
+
+// Count returns the number of users.
+func (r *UserRepository) Count(_ context.Context) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return len(r.store.users), nil
+}
+
+// Update saves the name and email of the user, and returns
+// models.ErrDuplicateEmail if another account already uses the email.
+func (r *UserRepository) Update(_ context.Context, u models.User) error {
+	return r.with(u.ID, func(stored *user) error {
+		if other := r.store.userByEmail(u.Email); other != nil && other.ID != u.ID {
+			return models.ErrDuplicateEmail
+		}
+		stored.Name, stored.Email = u.Name, u.Email
+		return nil
+	})
+}
+
+// UpdatePassword replaces the password of the user.
+func (r *UserRepository) UpdatePassword(_ context.Context, id int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+
+	return r.with(id, func(stored *user) error {
+		stored.HashedPassword = hashedPassword
+		stored.HasPassword = true
+		return nil
+	})
+}
+
+// UpdateLocale saves the language chosen by the user.
+func (r *UserRepository) UpdateLocale(_ context.Context, id int, locale string) error {
+	return r.with(id, func(stored *user) error {
+		stored.Locale = locale
+		return nil
+	})
+}
+
+// Delete removes the user with their credentials and the events they own,
+// which nobody else can attend in memory. There are no attachments to
+// return.
+func (r *UserRepository) Delete(_ context.Context, id int) ([]models.Attachment, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.users[id]
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+	delete(s.users, id)
+
+	for eventID, e := range s.events {
+		if e.UserID == id {
+			delete(s.events, eventID)
+		}
+	}
+	for key, userID := range s.identities {
+		if userID == id {
+			delete(s.identities, key)
+		}
+	}
+	for token, change := range s.changes {
+		if change.userID == id {
+			delete(s.changes, token)
+		}
+	}
+
+	return nil, nil
+}
+
+// Authenticate returns the ID of the user with the email and password, or
+// models.ErrInvalidCredentials.
+func (r *UserRepository) Authenticate(_ context.Context, email, password string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	u := s.userByEmail(email)
+	var hashedPassword []byte
+	if u != nil && !u.Disabled {
+		hashedPassword = u.HashedPassword
+	}
+	s.mu.Unlock()
+
+	if len(hashedPassword) == 0 {
+		return 0, models.ErrInvalidCredentials
+	}
+
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, models.ErrInvalidCredentials
+		}
+		return 0, err
+	}
+	return u.ID, nil
+}
+
+// IsLocked reports whether the account with the email is locked out.
+func (r *UserRepository) IsLocked(_ context.Context, email string) (bool, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.userByEmail(email)
+	return u != nil && u.lockedUntil.After(time.Now()), nil
+}
+
+// RecordLoginFailure counts a failed login for the account with the email,
+// and locks it for the lockout duration after maxFailures failures. It
+// returns the ID of the account, 0 for unknown emails, and whether it has
+// just been locked.
+func (r *UserRepository) RecordLoginFailure(_ context.Context, email string, maxFailures int, lockout time.Duration) (int, bool, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.userByEmail(email)
+	if u == nil {
+		return 0, false, nil
+	}
+
+	u.failedLogins++
+	locked := u.failedLogins >= maxFailures
+	if locked {
+		u.failedLogins = 0
+		u.lockedUntil = time.Now().Add(lockout)
+	}
+	return u.ID, locked, nil
+}
+
+// ResetLoginFailures clears the failed login count of the user.
+func (r *UserRepository) ResetLoginFailures(_ context.Context, id int) error {
+	err := r.with(id, func(stored *user) error {
+		stored.failedLogins = 0
+		stored.lockedUntil = time.Time{}
+		return nil
+	})
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil
+	}
+	return err
+}
+
+// RequestEmailChange records that the user wants to use the email and
+// returns the token confirming it, replacing any pending change of the user.
+// It returns models.ErrDuplicateEmail if another account uses the email.
+func (r *UserRepository) RequestEmailChange(_ context.Context, userID int, email string, expiresAt time.Time) (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.userByEmail(email) != nil {
+		return "", models.ErrDuplicateEmail
+	}
+
+	for key, change := range s.changes {
+		if change.userID == userID {
+			delete(s.changes, key)
+		}
+	}
+	s.changes[token] = emailChange{userID: userID, email: email, expiresAt: expiresAt}
+
+	return token, nil
+}
+
+// ConfirmEmailChange applies the pending email change matching the token and
+// returns the ID of the user with their previous and new emails. It returns
+// models.ErrNoRecord if the token is unknown or expired, and
+// models.ErrDuplicateEmail if the email was taken in the meantime.
+func (r *UserRepository) ConfirmEmailChange(_ context.Context, token string) (int, string, string, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, ok := s.changes[token]
+	if !ok || !change.expiresAt.After(time.Now()) {
+		return 0, "", "", models.ErrNoRecord
+	}
+
+	u, ok := s.users[change.userID]
+	if !ok {
+		return 0, "", "", models.ErrNoRecord
+	}
+	if s.userByEmail(change.email) != nil {
+		return 0, "", "", models.ErrDuplicateEmail
+	}
+
+	delete(s.changes, token)
+	oldEmail := u.Email
+	u.Email = change.email
+
+	return u.ID, oldEmail, u.Email, nil
+}
+
+// RetrieveByIdentity returns the ID of the user linked to the subject at the
+// issuer, or models.ErrNoRecord if there is none.
+func (r *UserRepository) RetrieveByIdentity(_ context.Context, issuer, subject string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.identities[identity{issuer, subject}]
+	if !ok {
+		return 0, models.ErrNoRecord
+	}
+	return id, nil
+}
+
+// LinkIdentity links the subject at the issuer to the user with the email and
+// returns the ID of the user, or models.ErrNoRecord if no user has the email.
+func (r *UserRepository) LinkIdentity(_ context.Context, email, issuer, subject string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.userByEmail(email)
+	if u == nil {
+		return 0, models.ErrNoRecord
+	}
+
+	s.identities[identity{issuer, subject}] = u.ID
+	return u.ID, nil
+}
+
+// CreateFromIdentity adds a user without password, who logs in with the
+// subject at the issuer, and returns the ID of the user. It returns
+// models.ErrDuplicateEmail if the email is already used.
+func (r *UserRepository) CreateFromIdentity(_ context.Context, name, email, issuer, subject string) (int, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.insertUser(name, email, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	s.identities[identity{issuer, subject}] = id
+	return id, nil
+}
+
+// TOTPSecret returns the TOTP secret of the user, empty if two-factor
+// authentication is disabled.
+func (r *UserRepository) TOTPSecret(_ context.Context, id int) (string, error) {
+	var secret string
+	err := r.with(id, func(stored *user) error {
+		secret = stored.totpSecret
+		return nil
+	})
+	return secret, err
+}
+
+// EnableTOTP turns on two-factor authentication for the user, replacing the
+// recovery codes.
+func (r *UserRepository) EnableTOTP(_ context.Context, id int, secret string, recoveryCodes []string) error {
+	return r.with(id, func(stored *user) error {
+		stored.totpSecret = secret
+		stored.totpLastStep = 0
+		stored.recoveryCodes = make(map[string]bool)
+		for _, code := range recoveryCodes {
+			stored.recoveryCodes[code] = false
+		}
+		return nil
+	})
+}
+
+// DisableTOTP turns off two-factor authentication for the user and deletes
+// the recovery codes.
+func (r *UserRepository) DisableTOTP(_ context.Context, id int) error {
+	err := r.with(id, func(stored *user) error {
+		stored.totpSecret = ""
+		stored.totpLastStep = 0
+		stored.recoveryCodes = nil
+		return nil
+	})
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil
+	}
+	return err
+}
+
+// UseTOTPStep records that the user logged in with the code of the time step,
+// and reports false if a code of the same or a later step was already used.
+func (r *UserRepository) UseTOTPStep(_ context.Context, id int, step int64) (bool, error) {
+	var used bool
+	err := r.with(id, func(stored *user) error {
+		if stored.totpLastStep < step {
+			stored.totpLastStep = step
+			used = true
+		}
+		return nil
+	})
+	if errors.Is(err, models.ErrNoRecord) {
+		return false, nil
+	}
+	return used, err
+}
+
+// UseRecoveryCode marks the recovery code of the user as used, and reports
+// false if the code is unknown or was already used.
+func (r *UserRepository) UseRecoveryCode(_ context.Context, id int, code string) (bool, error) {
+	var ok bool
+	err := r.with(id, func(stored *user) error {
+		used, known := stored.recoveryCodes[code]
+		if known && !used {
+			stored.recoveryCodes[code] = true
+			ok = true
+		}
+		return nil
+	})
+	if errors.Is(err, models.ErrNoRecord) {
+		return false, nil
+	}
+	return ok, err
+}
+
+// RecoveryCodesLeft returns the number of unused recovery codes of the user.
+func (r *UserRepository) RecoveryCodesLeft(_ context.Context, id int) (int, error) {
+	var n int
+	err := r.with(id, func(stored *user) error {
+		for _, used := range stored.recoveryCodes {
+			if !used {
+				n++
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, models.ErrNoRecord) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// with calls fn with the stored user while holding the lock, or returns
+// models.ErrNoRecord if there is no such user.
+func (r *UserRepository) with(id int, fn func(stored *user) error) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return models.ErrNoRecord
+	}
+	return fn(u)
+}
+
+// insertUser adds a user with the hashed password, empty for users who log
+// in with single sign-on, and returns its ID. The caller holds the lock.
+func (s *Store) insertUser(name, email string, hashedPassword []byte) (int, error) {
+	if s.userByEmail(email) != nil {
+		return 0, models.ErrDuplicateEmail
+	}
+
+	s.lastUserID++
+	s.users[s.lastUserID] = &user{
+		User: models.User{
+			ID:             s.lastUserID,
+			Name:           name,
+			Email:          email,
+			HashedPassword: hashedPassword,
+			Role:           models.RoleUser,
+			HasPassword:    len(hashedPassword) > 0,
+			CreatedAt:      now(),
+		},
+	}
+	return s.lastUserID, nil
+}
+
+// userByEmail returns the user with the email, or nil. The caller holds the
+// lock.
+func (s *Store) userByEmail(email string) *user {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
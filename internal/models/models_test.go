@@ -0,0 +1,10 @@
+package models_test
+
+import (
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"testing"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	modeltest.Run(t, modeltest.SQLite)
+}
@@ -0,0 +1,140 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runAPITokens(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("CreateAndAuthenticate", func(t *testing.T) {
+		r := open(t)
+		need(t, r.APITokens)
+		userID := newUser(t, r.Users, "ada@example.test")
+
+		expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		token, err := r.APITokens.Create(ctx, userID, "CI", []string{models.ScopeEventsRead, models.ScopeEventsWrite}, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(token, "ept_") {
+			t.Errorf("got token %q, want the ept_ prefix", token)
+		}
+
+		tokens, err := r.APITokens.List(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tokens) != 1 || !tokens[0].LastUsedAt.IsZero() {
+			t.Fatalf("got tokens %+v, want one never used", tokens)
+		}
+
+		got, err := r.APITokens.Authenticate(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != tokens[0].ID || got.UserID != userID || got.Name != "CI" || got.CreatedAt.IsZero() {
+			t.Errorf("got %+v", got)
+		}
+		if !slices.Equal(got.Scopes, []string{models.ScopeEventsRead, models.ScopeEventsWrite}) {
+			t.Errorf("got scopes %q", got.Scopes)
+		}
+		if !got.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("got expiry %v, want %v", got.ExpiresAt, expiresAt)
+		}
+		if got.LastUsedAt.IsZero() {
+			t.Error("got the use of the token not recorded")
+		}
+
+		_, err = r.APITokens.Authenticate(ctx, token+"x")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("authenticating an unknown token: got %v, want ErrInvalidCredentials", err)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		r := open(t)
+		need(t, r.APITokens)
+		userID := newUser(t, r.Users, "ada@example.test")
+
+		expired, err := r.APITokens.Create(ctx, userID, "Expired", []string{models.ScopeEventsRead}, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.APITokens.Authenticate(ctx, expired)
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("got %v, want ErrInvalidCredentials", err)
+		}
+
+		// A zero expiry never expires.
+		forever, err := r.APITokens.Create(ctx, userID, "Forever", []string{models.ScopeEventsRead}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.APITokens.Authenticate(ctx, forever)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.ExpiresAt.IsZero() {
+			t.Errorf("got expiry %v, want none", got.ExpiresAt)
+		}
+	})
+
+	t.Run("ListAndRevoke", func(t *testing.T) {
+		r := open(t)
+		need(t, r.APITokens)
+		userID := newUser(t, r.Users, "ada@example.test")
+		otherID := newUser(t, r.Users, "grace@example.test")
+
+		for _, name := range []string{"First", "Second"} {
+			_, err := r.APITokens.Create(ctx, userID, name, nil, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		token, err := r.APITokens.Create(ctx, otherID, "Other", nil, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The tokens are listed most recent first.
+		tokens, err := r.APITokens.List(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tokens) != 2 || tokens[0].Name != "Second" || tokens[1].Name != "First" {
+			t.Fatalf("got tokens %+v, want Second and First", tokens)
+		}
+		if tokens[0].Scopes != nil {
+			t.Errorf("got scopes %q, want none", tokens[0].Scopes)
+		}
+
+		others, err := r.APITokens.List(ctx, otherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(others) != 1 {
+			t.Fatalf("got %d tokens of the other user, want 1", len(others))
+		}
+
+		err = r.APITokens.Revoke(ctx, userID, others[0].ID)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("revoking the token of another user: got %v, want ErrNoRecord", err)
+		}
+
+		err = r.APITokens.Revoke(ctx, otherID, others[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.APITokens.Authenticate(ctx, token)
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("authenticating the revoked token: got %v, want ErrInvalidCredentials", err)
+		}
+	})
+}
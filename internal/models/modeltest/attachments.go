@@ -0,0 +1,104 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+func runAttachments(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("CreateListDelete", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Attachments)
+		userID := newUser(t, r.Users, "ada@example.test")
+		eventID := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+		otherEventID := newEvent(t, r.Events, userID, "Seminar", date(2026, 11, 6), time.Time{})
+
+		cover := models.Attachment{
+			EventID:      eventID,
+			Kind:         models.AttachmentCover,
+			Filename:     "cover.png",
+			ContentType:  "image/png",
+			Size:         2048,
+			StorageKey:   "attachments/cover",
+			ThumbnailKey: "attachments/cover-thumbnail",
+		}
+		coverID, err := r.Attachments.Create(ctx, cover)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agendaID, err := r.Attachments.Create(ctx, models.Attachment{
+			EventID: eventID, Kind: models.AttachmentFile, Filename: "agenda.pdf",
+			ContentType: "application/pdf", Size: 4096, StorageKey: "attachments/agenda",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Attachments.Create(ctx, models.Attachment{
+			EventID: otherEventID, Kind: models.AttachmentFile, Filename: "other.pdf",
+			ContentType: "application/pdf", Size: 1, StorageKey: "attachments/other",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := r.Attachments.Retrieve(ctx, coverID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cover.ID = coverID
+		cover.CreatedAt = a.CreatedAt
+		if a != cover || a.CreatedAt.IsZero() {
+			t.Errorf("got %+v, want %+v", a, cover)
+		}
+
+		attachments, err := r.Attachments.List(ctx, eventID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attachments) != 2 || attachments[0].ID != coverID || attachments[1].ID != agendaID {
+			t.Errorf("got attachments %+v, want %d and %d", attachments, coverID, agendaID)
+		}
+
+		err = r.Attachments.Delete(ctx, coverID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Attachments.Retrieve(ctx, coverID)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving the deleted attachment: got %v, want ErrNoRecord", err)
+		}
+		err = r.Attachments.Delete(ctx, coverID)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("deleting the deleted attachment: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("DeletedWithTheEvent", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Attachments)
+		userID := newUser(t, r.Users, "ada@example.test")
+		eventID := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+
+		id, err := r.Attachments.Create(ctx, models.Attachment{
+			EventID: eventID, Kind: models.AttachmentFile, Filename: "agenda.pdf",
+			ContentType: "application/pdf", Size: 4096, StorageKey: "attachments/agenda",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.Events.Delete(ctx, eventID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Attachments.Retrieve(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("got %v, want ErrNoRecord", err)
+		}
+	})
+}
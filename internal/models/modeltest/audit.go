@@ -0,0 +1,52 @@
+package modeltest
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+)
+
+func runAudit(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("RecordAndList", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Audit)
+		userID := newUser(t, r.Users, "ada@example.test")
+
+		err := r.Audit.Record(ctx, userID, models.AuditPasswordChanged, "192.0.2.1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Events of unknown users are recorded without one.
+		err = r.Audit.Record(ctx, 0, models.AuditAccountLocked, "192.0.2.2", "unknown@example.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Audit.Record(ctx, userID, models.AuditEmailChanged, "192.0.2.1", "ada@example.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := r.Audit.List(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("got %d events, want 2", len(events))
+		}
+
+		e := events[0]
+		if e.UserID != userID || e.UserEmail != "ada@example.test" || e.Action != models.AuditEmailChanged ||
+			e.IP != "192.0.2.1" || e.Detail != "ada@example.test" || e.CreatedAt.IsZero() {
+			t.Errorf("got %+v", e)
+		}
+		e = events[1]
+		if e.UserID != 0 || e.UserEmail != "" || e.Action != models.AuditAccountLocked || e.Detail != "unknown@example.test" {
+			t.Errorf("got %+v", e)
+		}
+		if events[0].ID <= events[1].ID {
+			t.Errorf("got events %d and %d, want the most recent first", events[0].ID, events[1].ID)
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+// date returns midnight UTC of the day, as the event forms submit dates.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// newUser creates a user and returns its ID.
+func newUser(t *testing.T, users models.UserRepository, email string) int {
+	t.Helper()
+	ctx := context.Background()
+
+	err := users.Create(ctx, "Test User", email, "password123")
+	if err != nil {
+		t.Fatalf("Create(%q): %v", email, err)
+	}
+
+	id, err := users.Authenticate(ctx, email, "password123")
+	if err != nil {
+		t.Fatalf("Authenticate(%q): %v", email, err)
+	}
+	return id
+}
+
+// newEvent creates an event of the user on the dates and returns its ID.
+func newEvent(t *testing.T, events models.EventRepository, userID int, title string, eventDate, endDate time.Time) int {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := events.Create(ctx, userID, title, "About "+title, eventDate, endDate, "Online", 0)
+	if err != nil {
+		t.Fatalf("Create(%q): %v", title, err)
+	}
+	return id
+}
+
+// ids returns the IDs of the events.
+func ids(events []models.Event) []int {
+	ids := make([]int, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+// equalIDs reports whether the IDs are the same, in the same order.
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func runEvents(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("CreateAndRetrieve", func(t *testing.T) {
+		r := open(t)
+		userID := newUser(t, r.Users, "owner@example.test")
+
+		id, err := r.Events.Create(ctx, userID, "Go Meetup", "Talks about **Go**", date(2026, 11, 5), date(2026, 11, 7), "Bucharest", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := r.Events.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Id != id || e.UserID != userID || e.VenueID != 0 {
+			t.Errorf("got IDs %d, user %d, venue %d; want %d, %d, 0", e.Id, e.UserID, e.VenueID, id, userID)
+		}
+		if e.Title != "Go Meetup" || e.Description != "Talks about **Go**" || e.Location != "Bucharest" {
+			t.Errorf("got %q, %q, %q", e.Title, e.Description, e.Location)
+		}
+		if !e.EventDate.Equal(date(2026, 11, 5)) || !e.EndDate.Equal(date(2026, 11, 7)) {
+			t.Errorf("got dates %v to %v", e.EventDate, e.EndDate)
+		}
+		if e.CreatedAt.IsZero() {
+			t.Error("CreatedAt is not set")
+		}
+	})
+
+	t.Run("SingleDay", func(t *testing.T) {
+		r := open(t)
+		userID := newUser(t, r.Users, "owner@example.test")
+		id := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+
+		e, err := r.Events.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !e.EndDate.IsZero() {
+			t.Errorf("got EndDate %v, want the zero time", e.EndDate)
+		}
+		if !e.LastDate().Equal(date(2026, 11, 5)) {
+			t.Errorf("got LastDate %v, want the event date", e.LastDate())
+		}
+	})
+
+	t.Run("RetrieveMissing", func(t *testing.T) {
+		r := open(t)
+
+		_, err := r.Events.Retrieve(ctx, 42)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := open(t)
+		userID := newUser(t, r.Users, "owner@example.test")
+		id := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), date(2026, 11, 6))
+
+		err := r.Events.Update(ctx, id, "Seminar", "Changed", date(2026, 12, 1), time.Time{}, "Berlin", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := r.Events.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Title != "Seminar" || e.Description != "Changed" || e.Location != "Berlin" || e.UserID != userID {
+			t.Errorf("got %+v", e)
+		}
+		if !e.EventDate.Equal(date(2026, 12, 1)) || !e.EndDate.IsZero() {
+			t.Errorf("got dates %v to %v", e.EventDate, e.EndDate)
+		}
+
+		err = r.Events.Update(ctx, id+1, "Seminar", "Changed", date(2026, 12, 1), time.Time{}, "Berlin", 0)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("updating a missing event: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := open(t)
+		userID := newUser(t, r.Users, "owner@example.test")
+		id := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+		kept := newEvent(t, r.Events, userID, "Meetup", date(2026, 11, 6), time.Time{})
+
+		err := r.Events.Delete(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Events.Retrieve(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving a deleted event: got %v, want ErrNoRecord", err)
+		}
+		_, err = r.Events.Retrieve(ctx, kept)
+		if err != nil {
+			t.Errorf("retrieving another event: %v", err)
+		}
+
+		err = r.Events.Delete(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("deleting a missing event: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := open(t)
+
+		events, err := r.Events.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Errorf("got %d events in a new store, want none", len(events))
+		}
+
+		userID := newUser(t, r.Users, "owner@example.test")
+		a := newEvent(t, r.Events, userID, "Later", date(2026, 12, 1), time.Time{})
+		b := newEvent(t, r.Events, userID, "Sooner", date(2026, 11, 1), time.Time{})
+
+		events, err = r.Events.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(events); !equalIDs(got, []int{a, b}) {
+			t.Errorf("got events %v, want %v", got, []int{a, b})
+		}
+	})
+
+	t.Run("Between", func(t *testing.T) {
+		r := open(t)
+		userID := newUser(t, r.Users, "owner@example.test")
+
+		before := newEvent(t, r.Events, userID, "Before", date(2026, 10, 30), time.Time{})
+		spanning := newEvent(t, r.Events, userID, "Spanning", date(2026, 10, 30), date(2026, 11, 2))
+		last := newEvent(t, r.Events, userID, "Last day", date(2026, 11, 30), time.Time{})
+		first := newEvent(t, r.Events, userID, "First day", date(2026, 11, 1), time.Time{})
+		after := newEvent(t, r.Events, userID, "After", date(2026, 12, 1), time.Time{})
+
+		events, err := r.Events.Between(ctx, date(2026, 11, 1), date(2026, 12, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []int{spanning, first, last}
+		if got := ids(events); !equalIDs(got, want) {
+			t.Errorf("got events %v, want %v (not %d or %d)", got, want, before, after)
+		}
+	})
+
+	t.Run("CountUpcoming", func(t *testing.T) {
+		r := open(t)
+		userID := newUser(t, r.Users, "owner@example.test")
+
+		newEvent(t, r.Events, userID, "Past", date(2026, 10, 1), date(2026, 11, 10))
+		newEvent(t, r.Events, userID, "Starting", date(2026, 11, 1), time.Time{})
+		newEvent(t, r.Events, userID, "Future", date(2026, 12, 1), time.Time{})
+
+		n, err := r.Events.CountUpcoming(ctx, date(2026, 11, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Errorf("got %d upcoming events, want 2", n)
+		}
+	})
+}
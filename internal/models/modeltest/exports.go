@@ -0,0 +1,246 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+func runExports(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("Request", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Exports)
+		userID := newUser(t, r.Users, "ada@example.test")
+
+		_, err := r.Exports.Latest(ctx, userID)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("got %v, want ErrNoRecord before any request", err)
+		}
+
+		id, err := r.Exports.Request(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Exports.Request(ctx, userID)
+		if !errors.Is(err, models.ErrExportInProgress) {
+			t.Errorf("requesting twice: got %v, want ErrExportInProgress", err)
+		}
+
+		e, err := r.Exports.Latest(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.ID != id || e.UserID != userID || e.Status != models.ExportPending || e.CreatedAt.IsZero() ||
+			!e.CompletedAt.IsZero() || !e.ExpiresAt.IsZero() {
+			t.Errorf("got %+v", e)
+		}
+
+		// Another export can be requested once the last one failed.
+		err = r.Exports.Fail(ctx, id, "disk full")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err = r.Exports.Latest(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Status != models.ExportFailed || e.Error != "disk full" || e.CompletedAt.IsZero() {
+			t.Errorf("got %+v", e)
+		}
+		_, err = r.Exports.Request(ctx, userID)
+		if err != nil {
+			t.Errorf("requesting after a failure: %v", err)
+		}
+	})
+
+	t.Run("Claim", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Exports)
+		userID := newUser(t, r.Users, "ada@example.test")
+		otherID := newUser(t, r.Users, "grace@example.test")
+		now := time.Now()
+
+		first, err := r.Exports.Request(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := r.Exports.Request(ctx, otherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The oldest pending export is claimed first.
+		for _, want := range []int{first, second} {
+			e, ok, err := r.Exports.Claim(ctx, now, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok || e.ID != want || e.Status != models.ExportRunning {
+				t.Errorf("got %+v, %t, want export %d running", e, ok, want)
+			}
+		}
+		_, ok, err := r.Exports.Claim(ctx, now, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("got an export claimed while every one is running")
+		}
+
+		// Exports running for too long are claimed again.
+		e, ok, err := r.Exports.Claim(ctx, now.Add(2*time.Hour), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || e.ID != first {
+			t.Errorf("got %+v, %t, want export %d claimed again", e, ok, first)
+		}
+	})
+
+	t.Run("CompleteAndDownload", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Exports)
+		userID := newUser(t, r.Users, "ada@example.test")
+		otherID := newUser(t, r.Users, "grace@example.test")
+
+		id, err := r.Exports.Request(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		token, err := r.Exports.Complete(ctx, id, "exports/ada.zip", 1024, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := r.Exports.Download(ctx, userID, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.ID != id || e.Status != models.ExportReady || e.StorageKey != "exports/ada.zip" || e.Size != 1024 ||
+			e.CompletedAt.IsZero() || !e.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("got %+v", e)
+		}
+
+		_, err = r.Exports.Download(ctx, otherID, token)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("downloading as another user: got %v, want ErrNoRecord", err)
+		}
+		_, err = r.Exports.Download(ctx, userID, token+"x")
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("downloading with an unknown token: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Exports)
+		userID := newUser(t, r.Users, "ada@example.test")
+		otherID := newUser(t, r.Users, "grace@example.test")
+		now := time.Now()
+
+		id, err := r.Exports.Request(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Exports.Complete(ctx, id, "exports/ada.zip", 1024, now.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherExport, err := r.Exports.Request(ctx, otherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Exports.Complete(ctx, otherExport, "exports/grace.zip", 2048, now.Add(3*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys, err := r.Exports.Prune(ctx, now, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 0 {
+			t.Errorf("got keys %q before any export expired, want none", keys)
+		}
+
+		keys, err = r.Exports.Prune(ctx, now.Add(2*time.Hour), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "exports/ada.zip" {
+			t.Errorf("got keys %q, want the expired export", keys)
+		}
+		_, err = r.Exports.Latest(ctx, userID)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("got %v, want ErrNoRecord once pruned", err)
+		}
+
+		// The exports of a user are pruned whether or not they expired.
+		keys, err = r.Exports.Prune(ctx, now, otherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "exports/grace.zip" {
+			t.Errorf("got keys %q, want the export of the user", keys)
+		}
+	})
+
+	t.Run("Collect", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Exports)
+		need(t, r.RSVPs)
+		need(t, r.Audit)
+		need(t, r.APITokens)
+		userID := newUser(t, r.Users, "ada@example.test")
+		otherID := newUser(t, r.Users, "grace@example.test")
+
+		eventID := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+		otherEventID := newEvent(t, r.Events, otherID, "Seminar", date(2026, 11, 6), time.Time{})
+		err := r.RSVPs.Create(ctx, otherEventID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Audit.Record(ctx, userID, models.AuditPasswordChanged, "192.0.2.1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Audit.Record(ctx, otherID, models.AuditPasswordChanged, "192.0.2.2", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.APITokens.Create(ctx, userID, "CI", []string{models.ScopeEventsRead}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := r.Exports.Collect(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.User.ID != userID || d.User.Email != "ada@example.test" {
+			t.Errorf("got user %+v", d.User)
+		}
+		if len(d.Events) != 1 || d.Events[0].Id != eventID {
+			t.Errorf("got events %+v, want %d", d.Events, eventID)
+		}
+		if len(d.Attendances) != 1 || d.Attendances[0].EventID != otherEventID || d.Attendances[0].EventTitle != "Seminar" {
+			t.Errorf("got attendances %+v, want the seminar", d.Attendances)
+		}
+		if len(d.AuditEvents) != 1 || d.AuditEvents[0].UserEmail != "ada@example.test" {
+			t.Errorf("got audit events %+v, want the one of the user", d.AuditEvents)
+		}
+		if len(d.APITokens) != 1 || d.APITokens[0].Name != "CI" {
+			t.Errorf("got tokens %+v, want CI", d.APITokens)
+		}
+
+		_, err = r.Exports.Collect(ctx, otherID+1)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("collecting a missing user: got %v, want ErrNoRecord", err)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package modeltest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"github.com/madalinpopa/go-event-planner/database"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/models/memory"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Repositories are the implementations under test, sharing the same store.
+// The repositories a store does not implement are nil, and their tests
+// skipped.
+type Repositories struct {
+	Events      models.EventRepository
+	Users       models.UserRepository
+	RSVPs       models.RSVPRepository
+	Reminders   models.ReminderRepository
+	Webhooks    models.WebhookRepository
+	Attachments models.AttachmentRepository
+	Venues      models.VenueRepository
+	Audit       models.AuditRepository
+	APITokens   models.APITokenRepository
+	Exports     models.ExportRepository
+}
+
+// Opener returns repositories over a new and empty store.
+type Opener func(t *testing.T) Repositories
+
+// Run checks that the repositories returned by open behave as the models
+// package documents. Every test gets repositories over a new store, so an
+// implementation passes when, for example:
+//
+//	func TestRepositories(t *testing.T) {
+//		modeltest.Run(t, modeltest.SQLite)
+//	}
+func Run(t *testing.T, open Opener) {
+	t.Run("Events", func(t *testing.T) { runEvents(t, open) })
+	t.Run("Users", func(t *testing.T) { runUsers(t, open) })
+	t.Run("RSVPs", func(t *testing.T) { runRSVPs(t, open) })
+	t.Run("Reminders", func(t *testing.T) { runReminders(t, open) })
+	t.Run("Webhooks", func(t *testing.T) { runWebhooks(t, open) })
+	t.Run("Attachments", func(t *testing.T) { runAttachments(t, open) })
+	t.Run("Venues", func(t *testing.T) { runVenues(t, open) })
+	t.Run("Audit", func(t *testing.T) { runAudit(t, open) })
+	t.Run("APITokens", func(t *testing.T) { runAPITokens(t, open) })
+	t.Run("Exports", func(t *testing.T) { runExports(t, open) })
+}
+
+// need skips the test when the store does not implement the repository.
+func need(t *testing.T, repository any) {
+	t.Helper()
+
+	if repository == nil {
+		t.Skip("the store does not implement the repository")
+	}
+}
+
+// Memory returns the repositories of a new in-memory store, which only
+// implements the events and users.
+func Memory(t *testing.T) Repositories {
+	store := memory.NewStore()
+	return Repositories{Events: store.Events(), Users: store.Users()}
+}
+
+// SQLite returns the repositories of a new SQLite database in a temporary
+// directory, with every migration applied.
+func SQLite(t *testing.T) Repositories {
+	db := OpenDB(t)
+	return Repositories{
+		Events:      &models.EventModel{DB: db},
+		Users:       &models.UserModel{DB: db},
+		RSVPs:       &models.RSVPModel{DB: db},
+		Reminders:   &models.ReminderModel{DB: db},
+		Webhooks:    &models.WebhookModel{DB: db},
+		Attachments: &models.AttachmentModel{DB: db},
+		Venues:      &models.VenueModel{DB: db},
+		Audit:       &models.AuditModel{DB: db},
+		APITokens:   &models.APITokenModel{DB: db},
+		Exports:     &models.ExportModel{DB: db},
+	}
+}
+
+// OpenDB creates an SQLite database in a temporary directory and applies the
+// up migrations to it. The database is closed when the test ends.
+func OpenDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.db")
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrate(t, db, "migrations/*.sql")
+	return db
+}
+
+// PostgresEnv is the environment variable holding the URL of the PostgreSQL
+// server used by Postgres, such as postgres://postgres@localhost/events_test.
+const PostgresEnv = "POSTGRES_TEST_DSN"
+
+// Postgres returns the PostgreSQL repositories of a new schema, with every
+// PostgreSQL migration applied, in the database of PostgresEnv. The test is
+// skipped when the variable is unset or no PostgreSQL driver is registered.
+func Postgres(t *testing.T) Repositories {
+	db := OpenPostgres(t)
+	return Repositories{
+		Events:      &models.PostgresEventModel{DB: db},
+		Users:       &models.PostgresUserModel{DB: db},
+		RSVPs:       &models.PostgresRSVPModel{DB: db},
+		Reminders:   &models.PostgresReminderModel{DB: db},
+		Webhooks:    &models.PostgresWebhookModel{DB: db},
+		Attachments: &models.PostgresAttachmentModel{DB: db},
+		Venues:      &models.PostgresVenueModel{DB: db},
+		Audit:       &models.PostgresAuditModel{DB: db},
+		APITokens:   &models.PostgresAPITokenModel{DB: db},
+		Exports:     &models.PostgresExportModel{DB: db},
+	}
+}
+
+// OpenPostgres creates a schema in the database of PostgresEnv, applies the
+// up migrations to it and returns a connection using it. The schema is
+// dropped when the test ends.
+func OpenPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresEnv)
+	}
+
+	driver := ""
+	for _, name := range []string{"pgx", "postgres"} {
+		if slices.Contains(sql.Drivers(), name) {
+			driver = name
+			break
+		}
+	}
+	if driver == "" {
+		t.Skip("no PostgreSQL driver is registered")
+	}
+
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "modeltest_" + hex.EncodeToString(b)
+
+	admin, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if err != nil {
+			t.Errorf("dropping the schema %s: %v", schema, err)
+		}
+	})
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open(driver, dsn+separator+"search_path="+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrate(t, db, "migrations/postgres/*.sql")
+	return db
+}
+
+// migrate applies the up section of the migrations matching the pattern in
+// database.Migrations.
+func migrate(t *testing.T, db *sql.DB, pattern string) {
+	t.Helper()
+
+	names, err := fs.Glob(database.Migrations, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		migration, err := fs.ReadFile(database.Migrations, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = db.Exec(upSection(string(migration)))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+// upSection returns the statements of the up section of a goose migration.
+func upSection(migration string) string {
+	_, up, _ := strings.Cut(migration, "-- +goose Up")
+	up, _, _ = strings.Cut(up, "-- +goose Down")
+	return up
+}
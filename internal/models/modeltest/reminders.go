@@ -0,0 +1,154 @@
+package modeltest
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+func runReminders(t *testing.T, open Opener) {
+	ctx := context.Background()
+	now := time.Date(2026, 11, 5, 12, 0, 0, 0, time.UTC)
+	lease := 5 * time.Minute
+
+	// setup creates an event starting two hours after now, attended by a
+	// user, and returns the repositories.
+	setup := func(t *testing.T) Repositories {
+		t.Helper()
+
+		r := open(t)
+		need(t, r.RSVPs)
+		need(t, r.Reminders)
+		userID := newUser(t, r.Users, "ada@example.test")
+		eventID := newEvent(t, r.Events, userID, "Workshop", now.Add(2*time.Hour), time.Time{})
+
+		err := r.RSVPs.Create(ctx, eventID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// due returns the reminders due at the time for the offset.
+	due := func(t *testing.T, r Repositories, offset time.Duration, at time.Time) []models.Reminder {
+		t.Helper()
+
+		reminders, err := r.Reminders.Due(ctx, offset, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reminders
+	}
+
+	// claim claims the reminder at the time, skipping the offsets.
+	claim := func(t *testing.T, r Repositories, rm models.Reminder, at time.Time, skipped ...time.Duration) bool {
+		t.Helper()
+
+		ok, err := r.Reminders.Claim(ctx, rm, skipped, at, lease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	t.Run("Due", func(t *testing.T) {
+		r := setup(t)
+
+		reminders := due(t, r, 3*time.Hour, now)
+		if len(reminders) != 1 {
+			t.Fatalf("got %d reminders, want 1", len(reminders))
+		}
+		rm := reminders[0]
+		if rm.EventTitle != "Workshop" || rm.UserEmail != "ada@example.test" || rm.Offset != 3*time.Hour {
+			t.Errorf("got %+v", rm)
+		}
+		if !rm.EventDate.Equal(now.Add(2 * time.Hour)) {
+			t.Errorf("got event date %v, want %v", rm.EventDate, now.Add(2*time.Hour))
+		}
+
+		// The window of the offset has not opened yet.
+		if got := due(t, r, time.Hour, now); len(got) != 0 {
+			t.Errorf("got %d reminders an hour before, want none", len(got))
+		}
+		// The event has started.
+		if got := due(t, r, 3*time.Hour, now.Add(3*time.Hour)); len(got) != 0 {
+			t.Errorf("got %d reminders after the start, want none", len(got))
+		}
+	})
+
+	t.Run("Claim", func(t *testing.T) {
+		r := setup(t)
+		rm := due(t, r, 3*time.Hour, now)[0]
+
+		if !claim(t, r, rm, now) {
+			t.Fatal("the first claim failed")
+		}
+		if claim(t, r, rm, now.Add(time.Minute)) {
+			t.Error("got the reminder claimed twice under the lease")
+		}
+		if got := due(t, r, 3*time.Hour, now.Add(time.Minute)); len(got) != 0 {
+			t.Errorf("got %d reminders under the lease, want none", len(got))
+		}
+
+		// The claim of a scheduler which stopped expires.
+		later := now.Add(lease + time.Minute)
+		if got := due(t, r, 3*time.Hour, later); len(got) != 1 {
+			t.Fatalf("got %d reminders after the lease, want 1", len(got))
+		}
+		if !claim(t, r, rm, later) {
+			t.Fatal("the expired claim was not taken over")
+		}
+
+		err := r.Reminders.MarkSent(ctx, rm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		muchLater := later.Add(lease + time.Minute)
+		if got := due(t, r, 3*time.Hour, muchLater); len(got) != 0 {
+			t.Errorf("got %d reminders once sent, want none", len(got))
+		}
+		if claim(t, r, rm, muchLater) {
+			t.Error("got the sent reminder claimed")
+		}
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		r := setup(t)
+		rm := due(t, r, 3*time.Hour, now)[0]
+
+		if !claim(t, r, rm, now) {
+			t.Fatal("the first claim failed")
+		}
+		err := r.Reminders.Release(ctx, rm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := due(t, r, 3*time.Hour, now); len(got) != 1 {
+			t.Errorf("got %d reminders once released, want 1", len(got))
+		}
+		if !claim(t, r, rm, now) {
+			t.Error("the released reminder could not be claimed again")
+		}
+	})
+
+	t.Run("Skipped", func(t *testing.T) {
+		r := setup(t)
+		rm := due(t, r, 3*time.Hour, now)[0]
+
+		// The reminder of 24 hours was missed, and is skipped.
+		if !claim(t, r, rm, now, 24*time.Hour) {
+			t.Fatal("the claim failed")
+		}
+
+		if got := due(t, r, 24*time.Hour, now); len(got) != 0 {
+			t.Errorf("got %d skipped reminders due, want none", len(got))
+		}
+		skipped := rm
+		skipped.Offset = 24 * time.Hour
+		if claim(t, r, skipped, now.Add(lease+time.Minute)) {
+			t.Error("got the skipped reminder claimed")
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package modeltest
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+func runRSVPs(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("CreateAndDelete", func(t *testing.T) {
+		r := open(t)
+		need(t, r.RSVPs)
+		userID := newUser(t, r.Users, "ada@example.test")
+		otherID := newUser(t, r.Users, "grace@example.test")
+		eventID := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+
+		for _, id := range []int{userID, userID, otherID} {
+			err := r.RSVPs.Create(ctx, eventID, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		count, err := r.RSVPs.Count(ctx, eventID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Errorf("got %d attendees, want 2; creating an RSVP twice records it once", count)
+		}
+
+		err = r.RSVPs.Delete(ctx, eventID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Deleting a missing RSVP is not an error.
+		err = r.RSVPs.Delete(ctx, eventID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exists, err := r.RSVPs.Exists(ctx, eventID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Error("got the deleted RSVP")
+		}
+		exists, err = r.RSVPs.Exists(ctx, eventID, otherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Error("the RSVP of the other user is missing")
+		}
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		r := open(t)
+		need(t, r.RSVPs)
+		need(t, r.Webhooks)
+		userID := newUser(t, r.Users, "ada@example.test")
+		eventID := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+
+		subscriptionID, err := r.Webhooks.CreateSubscription(ctx, "https://example.test/hook", "secret",
+			[]string{models.WebhookRSVPCreated, models.WebhookRSVPDeleted})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the changes queue deliveries.
+		for range 2 {
+			err = r.RSVPs.Create(ctx, eventID, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.RSVPs.Delete(ctx, eventID, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = r.RSVPs.Delete(ctx, eventID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		deliveries, err := r.Webhooks.ListDeliveries(ctx, subscriptionID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []string
+		for _, d := range deliveries {
+			types = append(types, d.EventType)
+		}
+		want := []string{models.WebhookRSVPDeleted, models.WebhookRSVPCreated, models.WebhookRSVPDeleted, models.WebhookRSVPCreated}
+		if len(types) != len(want) {
+			t.Fatalf("got deliveries %q, want %q", types, want)
+		}
+		for i := range want {
+			if types[i] != want[i] {
+				t.Fatalf("got deliveries %q, want %q", types, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,388 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+func runUsers(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("CreateAndRetrieve", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+
+		u, err := r.Users.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID != id || u.Name != "Test User" || u.Email != "ada@example.test" || u.Role != models.RoleUser {
+			t.Errorf("got %+v", u)
+		}
+		if !u.HasPassword || u.TOTPEnabled || u.Disabled || u.CreatedAt.IsZero() {
+			t.Errorf("got %+v", u)
+		}
+
+		_, err = r.Users.Retrieve(ctx, id+1)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving a missing user: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		r := open(t)
+		newUser(t, r.Users, "ada@example.test")
+
+		err := r.Users.Create(ctx, "Other", "ada@example.test", "password456")
+		if !errors.Is(err, models.ErrDuplicateEmail) {
+			t.Errorf("got %v, want ErrDuplicateEmail", err)
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		r := open(t)
+
+		for i, email := range []string{"", "ada@example.test", "grace@example.test"} {
+			if email != "" {
+				newUser(t, r.Users, email)
+			}
+
+			n, err := r.Users.Count(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != i {
+				t.Errorf("got %d users, want %d", n, i)
+			}
+		}
+	})
+
+	t.Run("Authenticate", func(t *testing.T) {
+		r := open(t)
+		newUser(t, r.Users, "ada@example.test")
+
+		_, err := r.Users.Authenticate(ctx, "ada@example.test", "wrong password")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("wrong password: got %v, want ErrInvalidCredentials", err)
+		}
+		_, err = r.Users.Authenticate(ctx, "nobody@example.test", "password123")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("unknown email: got %v, want ErrInvalidCredentials", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+		newUser(t, r.Users, "alan@example.test")
+
+		err := r.Users.Update(ctx, models.User{ID: id, Name: "Ada", Email: "ada@example.org"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := r.Users.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Name != "Ada" || u.Email != "ada@example.org" {
+			t.Errorf("got %q <%s>", u.Name, u.Email)
+		}
+
+		err = r.Users.Update(ctx, models.User{ID: id, Name: "Ada", Email: "alan@example.test"})
+		if !errors.Is(err, models.ErrDuplicateEmail) {
+			t.Errorf("taking another email: got %v, want ErrDuplicateEmail", err)
+		}
+		err = r.Users.Update(ctx, models.User{ID: id + 2, Name: "Nobody", Email: "nobody@example.test"})
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("updating a missing user: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("UpdatePassword", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+
+		err := r.Users.UpdatePassword(ctx, id, "new password")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Users.Authenticate(ctx, "ada@example.test", "password123")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("old password: got %v, want ErrInvalidCredentials", err)
+		}
+		got, err := r.Users.Authenticate(ctx, "ada@example.test", "new password")
+		if err != nil || got != id {
+			t.Errorf("new password: got %d, %v; want %d", got, err, id)
+		}
+	})
+
+	t.Run("UpdateLocale", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+
+		u, err := r.Users.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Locale != "" {
+			t.Errorf("new user: got locale %q, want none", u.Locale)
+		}
+
+		err = r.Users.UpdateLocale(ctx, id, "ro")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err = r.Users.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Locale != "ro" {
+			t.Errorf("got locale %q, want ro", u.Locale)
+		}
+
+		err = r.Users.UpdateLocale(ctx, id+1, "ro")
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("updating a missing user: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+		other := newUser(t, r.Users, "alan@example.test")
+		owned := newEvent(t, r.Events, id, "Owned", date(2026, 11, 5), time.Time{})
+		kept := newEvent(t, r.Events, other, "Kept", date(2026, 11, 5), time.Time{})
+
+		_, err := r.Users.Delete(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Users.Retrieve(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving the deleted user: got %v, want ErrNoRecord", err)
+		}
+		_, err = r.Users.Authenticate(ctx, "ada@example.test", "password123")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("logging in as the deleted user: got %v, want ErrInvalidCredentials", err)
+		}
+		_, err = r.Events.Retrieve(ctx, owned)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving an event nobody attends: got %v, want ErrNoRecord", err)
+		}
+		_, err = r.Events.Retrieve(ctx, kept)
+		if err != nil {
+			t.Errorf("retrieving the event of another user: %v", err)
+		}
+
+		_, err = r.Users.Delete(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("deleting a missing user: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Lockout", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+
+		got, locked, err := r.Users.RecordLoginFailure(ctx, "nobody@example.test", 2, time.Hour)
+		if err != nil || got != 0 || locked {
+			t.Errorf("unknown email: got %d, %t, %v; want 0, false", got, locked, err)
+		}
+
+		for i, want := range []bool{false, true} {
+			got, locked, err := r.Users.RecordLoginFailure(ctx, "ada@example.test", 2, time.Hour)
+			if err != nil || got != id || locked != want {
+				t.Errorf("failure %d: got %d, %t, %v; want %d, %t", i+1, got, locked, err, id, want)
+			}
+		}
+
+		locked, err = r.Users.IsLocked(ctx, "ada@example.test")
+		if err != nil || !locked {
+			t.Errorf("after the failures: got %t, %v; want locked", locked, err)
+		}
+
+		err = r.Users.ResetLoginFailures(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		locked, err = r.Users.IsLocked(ctx, "ada@example.test")
+		if err != nil || locked {
+			t.Errorf("after the reset: got %t, %v; want unlocked", locked, err)
+		}
+
+		locked, err = r.Users.IsLocked(ctx, "nobody@example.test")
+		if err != nil || locked {
+			t.Errorf("unknown email: got %t, %v; want unlocked", locked, err)
+		}
+	})
+
+	t.Run("EmailChange", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+		newUser(t, r.Users, "alan@example.test")
+		expiresAt := time.Now().Add(time.Hour)
+
+		_, err := r.Users.RequestEmailChange(ctx, id, "alan@example.test", expiresAt)
+		if !errors.Is(err, models.ErrDuplicateEmail) {
+			t.Errorf("requesting a taken email: got %v, want ErrDuplicateEmail", err)
+		}
+
+		replaced, err := r.Users.RequestEmailChange(ctx, id, "ada@example.net", expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := r.Users.RequestEmailChange(ctx, id, "ada@example.org", expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, _, err = r.Users.ConfirmEmailChange(ctx, replaced)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("confirming a replaced change: got %v, want ErrNoRecord", err)
+		}
+
+		userID, oldEmail, newEmail, err := r.Users.ConfirmEmailChange(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userID != id || oldEmail != "ada@example.test" || newEmail != "ada@example.org" {
+			t.Errorf("got %d, %q, %q", userID, oldEmail, newEmail)
+		}
+
+		u, err := r.Users.Retrieve(ctx, id)
+		if err != nil || u.Email != "ada@example.org" {
+			t.Errorf("after the change: got %q, %v", u.Email, err)
+		}
+
+		_, _, _, err = r.Users.ConfirmEmailChange(ctx, token)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("confirming twice: got %v, want ErrNoRecord", err)
+		}
+
+		expired, err := r.Users.RequestEmailChange(ctx, id, "ada@example.com", time.Now().Add(-time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = r.Users.ConfirmEmailChange(ctx, expired)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("confirming an expired change: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Identities", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+		const issuer = "https://idp.example.test"
+
+		_, err := r.Users.RetrieveByIdentity(ctx, issuer, "ada")
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("unknown identity: got %v, want ErrNoRecord", err)
+		}
+
+		_, err = r.Users.LinkIdentity(ctx, "nobody@example.test", issuer, "nobody")
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("linking an unknown email: got %v, want ErrNoRecord", err)
+		}
+
+		got, err := r.Users.LinkIdentity(ctx, "ada@example.test", issuer, "ada")
+		if err != nil || got != id {
+			t.Errorf("linking: got %d, %v; want %d", got, err, id)
+		}
+		got, err = r.Users.RetrieveByIdentity(ctx, issuer, "ada")
+		if err != nil || got != id {
+			t.Errorf("linked identity: got %d, %v; want %d", got, err, id)
+		}
+
+		_, err = r.Users.CreateFromIdentity(ctx, "Ada", "ada@example.test", issuer, "other")
+		if !errors.Is(err, models.ErrDuplicateEmail) {
+			t.Errorf("provisioning a taken email: got %v, want ErrDuplicateEmail", err)
+		}
+
+		provisioned, err := r.Users.CreateFromIdentity(ctx, "Alan", "alan@example.test", issuer, "alan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = r.Users.RetrieveByIdentity(ctx, issuer, "alan")
+		if err != nil || got != provisioned {
+			t.Errorf("provisioned identity: got %d, %v; want %d", got, err, provisioned)
+		}
+
+		u, err := r.Users.Retrieve(ctx, provisioned)
+		if err != nil || u.HasPassword || u.Name != "Alan" {
+			t.Errorf("provisioned user: got %+v, %v", u, err)
+		}
+		_, err = r.Users.Authenticate(ctx, "alan@example.test", "")
+		if !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Errorf("password login of a provisioned user: got %v, want ErrInvalidCredentials", err)
+		}
+	})
+
+	t.Run("TwoFactor", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r.Users, "ada@example.test")
+
+		err := r.Users.EnableTOTP(ctx, id, "SECRET", []string{"code-a", "code-b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		secret, err := r.Users.TOTPSecret(ctx, id)
+		if err != nil || secret != "SECRET" {
+			t.Errorf("got secret %q, %v", secret, err)
+		}
+		u, err := r.Users.Retrieve(ctx, id)
+		if err != nil || !u.TOTPEnabled {
+			t.Errorf("got TOTPEnabled %t, %v", u.TOTPEnabled, err)
+		}
+
+		for _, step := range []struct {
+			step int64
+			want bool
+		}{{5, true}, {5, false}, {4, false}, {6, true}} {
+			ok, err := r.Users.UseTOTPStep(ctx, id, step.step)
+			if err != nil || ok != step.want {
+				t.Errorf("step %d: got %t, %v; want %t", step.step, ok, err, step.want)
+			}
+		}
+
+		for _, code := range []struct {
+			code string
+			want bool
+		}{{"code-a", true}, {"code-a", false}, {"unknown", false}} {
+			ok, err := r.Users.UseRecoveryCode(ctx, id, code.code)
+			if err != nil || ok != code.want {
+				t.Errorf("recovery code %q: got %t, %v; want %t", code.code, ok, err, code.want)
+			}
+		}
+
+		left, err := r.Users.RecoveryCodesLeft(ctx, id)
+		if err != nil || left != 1 {
+			t.Errorf("got %d recovery codes left, %v; want 1", left, err)
+		}
+
+		err = r.Users.DisableTOTP(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		secret, err = r.Users.TOTPSecret(ctx, id)
+		if err != nil || secret != "" {
+			t.Errorf("after disabling: got secret %q, %v", secret, err)
+		}
+		left, err = r.Users.RecoveryCodesLeft(ctx, id)
+		if err != nil || left != 0 {
+			t.Errorf("after disabling: got %d recovery codes left, %v", left, err)
+		}
+
+		err = r.Users.EnableTOTP(ctx, id+1, "SECRET", nil)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("enabling for a missing user: got %v, want ErrNoRecord", err)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func runVenues(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("CreateAndRetrieve", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Venues)
+
+		id, err := r.Venues.Create(ctx, models.Venue{
+			Name:        "Grand Hall",
+			Address:     "1 Main Street",
+			Capacity:    300,
+			Coordinates: &models.Coordinates{Latitude: 44.4268, Longitude: 26.1025},
+			Amenities:   []string{"wifi", "parking"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := r.Venues.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.ID != id || v.Name != "Grand Hall" || v.Address != "1 Main Street" || v.Capacity != 300 || v.CreatedAt.IsZero() {
+			t.Errorf("got %+v", v)
+		}
+		if v.Coordinates == nil || *v.Coordinates != (models.Coordinates{Latitude: 44.4268, Longitude: 26.1025}) {
+			t.Errorf("got coordinates %+v", v.Coordinates)
+		}
+		if !slices.Equal(v.Amenities, []string{"wifi", "parking"}) {
+			t.Errorf("got amenities %q", v.Amenities)
+		}
+
+		// The position and amenities are optional.
+		id, err = r.Venues.Create(ctx, models.Venue{Name: "Small Room"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err = r.Venues.Retrieve(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Coordinates != nil || v.Amenities != nil {
+			t.Errorf("got %+v", v)
+		}
+
+		_, err = r.Venues.Retrieve(ctx, id+1)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving a missing venue: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("DuplicateName", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Venues)
+
+		_, err := r.Venues.Create(ctx, models.Venue{Name: "Grand Hall"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := r.Venues.Create(ctx, models.Venue{Name: "Small Room"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Venues.Create(ctx, models.Venue{Name: "Grand Hall"})
+		if !errors.Is(err, models.ErrDuplicateVenue) {
+			t.Errorf("creating: got %v, want ErrDuplicateVenue", err)
+		}
+		err = r.Venues.Update(ctx, models.Venue{ID: id, Name: "Grand Hall"})
+		if !errors.Is(err, models.ErrDuplicateVenue) {
+			t.Errorf("updating: got %v, want ErrDuplicateVenue", err)
+		}
+	})
+
+	t.Run("UpdateListDelete", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Venues)
+
+		var ids []int
+		for _, name := range []string{"hall", "Attic", "Garden"} {
+			id, err := r.Venues.Create(ctx, models.Venue{Name: name})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+
+		err := r.Venues.Update(ctx, models.Venue{ID: ids[0], Name: "Hall", Capacity: 50, Amenities: []string{"stage"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Venues.Update(ctx, models.Venue{ID: ids[2] + 1, Name: "Missing"})
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("updating a missing venue: got %v, want ErrNoRecord", err)
+		}
+
+		// The venues are ordered by name, ignoring the case.
+		venues, err := r.Venues.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, v := range venues {
+			names = append(names, v.Name)
+		}
+		if !slices.Equal(names, []string{"Attic", "Garden", "Hall"}) {
+			t.Errorf("got venues %q", names)
+		}
+		if venues[2].Capacity != 50 || !slices.Equal(venues[2].Amenities, []string{"stage"}) {
+			t.Errorf("got %+v", venues[2])
+		}
+
+		err = r.Venues.Delete(ctx, ids[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Venues.Delete(ctx, ids[1])
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("deleting the deleted venue: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Conflicts", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Venues)
+		userID := newUser(t, r.Users, "ada@example.test")
+
+		venueID, err := r.Venues.Create(ctx, models.Venue{Name: "Grand Hall"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Date(2026, 11, 5, 10, 0, 0, 0, time.UTC)
+		_, err = r.Events.Create(ctx, userID, "Workshop", "", start, start.Add(2*time.Hour), "Grand Hall", venueID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name       string
+			start, end time.Time
+			want       error
+		}{
+			{name: "Overlapping", start: start.Add(time.Hour), end: start.Add(3 * time.Hour), want: models.ErrVenueConflict},
+			{name: "Same day", start: date(2026, 11, 5), end: time.Time{}, want: models.ErrVenueConflict},
+			{name: "Starting at the end", start: start.Add(2 * time.Hour), end: start.Add(4 * time.Hour)},
+			{name: "Next day", start: date(2026, 11, 6), end: time.Time{}},
+		}
+
+		for _, tt := range tests {
+			_, err := r.Events.Create(ctx, userID, tt.name, "", tt.start, tt.end, "Grand Hall", venueID)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+			}
+		}
+
+		// Deleting the venue unlinks its events.
+		err = r.Venues.Delete(ctx, venueID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package modeltest
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"testing"
+	"time"
+)
+
+func runWebhooks(t *testing.T, open Opener) {
+	ctx := context.Background()
+
+	t.Run("Subscriptions", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Webhooks)
+
+		id, err := r.Webhooks.CreateSubscription(ctx, "https://example.test/hook", "secret",
+			[]string{models.WebhookEventCreated, models.WebhookEventDeleted})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := r.Webhooks.RetrieveSubscription(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != id || s.URL != "https://example.test/hook" || s.Secret != "secret" || s.CreatedAt.IsZero() {
+			t.Errorf("got %+v", s)
+		}
+		if len(s.EventTypes) != 2 || s.EventTypes[0] != models.WebhookEventCreated || s.EventTypes[1] != models.WebhookEventDeleted {
+			t.Errorf("got event types %q", s.EventTypes)
+		}
+
+		otherID, err := r.Webhooks.CreateSubscription(ctx, "https://example.test/other", "other", []string{models.WebhookRSVPCreated})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subscriptions, err := r.Webhooks.ListSubscriptions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subscriptions) != 2 || subscriptions[0].ID != id || subscriptions[1].ID != otherID {
+			t.Errorf("got subscriptions %+v, want %d and %d", subscriptions, id, otherID)
+		}
+
+		err = r.Webhooks.DeleteSubscription(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.Webhooks.RetrieveSubscription(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("retrieving the deleted subscription: got %v, want ErrNoRecord", err)
+		}
+		err = r.Webhooks.DeleteSubscription(ctx, id)
+		if !errors.Is(err, models.ErrNoRecord) {
+			t.Errorf("deleting the deleted subscription: got %v, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Deliveries", func(t *testing.T) {
+		r := open(t)
+		need(t, r.Webhooks)
+		userID := newUser(t, r.Users, "ada@example.test")
+
+		id, err := r.Webhooks.CreateSubscription(ctx, "https://example.test/hook", "secret", []string{models.WebhookEventCreated})
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherID, err := r.Webhooks.CreateSubscription(ctx, "https://example.test/other", "other", []string{models.WebhookEventDeleted})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the subscriptions to the event type get a delivery.
+		eventID := newEvent(t, r.Events, userID, "Workshop", date(2026, 11, 5), time.Time{})
+
+		deliveries, err := r.Webhooks.ListDeliveries(ctx, otherID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 0 {
+			t.Errorf("got %d deliveries for the other subscription, want none", len(deliveries))
+		}
+
+		// The deliveries are queued at the current time.
+		now := time.Now().Add(time.Minute)
+
+		due, err := r.Webhooks.Due(ctx, now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 {
+			t.Fatalf("got %d deliveries due, want 1", len(due))
+		}
+		d := due[0]
+		if d.SubscriptionID != id || d.URL != "https://example.test/hook" || d.Secret != "secret" ||
+			d.EventType != models.WebhookEventCreated || d.Status != models.DeliveryPending || d.Attempts != 0 {
+			t.Errorf("got %+v", d)
+		}
+		if d.Payload == "" || d.NextAttemptAt.IsZero() || d.CreatedAt.IsZero() {
+			t.Errorf("got %+v", d)
+		}
+
+		ok, err := r.Webhooks.Claim(ctx, d.ID, now, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("the first claim failed")
+		}
+		ok, err = r.Webhooks.Claim(ctx, d.ID, now, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("got the delivery claimed twice")
+		}
+		due, err = r.Webhooks.Due(ctx, now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 0 {
+			t.Errorf("got %d deliveries due while claimed, want none", len(due))
+		}
+
+		err = r.Webhooks.RecordAttempt(ctx, models.WebhookAttempt{
+			DeliveryID: d.ID,
+			StatusCode: 500,
+			Error:      "server error",
+			Duration:   250 * time.Millisecond,
+		}, models.DeliveryFailed, now.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The failed delivery is retried at its next attempt.
+		due, err = r.Webhooks.Due(ctx, now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 0 {
+			t.Errorf("got %d deliveries due before the retry, want none", len(due))
+		}
+		due, err = r.Webhooks.Due(ctx, now.Add(2*time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 || due[0].Status != models.DeliveryFailed || due[0].Attempts != 1 {
+			t.Errorf("got deliveries %+v, want the failed one", due)
+		}
+
+		attempts, err := r.Webhooks.ListAttempts(ctx, id, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != 1 {
+			t.Fatalf("got %d attempts, want 1", len(attempts))
+		}
+		a := attempts[0]
+		if a.DeliveryID != d.ID || a.EventType != models.WebhookEventCreated || a.StatusCode != 500 ||
+			a.Error != "server error" || a.Duration != 250*time.Millisecond || a.AttemptedAt.IsZero() {
+			t.Errorf("got %+v", a)
+		}
+
+		err = r.Webhooks.RecordAttempt(ctx, models.WebhookAttempt{DeliveryID: d.ID, StatusCode: 200}, models.DeliverySucceeded, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		due, err = r.Webhooks.Due(ctx, now.Add(2*time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 0 {
+			t.Errorf("got %d deliveries due once succeeded, want none", len(due))
+		}
+
+		// Deleting the event queues a delivery to the other subscription.
+		err = r.Events.Delete(ctx, eventID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deliveries, err = r.Webhooks.ListDeliveries(ctx, otherID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 1 || deliveries[0].EventType != models.WebhookEventDeleted {
+			t.Errorf("got deliveries %+v, want one of %s", deliveries, models.WebhookEventDeleted)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is the bcrypt cost of the stored passwords.
+const passwordCost = 12
+
+// tracer records the spans of the password hashing, which is slow enough by
+// design to show up in the traces of the requests.
+var tracer = otel.Tracer("github.com/madalinpopa/go-event-planner/internal/models")
+
+// hashPassword returns the bcrypt hash of the password.
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return hashedPassword, err
+}
+
+// comparePassword checks the password against its bcrypt hash, and returns
+// ErrInvalidCredentials when they do not match.
+func comparePassword(ctx context.Context, hashedPassword []byte, password string) error {
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
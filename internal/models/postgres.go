@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// pgUniqueViolation is the SQLSTATE code of PostgreSQL for a violated unique
+// constraint.
+const pgUniqueViolation = "23505"
+
+// isPostgresUniqueViolation reports whether the error of a PostgreSQL driver
+// is a violated unique constraint, the equivalent of the sqlite3
+// ErrConstraintUnique. The pgx and lib/pq errors both expose their SQLSTATE
+// code, so that no driver has to be imported here.
+func isPostgresUniqueViolation(err error) bool {
+	var pgError interface{ SQLState() string }
+	return errors.As(err, &pgError) && pgError.SQLState() == pgUniqueViolation
+}
+
+// enqueueWebhookPostgres is the PostgreSQL version of enqueueWebhook.
+func enqueueWebhookPostgres(ctx context.Context, tx *sql.Tx, eventType string, data any) error {
+	payload, err := newWebhookPayload(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+	SELECT id, $1, $2 FROM webhook_subscriptions
+	WHERE ',' || event_types || ',' LIKE '%,' || $1 || ',%'`
+
+	_, err = tx.ExecContext(ctx, stmt, eventType, payload)
+	return err
+}
+
+// checkVenueConflictPostgres is the PostgreSQL version of checkVenueConflict.
+func checkVenueConflictPostgres(ctx context.Context, tx *sql.Tx, venueID, eventID int, e Event) error {
+	if venueID == 0 {
+		return nil
+	}
+
+	stmt := `SELECT EXISTS(SELECT 1 FROM events
+	WHERE venue_id = $1 AND id != $2
+	  AND event_date < $3
+	  AND CASE
+	      WHEN (COALESCE(end_date, event_date) AT TIME ZONE 'UTC')::time = '00:00' THEN COALESCE(end_date, event_date) + interval '1 day'
+	      WHEN end_date IS NULL OR end_date <= event_date THEN event_date + interval '1 day'
+	      ELSE end_date
+	  END > $4)`
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, stmt, venueID, eventID, e.End(), e.EventDate.UTC()).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVenueConflict
+	}
+	return nil
+}
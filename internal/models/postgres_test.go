@@ -0,0 +1,16 @@
+//go:build postgres
+
+package models_test
+
+import (
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// TestPostgresRepositories runs against the database of POSTGRES_TEST_DSN, and
+// is skipped when the variable is not set.
+func TestPostgresRepositories(t *testing.T) {
+	modeltest.Run(t, modeltest.Postgres)
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PostgresReminderModel is the PostgreSQL version of ReminderModel.
+type PostgresReminderModel struct {
+	DB *sql.DB
+}
+
+// Due returns the reminders for the given offset which are due at the time
+// now and have not been sent, skipped or claimed under a lease which has not
+// expired yet. A reminder is due once now has passed EventDate minus offset,
+// for as long as the event has not started.
+func (m *PostgresReminderModel) Due(ctx context.Context, offset time.Duration, now time.Time) ([]Reminder, error) {
+	stmt := `SELECT e.id, e.title, e.event_date, e.location, u.id, u.name, u.email
+	FROM rsvps r
+	JOIN events e ON e.id = r.event_id
+	JOIN users u ON u.id = r.user_id
+	WHERE e.event_date > $1
+	  AND e.event_date <= $2
+	  AND NOT EXISTS (
+	      SELECT 1 FROM reminders rm
+	      WHERE rm.event_id = r.event_id AND rm.user_id = r.user_id AND rm.offset_sec = $3
+	        AND (rm.status <> 'pending' OR rm.locked_until > $1)
+	  )`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, now, now.Add(offset), int64(offset/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var reminders []Reminder
+	for rows.Next() {
+		rm := Reminder{Offset: offset}
+		err := rows.Scan(&rm.EventID, &rm.EventTitle, &rm.EventDate, &rm.Location, &rm.UserID, &rm.UserName, &rm.UserEmail)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, rm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// Claim atomically reserves the reminder for the caller until now plus lease.
+// It returns false when the reminder was already sent or skipped, or is
+// claimed by this or any other process sharing the database under a lease
+// which has not expired, in which case it must not be sent.
+//
+// The reminders at the offsets in skipped are recorded as skipped in the same
+// transaction, so that they are never sent afterwards.
+func (m *PostgresReminderModel) Claim(ctx context.Context, rm Reminder, skipped []time.Duration, now time.Time, lease time.Duration) (bool, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// An expired claim is taken over, and its lease renewed.
+	stmt := `INSERT INTO reminders (event_id, user_id, offset_sec, locked_until) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (event_id, user_id, offset_sec) DO UPDATE
+	SET locked_until = excluded.locked_until, claimed_at = CURRENT_TIMESTAMP
+	WHERE reminders.status = 'pending'
+	  AND (reminders.locked_until IS NULL OR reminders.locked_until <= $5)`
+
+	result, err := tx.ExecContext(ctx, stmt, rm.EventID, rm.UserID, int64(rm.Offset/time.Second), now.Add(lease), now)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil
+	}
+
+	// Expired claims of the skipped reminders are given up as well.
+	stmt = `INSERT INTO reminders (event_id, user_id, offset_sec, status) VALUES ($1, $2, $3, 'skipped')
+	ON CONFLICT (event_id, user_id, offset_sec) DO UPDATE
+	SET status = 'skipped', locked_until = NULL
+	WHERE reminders.status = 'pending'
+	  AND (reminders.locked_until IS NULL OR reminders.locked_until <= $4)`
+
+	for _, offset := range skipped {
+		_, err = tx.ExecContext(ctx, stmt, rm.EventID, rm.UserID, int64(offset/time.Second), now)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MarkSent records that a previously claimed reminder was delivered.
+func (m *PostgresReminderModel) MarkSent(ctx context.Context, rm Reminder) error {
+	stmt := `UPDATE reminders SET status = 'sent', sent_at = CURRENT_TIMESTAMP
+	WHERE event_id = $1 AND user_id = $2 AND offset_sec = $3`
+
+	_, err := m.DB.ExecContext(ctx, stmt, rm.EventID, rm.UserID, int64(rm.Offset/time.Second))
+	return err
+}
+
+// Release gives up the claim on a reminder that could not be delivered so
+// that it is picked up again on the next scan.
+func (m *PostgresReminderModel) Release(ctx context.Context, rm Reminder) error {
+	stmt := `DELETE FROM reminders WHERE event_id = $1 AND user_id = $2 AND offset_sec = $3 AND status = 'pending'`
+
+	_, err := m.DB.ExecContext(ctx, stmt, rm.EventID, rm.UserID, int64(rm.Offset/time.Second))
+	return err
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// The methods of the repositories take the context of the request they serve,
+// and stop with its error, context.Canceled or context.DeadlineExceeded, once
+// it is done.
+
+// EventRepository stores the events. EventModel implements it with SQLite
+// and PostgresEventModel with PostgreSQL.
+type EventRepository interface {
+	Create(ctx context.Context, userID int, title, description string, eventDate, endDate time.Time, location string, venueID int) (int, error)
+	Update(ctx context.Context, id int, title, description string, eventDate, endDate time.Time, location string, venueID int) error
+	Retrieve(ctx context.Context, id int) (Event, error)
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context) ([]Event, error)
+	Between(ctx context.Context, start, end time.Time) ([]Event, error)
+	CountUpcoming(ctx context.Context, now time.Time) (int, error)
+}
+
+// UserRepository stores the user accounts and their credentials. UserModel
+// implements it with SQLite and PostgresUserModel with PostgreSQL.
+type UserRepository interface {
+	Create(ctx context.Context, name, email, password string) error
+	Retrieve(ctx context.Context, id int) (User, error)
+	Update(ctx context.Context, u User) error
+	UpdatePassword(ctx context.Context, id int, password string) error
+	UpdateLocale(ctx context.Context, id int, locale string) error
+	Delete(ctx context.Context, id int) ([]Attachment, error)
+	Authenticate(ctx context.Context, email, password string) (int, error)
+	Count(ctx context.Context) (int, error)
+
+	IsLocked(ctx context.Context, email string) (bool, error)
+	RecordLoginFailure(ctx context.Context, email string, maxFailures int, lockout time.Duration) (int, bool, error)
+	ResetLoginFailures(ctx context.Context, id int) error
+
+	RequestEmailChange(ctx context.Context, userID int, email string, expiresAt time.Time) (string, error)
+	ConfirmEmailChange(ctx context.Context, token string) (int, string, string, error)
+
+	RetrieveByIdentity(ctx context.Context, issuer, subject string) (int, error)
+	LinkIdentity(ctx context.Context, email, issuer, subject string) (int, error)
+	CreateFromIdentity(ctx context.Context, name, email, issuer, subject string) (int, error)
+
+	TOTPSecret(ctx context.Context, id int) (string, error)
+	EnableTOTP(ctx context.Context, id int, secret string, recoveryCodes []string) error
+	DisableTOTP(ctx context.Context, id int) error
+	UseTOTPStep(ctx context.Context, id int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, id int, code string) (bool, error)
+	RecoveryCodesLeft(ctx context.Context, id int) (int, error)
+}
+
+// SessionRepository stores the authenticated sessions of the users.
+// SessionModel implements it with SQLite and PostgresSessionModel with
+// PostgreSQL.
+type SessionRepository interface {
+	Create(ctx context.Context, token string, userID int, userAgent, ip string, expiresAt time.Time) error
+	Touch(ctx context.Context, token, ip string) (int, error)
+	List(ctx context.Context, userID int, currentToken string) ([]Session, error)
+	Revoke(ctx context.Context, userID, id int) error
+	RevokeToken(ctx context.Context, token string) error
+	RevokeAll(ctx context.Context, userID int, exceptToken string) (int, error)
+	Prune(ctx context.Context) (int, error)
+	CountActive(ctx context.Context) (int, error)
+}
+
+// RSVPRepository stores the attendance of users to events. RSVPModel
+// implements it with SQLite and PostgresRSVPModel with PostgreSQL.
+type RSVPRepository interface {
+	Create(ctx context.Context, eventID, userID int) error
+	Delete(ctx context.Context, eventID, userID int) error
+	Exists(ctx context.Context, eventID, userID int) (bool, error)
+	Count(ctx context.Context, eventID int) (int, error)
+}
+
+// ReminderRepository finds the due reminders and records which were sent.
+// ReminderModel implements it with SQLite and PostgresReminderModel with
+// PostgreSQL.
+type ReminderRepository interface {
+	Due(ctx context.Context, offset time.Duration, now time.Time) ([]Reminder, error)
+	Claim(ctx context.Context, rm Reminder, skipped []time.Duration, now time.Time, lease time.Duration) (bool, error)
+	MarkSent(ctx context.Context, rm Reminder) error
+	Release(ctx context.Context, rm Reminder) error
+}
+
+// WebhookRepository stores the webhook subscriptions and their delivery
+// queue. WebhookModel implements it with SQLite and PostgresWebhookModel with
+// PostgreSQL.
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (int, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	RetrieveSubscription(ctx context.Context, id int) (WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	ListDeliveries(ctx context.Context, subscriptionID, limit int) ([]WebhookDelivery, error)
+	ListAttempts(ctx context.Context, subscriptionID, limit int) ([]WebhookAttempt, error)
+
+	Due(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	Claim(ctx context.Context, id int, now time.Time, lease time.Duration) (bool, error)
+	RecordAttempt(ctx context.Context, a WebhookAttempt, status string, nextAttemptAt time.Time) error
+}
+
+// AttachmentRepository stores the attachments of events, but not their
+// content. AttachmentModel implements it with SQLite and
+// PostgresAttachmentModel with PostgreSQL.
+type AttachmentRepository interface {
+	Create(ctx context.Context, a Attachment) (int, error)
+	Retrieve(ctx context.Context, id int) (Attachment, error)
+	List(ctx context.Context, eventID int) ([]Attachment, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// VenueRepository stores the venues. VenueModel implements it with SQLite
+// and PostgresVenueModel with PostgreSQL.
+type VenueRepository interface {
+	Create(ctx context.Context, v Venue) (int, error)
+	Update(ctx context.Context, v Venue) error
+	Retrieve(ctx context.Context, id int) (Venue, error)
+	List(ctx context.Context) ([]Venue, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// AuditRepository stores the audit events. AuditModel implements it with
+// SQLite and PostgresAuditModel with PostgreSQL.
+type AuditRepository interface {
+	Record(ctx context.Context, userID int, action, ip, detail string) error
+	List(ctx context.Context, limit int) ([]AuditEvent, error)
+}
+
+// APITokenRepository stores the personal API tokens. APITokenModel
+// implements it with SQLite and PostgresAPITokenModel with PostgreSQL.
+type APITokenRepository interface {
+	Create(ctx context.Context, userID int, name string, scopes []string, expiresAt time.Time) (string, error)
+	List(ctx context.Context, userID int) ([]APIToken, error)
+	Revoke(ctx context.Context, userID, id int) error
+	Authenticate(ctx context.Context, token string) (APIToken, error)
+}
+
+// ExportRepository stores the exports of personal data and collects the data
+// they contain. ExportModel implements it with SQLite and PostgresExportModel
+// with PostgreSQL.
+type ExportRepository interface {
+	Request(ctx context.Context, userID int) (int, error)
+	Latest(ctx context.Context, userID int) (Export, error)
+	Claim(ctx context.Context, now time.Time, staleAfter time.Duration) (Export, bool, error)
+	Complete(ctx context.Context, id int, storageKey string, size int64, expiresAt time.Time) (string, error)
+	Fail(ctx context.Context, id int, reason string) error
+	Download(ctx context.Context, userID int, token string) (Export, error)
+	Prune(ctx context.Context, now time.Time, userID int) ([]string, error)
+	Collect(ctx context.Context, userID int) (PersonalData, error)
+}
+
+var (
+	_ EventRepository      = (*EventModel)(nil)
+	_ UserRepository       = (*UserModel)(nil)
+	_ SessionRepository    = (*SessionModel)(nil)
+	_ RSVPRepository       = (*RSVPModel)(nil)
+	_ ReminderRepository   = (*ReminderModel)(nil)
+	_ WebhookRepository    = (*WebhookModel)(nil)
+	_ AttachmentRepository = (*AttachmentModel)(nil)
+	_ VenueRepository      = (*VenueModel)(nil)
+	_ AuditRepository      = (*AuditModel)(nil)
+	_ APITokenRepository   = (*APITokenModel)(nil)
+	_ ExportRepository     = (*ExportModel)(nil)
+
+	_ EventRepository      = (*PostgresEventModel)(nil)
+	_ UserRepository       = (*PostgresUserModel)(nil)
+	_ SessionRepository    = (*PostgresSessionModel)(nil)
+	_ RSVPRepository       = (*PostgresRSVPModel)(nil)
+	_ ReminderRepository   = (*PostgresReminderModel)(nil)
+	_ WebhookRepository    = (*PostgresWebhookModel)(nil)
+	_ AttachmentRepository = (*PostgresAttachmentModel)(nil)
+	_ VenueRepository      = (*PostgresVenueModel)(nil)
+	_ AuditRepository      = (*PostgresAuditModel)(nil)
+	_ APITokenRepository   = (*PostgresAPITokenModel)(nil)
+	_ ExportRepository     = (*PostgresExportModel)(nil)
+)
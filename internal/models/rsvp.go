@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RSVP represents a user's intention to attend an event.
+type RSVP struct {
+	ID        int
+	EventID   int
+	UserID    int
+	CreatedAt time.Time
+}
+
+// RSVPModel provides methods to manage event attendance in the database.
+type RSVPModel struct {
+	DB *sql.DB
+}
+
+// Create records that the user attends the event. Creating an RSVP that
+// already exists is not an error. The rsvp.created webhook is queued in the
+// same transaction when a new RSVP is recorded.
+func (m *RSVPModel) Create(ctx context.Context, eventID, userID int) error {
+	return m.exec(ctx, "INSERT OR IGNORE INTO rsvps (event_id, user_id) VALUES (?, ?)", WebhookRSVPCreated, eventID, userID)
+}
+
+// Delete removes the user's RSVP for the event, if any. The rsvp.deleted
+// webhook is queued in the same transaction when an RSVP was removed.
+func (m *RSVPModel) Delete(ctx context.Context, eventID, userID int) error {
+	return m.exec(ctx, "DELETE FROM rsvps WHERE event_id = ? AND user_id = ?", WebhookRSVPDeleted, eventID, userID)
+}
+
+// exec runs a statement changing a single RSVP and queues the webhook of the
+// given event type if the statement affected a row.
+func (m *RSVPModel) exec(ctx context.Context, stmt, eventType string, eventID, userID int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, stmt, eventID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	err = enqueueWebhook(ctx, tx, eventType, webhookRSVP{EventID: eventID, UserID: userID})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Exists reports whether the user has an RSVP for the event.
+func (m *RSVPModel) Exists(ctx context.Context, eventID, userID int) (bool, error) {
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT true FROM rsvps WHERE event_id = ? AND user_id = ?)"
+
+	err := m.DB.QueryRowContext(ctx, stmt, eventID, userID).Scan(&exists)
+	return exists, err
+}
+
+// Count returns the number of users attending the event.
+func (m *RSVPModel) Count(ctx context.Context, eventID int) (int, error) {
+	var count int
+
+	stmt := "SELECT COUNT(*) FROM rsvps WHERE event_id = ?"
+
+	err := m.DB.QueryRowContext(ctx, stmt, eventID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// PostgresRSVPModel is the PostgreSQL version of RSVPModel.
+type PostgresRSVPModel struct {
+	DB *sql.DB
+}
+
+// Create records that the user attends the event. Creating an RSVP that
+// already exists is not an error. The rsvp.created webhook is queued in the
+// same transaction when a new RSVP is recorded.
+func (m *PostgresRSVPModel) Create(ctx context.Context, eventID, userID int) error {
+	stmt := "INSERT INTO rsvps (event_id, user_id) VALUES ($1, $2) ON CONFLICT (event_id, user_id) DO NOTHING"
+	return m.exec(ctx, stmt, WebhookRSVPCreated, eventID, userID)
+}
+
+// Delete removes the user's RSVP for the event, if any. The rsvp.deleted
+// webhook is queued in the same transaction when an RSVP was removed.
+func (m *PostgresRSVPModel) Delete(ctx context.Context, eventID, userID int) error {
+	return m.exec(ctx, "DELETE FROM rsvps WHERE event_id = $1 AND user_id = $2", WebhookRSVPDeleted, eventID, userID)
+}
+
+// exec runs a statement changing a single RSVP and queues the webhook of the
+// given event type if the statement affected a row.
+func (m *PostgresRSVPModel) exec(ctx context.Context, stmt, eventType string, eventID, userID int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, stmt, eventID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	err = enqueueWebhookPostgres(ctx, tx, eventType, webhookRSVP{EventID: eventID, UserID: userID})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Exists reports whether the user has an RSVP for the event.
+func (m *PostgresRSVPModel) Exists(ctx context.Context, eventID, userID int) (bool, error) {
+	var exists bool
+
+	stmt := "SELECT EXISTS(SELECT true FROM rsvps WHERE event_id = $1 AND user_id = $2)"
+
+	err := m.DB.QueryRowContext(ctx, stmt, eventID, userID).Scan(&exists)
+	return exists, err
+}
+
+// Count returns the number of users attending the event.
+func (m *PostgresRSVPModel) Count(ctx context.Context, eventID int) (int, error) {
+	var count int
+
+	err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM rsvps WHERE event_id = $1", eventID).Scan(&count)
+	return count, err
+}
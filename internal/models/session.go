@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// lastSeenResolution is the precision of the last seen time of sessions, which
+// avoids a write on every request.
+const lastSeenResolution = time.Minute
+
+// Session is an authenticated session of a user on a device. Current is set
+// by List for the session making the request.
+type Session struct {
+	ID         int
+	UserID     int
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	Current    bool
+}
+
+// SessionModel provides methods to track and revoke the authenticated
+// sessions of users. Sessions are identified by the token of the session
+// manager, of which only a hash is stored.
+type SessionModel struct {
+	DB *sql.DB
+}
+
+// Create records the session with the token, authenticated for the user
+// until expiresAt.
+func (m *SessionModel) Create(ctx context.Context, token string, userID int, userAgent, ip string, expiresAt time.Time) error {
+	stmt := `INSERT INTO user_sessions (user_id, token_hash, user_agent, ip, expires_at)
+	VALUES (?, ?, ?, ?, ?)`
+
+	_, err := m.DB.ExecContext(ctx, stmt, userID, hashToken(token), userAgent, ip, expiresAt.UTC().Format(sqliteDateTime))
+	return err
+}
+
+// Touch returns the ID of the user authenticated by the session with the
+// token, and updates its last seen time and IP address. It returns
+// ErrNoRecord if the session was revoked or expired, or the user disabled.
+func (m *SessionModel) Touch(ctx context.Context, token, ip string) (int, error) {
+	var id, userID int
+	var lastSeenAt time.Time
+	var lastIP string
+
+	stmt := `SELECT s.id, s.user_id, s.last_seen_at, s.ip FROM user_sessions s
+	JOIN users u ON u.id = s.user_id
+	WHERE s.token_hash = ? AND s.expires_at > datetime('now') AND u.disabled_at IS NULL`
+
+	err := m.DB.QueryRowContext(ctx, stmt, hashToken(token)).Scan(&id, &userID, &lastSeenAt, &lastIP)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	if time.Since(lastSeenAt) >= lastSeenResolution || ip != lastIP {
+		_, err = m.DB.ExecContext(ctx, "UPDATE user_sessions SET last_seen_at = CURRENT_TIMESTAMP, ip = ? WHERE id = ?", ip, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+// List returns the active sessions of the user, most recently seen first.
+// The session with the current token is marked as such.
+func (m *SessionModel) List(ctx context.Context, userID int, currentToken string) ([]Session, error) {
+	stmt := `SELECT id, user_id, token_hash, user_agent, ip, created_at, last_seen_at, expires_at
+	FROM user_sessions
+	WHERE user_id = ? AND expires_at > datetime('now')
+	ORDER BY last_seen_at DESC, id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	current := hashToken(currentToken)
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		var tokenHash string
+
+		err := rows.Scan(&s.ID, &s.UserID, &tokenHash, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		s.Current = tokenHash == current
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends the session of the user with the ID. It returns ErrNoRecord if
+// the user has no such session.
+func (m *SessionModel) Revoke(ctx context.Context, userID, id int) error {
+	stmt := "DELETE FROM user_sessions WHERE id = ? AND user_id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// RevokeToken ends the session with the token, if it is recorded.
+func (m *SessionModel) RevokeToken(ctx context.Context, token string) error {
+	stmt := "DELETE FROM user_sessions WHERE token_hash = ?"
+
+	_, err := m.DB.ExecContext(ctx, stmt, hashToken(token))
+	return err
+}
+
+// RevokeAll ends every session of the user except the one with the token,
+// which may be empty to end them all. It returns the number of sessions
+// ended.
+func (m *SessionModel) RevokeAll(ctx context.Context, userID int, exceptToken string) (int, error) {
+	stmt := "DELETE FROM user_sessions WHERE user_id = ? AND token_hash != ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, userID, hashToken(exceptToken))
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// CountActive returns the number of sessions which have not expired.
+func (m *SessionModel) CountActive(ctx context.Context) (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_sessions WHERE expires_at > datetime('now')").Scan(&n)
+	return n, err
+}
+
+// Prune deletes the expired sessions and returns how many were deleted.
+func (m *SessionModel) Prune(ctx context.Context) (int, error) {
+	stmt := "DELETE FROM user_sessions WHERE expires_at <= datetime('now')"
+
+	result, err := m.DB.ExecContext(ctx, stmt)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Stats summarizes the content of the database for operators.
+type Stats struct {
+	Users             int
+	Admins            int
+	DisabledUsers     int
+	Events            int
+	EventsWithoutUser int
+	RSVPs             int
+	Venues            int
+	Attachments       int
+	AttachmentBytes   int64
+	ActiveSessions    int
+	APITokens         int
+	PendingWebhooks   int
+	DeadWebhooks      int
+	AuditEvents       int
+
+	// DatabaseBytes is the size of the database file, and FreeBytes the
+	// part of it that is unused and can be reclaimed by VACUUM.
+	DatabaseBytes int64
+	FreeBytes     int64
+}
+
+// StatsModel computes statistics about the database.
+type StatsModel struct {
+	DB *sql.DB
+}
+
+// Retrieve returns the current statistics.
+func (m *StatsModel) Retrieve(ctx context.Context) (Stats, error) {
+	var s Stats
+
+	stmt := `SELECT
+		(SELECT COUNT(*) FROM users),
+		(SELECT COUNT(*) FROM users WHERE role = ?),
+		(SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL),
+		(SELECT COUNT(*) FROM events),
+		(SELECT COUNT(*) FROM events WHERE user_id IS NULL),
+		(SELECT COUNT(*) FROM rsvps),
+		(SELECT COUNT(*) FROM venues),
+		(SELECT COUNT(*) FROM attachments),
+		(SELECT COALESCE(SUM(size), 0) FROM attachments),
+		(SELECT COUNT(*) FROM user_sessions WHERE expires_at > datetime('now')),
+		(SELECT COUNT(*) FROM api_tokens),
+		(SELECT COUNT(*) FROM webhook_deliveries WHERE status IN (?, ?)),
+		(SELECT COUNT(*) FROM webhook_deliveries WHERE status = ?),
+		(SELECT COUNT(*) FROM audit_events)`
+
+	err := m.DB.QueryRowContext(ctx, stmt, RoleAdmin, DeliveryPending, DeliveryFailed, DeliveryDead).Scan(
+		&s.Users, &s.Admins, &s.DisabledUsers,
+		&s.Events, &s.EventsWithoutUser, &s.RSVPs, &s.Venues,
+		&s.Attachments, &s.AttachmentBytes,
+		&s.ActiveSessions, &s.APITokens,
+		&s.PendingWebhooks, &s.DeadWebhooks, &s.AuditEvents,
+	)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var pageSize, pageCount, freePages int64
+	err = m.DB.QueryRowContext(ctx, "SELECT page_size, page_count, freelist_count FROM pragma_page_size, pragma_page_count, pragma_freelist_count").
+		Scan(&pageSize, &pageCount, &freePages)
+	if err != nil {
+		return Stats{}, err
+	}
+	s.DatabaseBytes = pageSize * pageCount
+	s.FreeBytes = pageSize * freePages
+
+	return s, nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+)
+
+// hashRecoveryCode returns the hash under which a normalized recovery code
+// is stored. Recovery codes are random, so a fast hash is enough.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// TOTPSecret returns the TOTP secret of the user, empty if two-factor
+// authentication is disabled.
+func (m *UserModel) TOTPSecret(ctx context.Context, id int) (string, error) {
+	var secret string
+
+	stmt := "SELECT totp_secret FROM users WHERE id = ?"
+
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&secret)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		}
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// EnableTOTP turns on two-factor authentication for the user with the
+// confirmed secret, replacing any previous recovery codes with the given
+// normalized ones.
+func (m *UserModel) EnableTOTP(ctx context.Context, id int, secret string, recoveryCodes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE id = ?", secret, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, id, recoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP turns off two-factor authentication for the user and deletes
+// the recovery codes.
+func (m *UserModel) DisableTOTP(ctx context.Context, id int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_secret = '', totp_last_step = 0 WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the recovery codes of the user and stores the
+// hashes of the given ones.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codes []string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseTOTPStep records that the user logged in with the code of the time
+// step. It reports false if a code of the same or a later step was already
+// used, so that an intercepted code cannot be replayed.
+func (m *UserModel) UseTOTPStep(ctx context.Context, id int, step int64) (bool, error) {
+	stmt := "UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, step, id, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// UseRecoveryCode marks the normalized recovery code of the user as used.
+// It reports false if the code is unknown or was already used.
+func (m *UserModel) UseRecoveryCode(ctx context.Context, id int, code string) (bool, error) {
+	stmt := `UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+	WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// RecoveryCodesLeft returns the number of unused recovery codes of the user.
+func (m *UserModel) RecoveryCodesLeft(ctx context.Context, id int) (int, error) {
+	var n int
+
+	stmt := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL"
+
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&n)
+	return n, err
+}
@@ -1,10 +1,20 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/mattn/go-sqlite3"
-	"golang.org/x/crypto/bcrypt"
+	"log"
+	"time"
+)
+
+// User roles. Administrators can manage application wide settings such as
+// webhook subscriptions.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
 
 // User represents a user entity with basic
@@ -14,6 +24,20 @@ type User struct {
 	Name           string
 	Email          string
 	HashedPassword []byte
+	Role           string
+	TOTPEnabled    bool
+	HasPassword    bool
+	Disabled       bool
+	CreatedAt      time.Time
+
+	// Locale is the language chosen for the interface, empty when the
+	// browser chooses.
+	Locale string
+}
+
+// IsAdmin reports whether the user has the administrator role.
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
 
 // UserModel provides methods to interact with the users'
@@ -24,17 +48,18 @@ type UserModel struct {
 
 // Create adds a new user with the provided name,
 // email, and hashed password to the database.
-func (m *UserModel) Create(name, email, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+// It returns ErrDuplicateEmail if the email is already used.
+func (m *UserModel) Create(ctx context.Context, name, email, password string) error {
+	hashedPassword, err := hashPassword(ctx, password)
 	if err != nil {
 		return err
 	}
 
 	stmt := "INSERT INTO users (name, email, password) VALUES (?, ?, ?)"
 
-	_, err = m.DB.Exec(stmt, name, email, hashedPassword)
+	_, err = m.DB.ExecContext(ctx, stmt, name, email, hashedPassword)
 	if err != nil {
-		var sqliteError *sqlite3.Error
+		var sqliteError sqlite3.Error
 		if errors.As(err, &sqliteError) && errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
 			return ErrDuplicateEmail
 		}
@@ -44,14 +69,39 @@ func (m *UserModel) Create(name, email, password string) error {
 	return nil
 }
 
+// UpdatePassword replaces the password of the user.
+func (m *UserModel) UpdatePassword(ctx context.Context, id int, password string) error {
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	stmt := "UPDATE users SET password = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, hashedPassword, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
 // Authenticate verifies a user's credentials and returns
 // their ID if valid or an error if authentication fails.
-func (m *UserModel) Authenticate(email, password string) (int, error) {
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
 	var id int
 	var hashedPassword []byte
 
-	stmt := "SELECT id, password FROM users WHERE email = ?"
-	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	// Disabled accounts get the same response as unknown ones.
+	stmt := "SELECT id, password FROM users WHERE email = ? AND disabled_at IS NULL"
+	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(&id, &hashedPassword)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrInvalidCredentials
@@ -60,13 +110,14 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		}
 	}
 
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	// Users provisioned by single sign-on have no password.
+	if len(hashedPassword) == 0 {
+		return 0, ErrInvalidCredentials
+	}
+
+	err = comparePassword(ctx, hashedPassword, password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return 0, ErrInvalidCredentials
-		} else {
-			return 0, err
-		}
+		return 0, err
 	}
 
 	return id, nil
@@ -74,12 +125,181 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 
 // Exists checks if a user with the specified ID exists in the
 // database and returns a boolean result and an error.
-func (m *UserModel) Exists(id int) (bool, error) {
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
 
 	var exists bool
 
 	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
 
-	err := m.DB.QueryRow(stmt, id).Scan(&exists)
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&exists)
 	return exists, err
 }
+
+// Count returns the number of registered users, including the disabled ones.
+func (m *UserModel) Count(ctx context.Context) (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&n)
+	return n, err
+}
+
+// userColumns lists the columns scanned by scanUser, in order.
+const userColumns = "id, name, email, role, totp_secret != '', password != '', disabled_at IS NOT NULL, created_at, locale"
+
+// scanUser scans a row selected with userColumns into a User.
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.TOTPEnabled, &u.HasPassword, &u.Disabled, &u.CreatedAt, &u.Locale)
+	return u, err
+}
+
+// Retrieve returns the user with the specified ID, or ErrNoRecord if no such user exists.
+func (m *UserModel) Retrieve(ctx context.Context, id int) (User, error) {
+	stmt := "SELECT " + userColumns + " FROM users WHERE id = ?"
+
+	u, err := scanUser(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		}
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// RetrieveByEmail returns the user with the specified email, or ErrNoRecord
+// if no such user exists.
+func (m *UserModel) RetrieveByEmail(ctx context.Context, email string) (User, error) {
+	stmt := "SELECT " + userColumns + " FROM users WHERE email = ?"
+
+	u, err := scanUser(m.DB.QueryRowContext(ctx, stmt, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		}
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// List returns every user, oldest first.
+func (m *UserModel) List(ctx context.Context) ([]User, error) {
+	rows, err := m.DB.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var users []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SetRole changes the role of the user to RoleUser or RoleAdmin.
+func (m *UserModel) SetRole(ctx context.Context, id int, role string) error {
+	if role != RoleUser && role != RoleAdmin {
+		return fmt.Errorf("models: unknown role %q", role)
+	}
+
+	return m.update(ctx, "UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", role, id)
+}
+
+// SetDisabled disables or enables the account of the user. A disabled user
+// cannot log in, and their sessions and API tokens are rejected.
+func (m *UserModel) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	if disabled {
+		return m.update(ctx, "UPDATE users SET disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP) WHERE id = ?", id)
+	}
+	return m.update(ctx, "UPDATE users SET disabled_at = NULL WHERE id = ?", id)
+}
+
+// update runs a statement changing a single user and returns ErrNoRecord if
+// it affected no row.
+func (m *UserModel) update(ctx context.Context, stmt string, args ...any) error {
+	result, err := m.DB.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// IsLocked reports whether the account with the specified email is locked
+// out after too many failed logins. Unknown emails are never locked.
+func (m *UserModel) IsLocked(ctx context.Context, email string) (bool, error) {
+	var locked bool
+
+	stmt := `SELECT EXISTS(SELECT 1 FROM users WHERE email = ? AND datetime(locked_until) > datetime(?))`
+
+	err := m.DB.QueryRowContext(ctx, stmt, email, time.Now().UTC().Format(sqliteDateTime)).Scan(&locked)
+	return locked, err
+}
+
+// RecordLoginFailure counts a failed login for the account with the specified
+// email. Once maxFailures consecutive failures are reached, the account is
+// locked for the lockout duration and the count starts over. It returns the ID
+// of the account, 0 for unknown emails, and whether it has just been locked.
+func (m *UserModel) RecordLoginFailure(ctx context.Context, email string, maxFailures int, lockout time.Duration) (int, bool, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id, failures int
+
+	stmt := `UPDATE users SET failed_logins = failed_logins + 1 WHERE email = ? RETURNING id, failed_logins`
+
+	err = tx.QueryRowContext(ctx, stmt, email).Scan(&id, &failures)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	locked := failures >= maxFailures
+	if locked {
+		lockedUntil := time.Now().UTC().Add(lockout).Format(sqliteDateTime)
+
+		_, err = tx.ExecContext(ctx, "UPDATE users SET failed_logins = 0, locked_until = ? WHERE id = ?", lockedUntil, id)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return id, locked, tx.Commit()
+}
+
+// ResetLoginFailures clears the failed login count of the user after a
+// successful login.
+func (m *UserModel) ResetLoginFailures(ctx context.Context, id int) error {
+	stmt := "UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = ?"
+
+	_, err := m.DB.ExecContext(ctx, stmt, id)
+	return err
+}
@@ -0,0 +1,528 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// PostgresUserModel is the PostgreSQL version of UserModel.
+type PostgresUserModel struct {
+	DB *sql.DB
+}
+
+// Create adds a new user with the name, email and password. It returns
+// ErrDuplicateEmail if the email is already used.
+func (m *PostgresUserModel) Create(ctx context.Context, name, email, password string) error {
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	stmt := "INSERT INTO users (name, email, password) VALUES ($1, $2, $3)"
+
+	_, err = m.DB.ExecContext(ctx, stmt, name, email, string(hashedPassword))
+	if err != nil {
+		if isPostgresUniqueViolation(err) {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Count returns the number of registered users, including the disabled ones.
+func (m *PostgresUserModel) Count(ctx context.Context) (int, error) {
+	var n int
+	err := m.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&n)
+	return n, err
+}
+
+// Retrieve returns the user with the ID, or ErrNoRecord if there is none.
+func (m *PostgresUserModel) Retrieve(ctx context.Context, id int) (User, error) {
+	stmt := "SELECT " + userColumns + " FROM users WHERE id = $1"
+
+	u, err := scanUser(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		}
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// Update saves the name and email of the user. It returns ErrDuplicateEmail
+// if another account already uses the email.
+func (m *PostgresUserModel) Update(ctx context.Context, u User) error {
+	stmt := "UPDATE users SET name = $1, email = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3"
+
+	result, err := m.DB.ExecContext(ctx, stmt, u.Name, u.Email, u.ID)
+	if err != nil {
+		if isPostgresUniqueViolation(err) {
+			return ErrDuplicateEmail
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// UpdateLocale saves the language chosen by the user, empty to let the
+// browser choose.
+func (m *PostgresUserModel) UpdateLocale(ctx context.Context, id int, locale string) error {
+	stmt := "UPDATE users SET locale = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+
+	result, err := m.DB.ExecContext(ctx, stmt, locale, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// UpdatePassword replaces the password of the user.
+func (m *PostgresUserModel) UpdatePassword(ctx context.Context, id int, password string) error {
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	stmt := "UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+
+	result, err := m.DB.ExecContext(ctx, stmt, string(hashedPassword), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Delete removes the account of the user following the policy of
+// UserModel.Delete, and returns the attachments of the deleted events.
+func (m *PostgresUserModel) Delete(ctx context.Context, id int) ([]Attachment, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	eventIDs, err := queryIDs(ctx, tx, "DELETE FROM rsvps WHERE user_id = $1 RETURNING event_id", id)
+	if err != nil {
+		return nil, err
+	}
+	for _, eventID := range eventIDs {
+		err = enqueueWebhookPostgres(ctx, tx, WebhookRSVPDeleted, webhookRSVP{EventID: eventID, UserID: id})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stmt := `SELECT a.id, a.event_id, a.kind, a.filename, a.content_type, a.size, a.storage_key, a.thumbnail_key, a.created_at
+	FROM attachments a
+	JOIN events e ON e.id = a.event_id
+	WHERE e.user_id = $1 AND NOT EXISTS (SELECT 1 FROM rsvps r WHERE r.event_id = e.id)`
+
+	attachments, err := queryAttachments(ctx, tx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt = `DELETE FROM events
+	WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM rsvps r WHERE r.event_id = events.id)
+	RETURNING id`
+
+	eventIDs, err = queryIDs(ctx, tx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, eventID := range eventIDs {
+		err = enqueueWebhookPostgres(ctx, tx, WebhookEventDeleted, webhookEventRef{ID: eventID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return attachments, tx.Commit()
+}
+
+// Authenticate verifies the credentials of a user and returns their ID, or
+// ErrInvalidCredentials.
+func (m *PostgresUserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	stmt := "SELECT id, password FROM users WHERE email = $1 AND disabled_at IS NULL"
+	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	if len(hashedPassword) == 0 {
+		return 0, ErrInvalidCredentials
+	}
+
+	err = comparePassword(ctx, hashedPassword, password)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// IsLocked reports whether the account with the email is locked out after
+// too many failed logins.
+func (m *PostgresUserModel) IsLocked(ctx context.Context, email string) (bool, error) {
+	var locked bool
+
+	stmt := "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND locked_until > CURRENT_TIMESTAMP)"
+
+	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(&locked)
+	return locked, err
+}
+
+// RecordLoginFailure counts a failed login for the account with the email,
+// as UserModel.RecordLoginFailure does.
+func (m *PostgresUserModel) RecordLoginFailure(ctx context.Context, email string, maxFailures int, lockout time.Duration) (int, bool, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id, failures int
+
+	stmt := "UPDATE users SET failed_logins = failed_logins + 1 WHERE email = $1 RETURNING id, failed_logins"
+
+	err = tx.QueryRowContext(ctx, stmt, email).Scan(&id, &failures)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	locked := failures >= maxFailures
+	if locked {
+		_, err = tx.ExecContext(ctx, "UPDATE users SET failed_logins = 0, locked_until = $1 WHERE id = $2", time.Now().Add(lockout), id)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return id, locked, tx.Commit()
+}
+
+// ResetLoginFailures clears the failed login count of the user.
+func (m *PostgresUserModel) ResetLoginFailures(ctx context.Context, id int) error {
+	_, err := m.DB.ExecContext(ctx, "UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = $1", id)
+	return err
+}
+
+// RequestEmailChange records that the user wants to use a new email and
+// returns the token confirming it, as UserModel.RequestEmailChange does.
+func (m *PostgresUserModel) RequestEmailChange(ctx context.Context, userID int, email string, expiresAt time.Time) (string, error) {
+	var taken bool
+	err := m.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT true FROM users WHERE email = $1)", email).Scan(&taken)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrDuplicateEmail
+	}
+
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `INSERT INTO email_changes (user_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE SET email = excluded.email, token_hash = excluded.token_hash,
+	expires_at = excluded.expires_at, created_at = CURRENT_TIMESTAMP`
+
+	_, err = m.DB.ExecContext(ctx, stmt, userID, email, hashToken(token), expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConfirmEmailChange applies the pending email change matching the token, as
+// UserModel.ConfirmEmailChange does.
+func (m *PostgresUserModel) ConfirmEmailChange(ctx context.Context, token string) (int, string, string, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userID int
+	var oldEmail, newEmail string
+
+	stmt := `DELETE FROM email_changes WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+	RETURNING user_id, email, (SELECT email FROM users WHERE id = email_changes.user_id)`
+
+	err = tx.QueryRowContext(ctx, stmt, hashToken(token)).Scan(&userID, &newEmail, &oldEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", "", ErrNoRecord
+		}
+		return 0, "", "", err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET email = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", newEmail, userID)
+	if err != nil {
+		if isPostgresUniqueViolation(err) {
+			return 0, "", "", ErrDuplicateEmail
+		}
+		return 0, "", "", err
+	}
+
+	return userID, oldEmail, newEmail, tx.Commit()
+}
+
+// RetrieveByIdentity returns the ID of the user linked to the subject at the
+// OpenID Connect issuer, or ErrNoRecord if there is none.
+func (m *PostgresUserModel) RetrieveByIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	var id int
+
+	stmt := "SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2"
+
+	err := m.DB.QueryRowContext(ctx, stmt, issuer, subject).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// LinkIdentity links the subject at the issuer to the user with the email and
+// returns the ID of the user, or ErrNoRecord if no user has the email.
+func (m *PostgresUserModel) LinkIdentity(ctx context.Context, email, issuer, subject string) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM users WHERE email = $1", email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)", id, issuer, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// CreateFromIdentity adds a user without password, who logs in with the
+// subject at the issuer, and returns the ID of the user. It returns
+// ErrDuplicateEmail if the email is already used.
+func (m *PostgresUserModel) CreateFromIdentity(ctx context.Context, name, email, issuer, subject string) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int
+	err = tx.QueryRowContext(ctx, "INSERT INTO users (name, email, password) VALUES ($1, $2, '') RETURNING id", name, email).Scan(&id)
+	if err != nil {
+		if isPostgresUniqueViolation(err) {
+			return 0, ErrDuplicateEmail
+		}
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)", id, issuer, subject)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// TOTPSecret returns the TOTP secret of the user, empty if two-factor
+// authentication is disabled.
+func (m *PostgresUserModel) TOTPSecret(ctx context.Context, id int) (string, error) {
+	var secret string
+
+	err := m.DB.QueryRowContext(ctx, "SELECT totp_secret FROM users WHERE id = $1", id).Scan(&secret)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		}
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// EnableTOTP turns on two-factor authentication for the user with the
+// confirmed secret, replacing any previous recovery codes.
+func (m *PostgresUserModel) EnableTOTP(ctx context.Context, id int, secret string, recoveryCodes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "UPDATE users SET totp_secret = $1, totp_last_step = 0 WHERE id = $2", secret, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	err = replaceRecoveryCodesPostgres(ctx, tx, id, recoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP turns off two-factor authentication for the user and deletes
+// the recovery codes.
+func (m *PostgresUserModel) DisableTOTP(ctx context.Context, id int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_secret = '', totp_last_step = 0 WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	err = replaceRecoveryCodesPostgres(ctx, tx, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodesPostgres is the PostgreSQL version of replaceRecoveryCodes.
+func replaceRecoveryCodesPostgres(ctx context.Context, tx *sql.Tx, userID int, codes []string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseTOTPStep records that the user logged in with the code of the time
+// step. It reports false if a code of the same or a later step was already
+// used.
+func (m *PostgresUserModel) UseTOTPStep(ctx context.Context, id int, step int64) (bool, error) {
+	stmt := "UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1"
+
+	result, err := m.DB.ExecContext(ctx, stmt, step, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// UseRecoveryCode marks the recovery code of the user as used. It reports
+// false if the code is unknown or was already used.
+func (m *PostgresUserModel) UseRecoveryCode(ctx context.Context, id int, code string) (bool, error) {
+	stmt := `UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := m.DB.ExecContext(ctx, stmt, id, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// RecoveryCodesLeft returns the number of unused recovery codes of the user.
+func (m *PostgresUserModel) RecoveryCodesLeft(ctx context.Context, id int) (int, error) {
+	var n int
+
+	stmt := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"log"
+	"strings"
+	"time"
+)
+
+// Coordinates is the geographic position of a venue, in decimal degrees.
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Venue represents a place where events are held. Capacity is 0 when it is
+// unknown, and Coordinates is nil when the position was not provided.
+type Venue struct {
+	ID          int
+	Name        string
+	Address     string
+	Capacity    int
+	Coordinates *Coordinates
+	Amenities   []string
+	CreatedAt   time.Time
+}
+
+// VenueModel provides methods to manage venues in the database.
+type VenueModel struct {
+	DB *sql.DB
+}
+
+// venueColumns lists the columns scanned by scanVenue, in order.
+const venueColumns = "id, name, address, capacity, latitude, longitude, amenities, created_at"
+
+// scanVenue scans a row selected with venueColumns into a Venue.
+func scanVenue(row interface{ Scan(...any) error }) (Venue, error) {
+	var v Venue
+	var latitude, longitude sql.NullFloat64
+	var amenities string
+
+	err := row.Scan(&v.ID, &v.Name, &v.Address, &v.Capacity, &latitude, &longitude, &amenities, &v.CreatedAt)
+	if err != nil {
+		return Venue{}, err
+	}
+
+	if latitude.Valid && longitude.Valid {
+		v.Coordinates = &Coordinates{Latitude: latitude.Float64, Longitude: longitude.Float64}
+	}
+	if amenities != "" {
+		v.Amenities = strings.Split(amenities, ",")
+	}
+
+	return v, nil
+}
+
+// venueArgs returns the values of the columns written for the venue, in the
+// order name, address, capacity, latitude, longitude, amenities.
+func venueArgs(v Venue) []any {
+	var latitude, longitude sql.NullFloat64
+	if v.Coordinates != nil {
+		latitude = sql.NullFloat64{Float64: v.Coordinates.Latitude, Valid: true}
+		longitude = sql.NullFloat64{Float64: v.Coordinates.Longitude, Valid: true}
+	}
+
+	return []any{v.Name, v.Address, v.Capacity, latitude, longitude, strings.Join(v.Amenities, ",")}
+}
+
+// Create adds a new venue and returns its ID. It returns ErrDuplicateVenue
+// if a venue with the same name exists.
+func (m *VenueModel) Create(ctx context.Context, v Venue) (int, error) {
+	stmt := `INSERT INTO venues (name, address, capacity, latitude, longitude, amenities)
+	VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := m.DB.ExecContext(ctx, stmt, venueArgs(v)...)
+	if err != nil {
+		return 0, venueError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Update modifies the venue with the ID of v. It returns ErrDuplicateVenue
+// if another venue has the same name.
+func (m *VenueModel) Update(ctx context.Context, v Venue) error {
+	stmt := `UPDATE venues SET name = ?, address = ?, capacity = ?, latitude = ?, longitude = ?, amenities = ?
+	WHERE id = ?`
+
+	result, err := m.DB.ExecContext(ctx, stmt, append(venueArgs(v), v.ID)...)
+	if err != nil {
+		return venueError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Retrieve returns the venue with the given ID.
+func (m *VenueModel) Retrieve(ctx context.Context, id int) (Venue, error) {
+	stmt := "SELECT " + venueColumns + " FROM venues WHERE id = ?"
+
+	v, err := scanVenue(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Venue{}, ErrNoRecord
+		}
+		return Venue{}, err
+	}
+
+	return v, nil
+}
+
+// List returns all the venues ordered by name.
+func (m *VenueModel) List(ctx context.Context) ([]Venue, error) {
+	stmt := "SELECT " + venueColumns + " FROM venues ORDER BY name COLLATE NOCASE"
+
+	rows, err := m.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var venues []Venue
+	for rows.Next() {
+		v, err := scanVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		venues = append(venues, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return venues, nil
+}
+
+// Delete removes the venue. Events booked at the venue keep their location
+// but are no longer linked to it.
+func (m *VenueModel) Delete(ctx context.Context, id int) error {
+	stmt := "DELETE FROM venues WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// venueError maps the unique constraint on venue names to ErrDuplicateVenue.
+func venueError(err error) error {
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) && errors.Is(sqliteError.ExtendedCode, sqlite3.ErrConstraintUnique) {
+		return ErrDuplicateVenue
+	}
+	return err
+}
+
+// checkVenueConflict returns ErrVenueConflict if an event other than
+// eventID is booked at the venue at any time from the start of the event up
+// to its end, as returned by Event.End. Bookings which only touch, one
+// ending when the other starts, do not conflict.
+func checkVenueConflict(ctx context.Context, tx *sql.Tx, venueID, eventID int, e Event) error {
+	if venueID == 0 {
+		return nil
+	}
+
+	// The end of the other events is computed as Event.End does.
+	stmt := `SELECT EXISTS(SELECT 1 FROM events
+	WHERE venue_id = ? AND id != ?
+	  AND datetime(event_date) < datetime(?)
+	  AND CASE
+	      WHEN time(COALESCE(end_date, event_date)) = '00:00:00' THEN datetime(COALESCE(end_date, event_date), '+1 day')
+	      WHEN end_date IS NULL OR datetime(end_date) <= datetime(event_date) THEN datetime(event_date, '+1 day')
+	      ELSE datetime(end_date)
+	  END > datetime(?))`
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, stmt, venueID, eventID, e.End().Format(sqliteDateTime), e.EventDate.UTC().Format(sqliteDateTime)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVenueConflict
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// PostgresVenueModel is the PostgreSQL version of VenueModel.
+type PostgresVenueModel struct {
+	DB *sql.DB
+}
+
+// Create adds a new venue and returns its ID. It returns ErrDuplicateVenue
+// if a venue with the same name exists.
+func (m *PostgresVenueModel) Create(ctx context.Context, v Venue) (int, error) {
+	stmt := `INSERT INTO venues (name, address, capacity, latitude, longitude, amenities)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRowContext(ctx, stmt, venueArgs(v)...).Scan(&id)
+	if err != nil {
+		return 0, venueErrorPostgres(err)
+	}
+
+	return id, nil
+}
+
+// Update modifies the venue with the ID of v. It returns ErrDuplicateVenue
+// if another venue has the same name.
+func (m *PostgresVenueModel) Update(ctx context.Context, v Venue) error {
+	stmt := `UPDATE venues SET name = $1, address = $2, capacity = $3, latitude = $4, longitude = $5, amenities = $6
+	WHERE id = $7`
+
+	result, err := m.DB.ExecContext(ctx, stmt, append(venueArgs(v), v.ID)...)
+	if err != nil {
+		return venueErrorPostgres(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Retrieve returns the venue with the given ID.
+func (m *PostgresVenueModel) Retrieve(ctx context.Context, id int) (Venue, error) {
+	stmt := "SELECT " + venueColumns + " FROM venues WHERE id = $1"
+
+	v, err := scanVenue(m.DB.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Venue{}, ErrNoRecord
+		}
+		return Venue{}, err
+	}
+
+	return v, nil
+}
+
+// List returns all the venues ordered by name.
+func (m *PostgresVenueModel) List(ctx context.Context) ([]Venue, error) {
+	stmt := "SELECT " + venueColumns + " FROM venues ORDER BY lower(name)"
+
+	rows, err := m.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var venues []Venue
+	for rows.Next() {
+		v, err := scanVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		venues = append(venues, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return venues, nil
+}
+
+// Delete removes the venue. Events booked at the venue keep their location
+// but are no longer linked to it.
+func (m *PostgresVenueModel) Delete(ctx context.Context, id int) error {
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM venues WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// venueErrorPostgres is the PostgreSQL version of venueError.
+func venueErrorPostgres(err error) error {
+	if isPostgresUniqueViolation(err) {
+		return ErrDuplicateVenue
+	}
+	return err
+}
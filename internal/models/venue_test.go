@@ -0,0 +1,101 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"testing"
+	"time"
+)
+
+func TestEventEnd(t *testing.T) {
+	day := time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event models.Event
+		want  time.Time
+	}{
+		{name: "Single day", event: models.Event{EventDate: day}, want: day.AddDate(0, 0, 1)},
+		{name: "Several days", event: models.Event{EventDate: day, EndDate: day.AddDate(0, 0, 2)}, want: day.AddDate(0, 0, 3)},
+		{name: "With times", event: models.Event{EventDate: day.Add(10 * time.Hour), EndDate: day.Add(12 * time.Hour)}, want: day.Add(12 * time.Hour)},
+		{name: "Start time only", event: models.Event{EventDate: day.Add(10 * time.Hour)}, want: day.Add(34 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.End(); !got.Equal(tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVenueConflict(t *testing.T) {
+	day := time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC)
+
+	// The booked event runs from 10:00 to 12:00.
+	booked := models.Event{EventDate: day.Add(10 * time.Hour), EndDate: day.Add(12 * time.Hour)}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		conflict bool
+	}{
+		{name: "Ends when booked starts", start: day.Add(8 * time.Hour), end: day.Add(10 * time.Hour)},
+		{name: "Starts when booked ends", start: day.Add(12 * time.Hour), end: day.Add(14 * time.Hour)},
+		{name: "Ends a second into booked", start: day.Add(8 * time.Hour), end: day.Add(10*time.Hour + time.Second), conflict: true},
+		{name: "Starts a second before booked ends", start: day.Add(12*time.Hour - time.Second), end: day.Add(14 * time.Hour), conflict: true},
+		{name: "Inside booked", start: day.Add(11 * time.Hour), end: day.Add(11*time.Hour + 30*time.Minute), conflict: true},
+		{name: "Around booked", start: day.Add(9 * time.Hour), end: day.Add(13 * time.Hour), conflict: true},
+		{name: "Whole day", start: day, conflict: true},
+		{name: "Day before", start: day.AddDate(0, 0, -1)},
+		{name: "Days before and after", start: day.AddDate(0, 0, -1), end: day.AddDate(0, 0, 1), conflict: true},
+		{name: "Day after", start: day.AddDate(0, 0, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := modeltest.OpenDB(t)
+			events := &models.EventModel{DB: db}
+
+			err := (&models.UserModel{DB: db}).Create(ctx, "Alice", "alice@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			userID, err := (&models.UserModel{DB: db}).Authenticate(ctx, "alice@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			venueID, err := (&models.VenueModel{DB: db}).Create(ctx, models.Venue{Name: "Main hall", Address: "1 Main Street", Capacity: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = events.Create(ctx, userID, "Booked", "Booked event", booked.EventDate, booked.EndDate, "Main hall", venueID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			id, err := events.Create(ctx, userID, "Other", "Other event", tt.start, tt.end, "Main hall", venueID)
+			if tt.conflict {
+				if !errors.Is(err, models.ErrVenueConflict) {
+					t.Fatalf("got error %v; want %v", err, models.ErrVenueConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// An event does not conflict with itself when it is updated.
+			err = events.Update(ctx, id, "Other", "Other event", tt.start, tt.end, "Main hall", venueID)
+			if err != nil {
+				t.Errorf("got error %v updating the event; want none", err)
+			}
+		})
+	}
+}
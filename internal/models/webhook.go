@@ -0,0 +1,394 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// Webhook event types emitted by the models when the data they manage changes.
+const (
+	WebhookEventCreated = "event.created"
+	WebhookEventUpdated = "event.updated"
+	WebhookEventDeleted = "event.deleted"
+	WebhookRSVPCreated  = "rsvp.created"
+	WebhookRSVPDeleted  = "rsvp.deleted"
+)
+
+// WebhookEventTypes lists every event type a subscription can listen to.
+var WebhookEventTypes = []string{
+	WebhookEventCreated,
+	WebhookEventUpdated,
+	WebhookEventDeleted,
+	WebhookRSVPCreated,
+	WebhookRSVPDeleted,
+}
+
+// Webhook delivery states. A failed delivery is retried until it either
+// succeeds or runs out of attempts, at which point it becomes dead.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSubscription represents an external endpoint that is notified
+// about the event types it subscribed to.
+type WebhookSubscription struct {
+	ID         int
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+// WebhookDelivery represents a single payload queued for delivery to a
+// subscription, together with the subscription's endpoint and secret.
+type WebhookDelivery struct {
+	ID             int
+	SubscriptionID int
+	URL            string
+	Secret         string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+}
+
+// WebhookAttempt records the outcome of one HTTP request made for a delivery.
+type WebhookAttempt struct {
+	ID          int
+	DeliveryID  int
+	EventType   string
+	StatusCode  int
+	Error       string
+	Duration    time.Duration
+	AttemptedAt time.Time
+}
+
+// WebhookModel provides methods to manage webhook subscriptions and their
+// persistent delivery queue.
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+// webhookPayload is the JSON document sent to subscribers.
+type webhookPayload struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// webhookEvent is the representation of an Event inside a webhook payload.
+type webhookEvent struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	EventDate   time.Time  `json:"event_date"`
+	EndDate     *time.Time `json:"end_date"`
+}
+
+// optionalTime returns nil for the zero time, so that it is encoded as null.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// webhookEventRef identifies a deleted Event inside a webhook payload.
+type webhookEventRef struct {
+	ID int `json:"id"`
+}
+
+// webhookRSVP is the representation of an RSVP inside a webhook payload.
+type webhookRSVP struct {
+	EventID int `json:"event_id"`
+	UserID  int `json:"user_id"`
+}
+
+// enqueueWebhook queues a delivery of the event type for every subscription
+// listening to it. It runs inside the caller's transaction so that a
+// delivery is only queued when the change itself is committed.
+func enqueueWebhook(ctx context.Context, tx *sql.Tx, eventType string, data any) error {
+	payload, err := newWebhookPayload(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO webhook_deliveries (subscription_id, event_type, payload)
+	SELECT id, ?, ? FROM webhook_subscriptions
+	WHERE ',' || event_types || ',' LIKE '%,' || ? || ',%'`
+
+	_, err = tx.ExecContext(ctx, stmt, eventType, payload, eventType)
+	return err
+}
+
+// newWebhookPayload returns the JSON payload of a delivery of the event type
+// with the data, under a new random ID.
+func newWebhookPayload(eventType string, data any) (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		ID:        hex.EncodeToString(id),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(payload), nil
+}
+
+// CreateSubscription adds a new webhook subscription and returns its ID.
+func (m *WebhookModel) CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (int, error) {
+	stmt := "INSERT INTO webhook_subscriptions (url, secret, event_types) VALUES (?, ?, ?)"
+
+	result, err := m.DB.ExecContext(ctx, stmt, url, secret, strings.Join(eventTypes, ","))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// DeleteSubscription removes a subscription together with its deliveries.
+func (m *WebhookModel) DeleteSubscription(ctx context.Context, id int) error {
+	stmt := "DELETE FROM webhook_subscriptions WHERE id = ?"
+
+	result, err := m.DB.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// RetrieveSubscription returns the subscription with the given ID.
+func (m *WebhookModel) RetrieveSubscription(ctx context.Context, id int) (WebhookSubscription, error) {
+	stmt := "SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions WHERE id = ?"
+
+	var s WebhookSubscription
+	var eventTypes string
+
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&s.ID, &s.URL, &s.Secret, &eventTypes, &s.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return WebhookSubscription{}, ErrNoRecord
+		}
+		return WebhookSubscription{}, err
+	}
+	s.EventTypes = strings.Split(eventTypes, ",")
+
+	return s, nil
+}
+
+// ListSubscriptions returns all webhook subscriptions.
+func (m *WebhookModel) ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	stmt := "SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions ORDER BY id"
+
+	rows, err := m.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var subscriptions []WebhookSubscription
+	for rows.Next() {
+		var s WebhookSubscription
+		var eventTypes string
+		err := rows.Scan(&s.ID, &s.URL, &s.Secret, &eventTypes, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		s.EventTypes = strings.Split(eventTypes, ",")
+		subscriptions = append(subscriptions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription.
+func (m *WebhookModel) ListDeliveries(ctx context.Context, subscriptionID, limit int) ([]WebhookDelivery, error) {
+	stmt := `SELECT d.id, d.subscription_id, s.url, s.secret, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at
+	FROM webhook_deliveries d
+	JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.subscription_id = ?
+	ORDER BY d.id DESC
+	LIMIT ?`
+
+	return m.queryDeliveries(ctx, stmt, subscriptionID, limit)
+}
+
+// ListAttempts returns the most recent delivery attempts of a subscription.
+func (m *WebhookModel) ListAttempts(ctx context.Context, subscriptionID, limit int) ([]WebhookAttempt, error) {
+	stmt := `SELECT a.id, a.delivery_id, d.event_type, a.status_code, a.error, a.duration_ms, a.attempted_at
+	FROM webhook_attempts a
+	JOIN webhook_deliveries d ON d.id = a.delivery_id
+	WHERE d.subscription_id = ?
+	ORDER BY a.id DESC
+	LIMIT ?`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var attempts []WebhookAttempt
+	for rows.Next() {
+		var a WebhookAttempt
+		var durationMs int64
+		err := rows.Scan(&a.ID, &a.DeliveryID, &a.EventType, &a.StatusCode, &a.Error, &durationMs, &a.AttemptedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// Due returns up to limit deliveries that are waiting to be attempted at
+// the time now and are not currently locked by a dispatcher.
+func (m *WebhookModel) Due(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error) {
+	stmt := `SELECT d.id, d.subscription_id, s.url, s.secret, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at
+	FROM webhook_deliveries d
+	JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.status IN ('pending', 'failed')
+	  AND datetime(d.next_attempt_at) <= datetime(?)
+	  AND (d.locked_until IS NULL OR datetime(d.locked_until) <= datetime(?))
+	ORDER BY d.next_attempt_at
+	LIMIT ?`
+
+	ts := now.UTC().Format(sqliteDateTime)
+	return m.queryDeliveries(ctx, stmt, ts, ts, limit)
+}
+
+// Claim locks the delivery until now plus lease so that no other
+// dispatcher sharing the database attempts it at the same time. It returns
+// false when the delivery is already locked.
+func (m *WebhookModel) Claim(ctx context.Context, id int, now time.Time, lease time.Duration) (bool, error) {
+	stmt := `UPDATE webhook_deliveries SET locked_until = ?
+	WHERE id = ? AND (locked_until IS NULL OR datetime(locked_until) <= datetime(?))`
+
+	now = now.UTC()
+	result, err := m.DB.ExecContext(ctx, stmt, now.Add(lease).Format(sqliteDateTime), id, now.Format(sqliteDateTime))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RecordAttempt stores the outcome of an attempt, moves the delivery to the
+// given status, schedules its next attempt and releases the lock.
+func (m *WebhookModel) RecordAttempt(ctx context.Context, a WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := `INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms) VALUES (?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, stmt, a.DeliveryID, a.StatusCode, a.Error, a.Duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE webhook_deliveries
+	SET status = ?, attempts = attempts + 1, next_attempt_at = ?, locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?`
+
+	_, err = tx.ExecContext(ctx, stmt, status, nextAttemptAt.UTC().Format(sqliteDateTime), a.DeliveryID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryDeliveries runs a query selecting deliveries joined with their
+// subscription and scans the resulting rows.
+func (m *WebhookModel) queryDeliveries(ctx context.Context, stmt string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
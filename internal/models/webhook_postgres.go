@@ -0,0 +1,243 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// PostgresWebhookModel is the PostgreSQL version of WebhookModel.
+type PostgresWebhookModel struct {
+	DB *sql.DB
+}
+
+// CreateSubscription adds a new webhook subscription and returns its ID.
+func (m *PostgresWebhookModel) CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (int, error) {
+	stmt := "INSERT INTO webhook_subscriptions (url, secret, event_types) VALUES ($1, $2, $3) RETURNING id"
+
+	var id int
+	err := m.DB.QueryRowContext(ctx, stmt, url, secret, strings.Join(eventTypes, ",")).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// DeleteSubscription removes a subscription together with its deliveries.
+func (m *PostgresWebhookModel) DeleteSubscription(ctx context.Context, id int) error {
+	result, err := m.DB.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// RetrieveSubscription returns the subscription with the given ID.
+func (m *PostgresWebhookModel) RetrieveSubscription(ctx context.Context, id int) (WebhookSubscription, error) {
+	stmt := "SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions WHERE id = $1"
+
+	var s WebhookSubscription
+	var eventTypes string
+
+	err := m.DB.QueryRowContext(ctx, stmt, id).Scan(&s.ID, &s.URL, &s.Secret, &eventTypes, &s.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return WebhookSubscription{}, ErrNoRecord
+		}
+		return WebhookSubscription{}, err
+	}
+	s.EventTypes = strings.Split(eventTypes, ",")
+
+	return s, nil
+}
+
+// ListSubscriptions returns all webhook subscriptions.
+func (m *PostgresWebhookModel) ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	stmt := "SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions ORDER BY id"
+
+	rows, err := m.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var subscriptions []WebhookSubscription
+	for rows.Next() {
+		var s WebhookSubscription
+		var eventTypes string
+		err := rows.Scan(&s.ID, &s.URL, &s.Secret, &eventTypes, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		s.EventTypes = strings.Split(eventTypes, ",")
+		subscriptions = append(subscriptions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription.
+func (m *PostgresWebhookModel) ListDeliveries(ctx context.Context, subscriptionID, limit int) ([]WebhookDelivery, error) {
+	stmt := `SELECT d.id, d.subscription_id, s.url, s.secret, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at
+	FROM webhook_deliveries d
+	JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.subscription_id = $1
+	ORDER BY d.id DESC
+	LIMIT $2`
+
+	return m.queryDeliveries(ctx, stmt, subscriptionID, limit)
+}
+
+// ListAttempts returns the most recent delivery attempts of a subscription.
+func (m *PostgresWebhookModel) ListAttempts(ctx context.Context, subscriptionID, limit int) ([]WebhookAttempt, error) {
+	stmt := `SELECT a.id, a.delivery_id, d.event_type, a.status_code, a.error, a.duration_ms, a.attempted_at
+	FROM webhook_attempts a
+	JOIN webhook_deliveries d ON d.id = a.delivery_id
+	WHERE d.subscription_id = $1
+	ORDER BY a.id DESC
+	LIMIT $2`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var attempts []WebhookAttempt
+	for rows.Next() {
+		var a WebhookAttempt
+		var durationMs int64
+		err := rows.Scan(&a.ID, &a.DeliveryID, &a.EventType, &a.StatusCode, &a.Error, &durationMs, &a.AttemptedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// Due returns up to limit deliveries that are waiting to be attempted at
+// the time now and are not currently locked by a dispatcher.
+func (m *PostgresWebhookModel) Due(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error) {
+	stmt := `SELECT d.id, d.subscription_id, s.url, s.secret, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at
+	FROM webhook_deliveries d
+	JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	WHERE d.status IN ('pending', 'failed')
+	  AND d.next_attempt_at <= $1
+	  AND (d.locked_until IS NULL OR d.locked_until <= $1)
+	ORDER BY d.next_attempt_at
+	LIMIT $2`
+
+	return m.queryDeliveries(ctx, stmt, now, limit)
+}
+
+// Claim locks the delivery until now plus lease so that no other
+// dispatcher sharing the database attempts it at the same time. It returns
+// false when the delivery is already locked.
+func (m *PostgresWebhookModel) Claim(ctx context.Context, id int, now time.Time, lease time.Duration) (bool, error) {
+	stmt := `UPDATE webhook_deliveries SET locked_until = $1
+	WHERE id = $2 AND (locked_until IS NULL OR locked_until <= $3)`
+
+	result, err := m.DB.ExecContext(ctx, stmt, now.Add(lease), id, now)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RecordAttempt stores the outcome of an attempt, moves the delivery to the
+// given status, schedules its next attempt and releases the lock.
+func (m *PostgresWebhookModel) RecordAttempt(ctx context.Context, a WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt := `INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms) VALUES ($1, $2, $3, $4)`
+
+	_, err = tx.ExecContext(ctx, stmt, a.DeliveryID, a.StatusCode, a.Error, a.Duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE webhook_deliveries
+	SET status = $1, attempts = attempts + 1, next_attempt_at = $2, locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3`
+
+	_, err = tx.ExecContext(ctx, stmt, status, nextAttemptAt, a.DeliveryID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryDeliveries runs a query selecting deliveries joined with their
+// subscription and scans the resulting rows.
+func (m *PostgresWebhookModel) queryDeliveries(ctx context.Context, stmt string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := m.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Printf("error closing rows: %v", err)
+		}
+	}(rows)
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,166 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Provider is an OpenID Connect identity provider, described by its
+// discovery document.
+type Provider struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+
+	client *http.Client
+
+	// keys caches the signing keys of the provider by key ID.
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+// Discover fetches the discovery document of the issuer from its
+// /.well-known/openid-configuration URL. The document must name the same
+// issuer, as required by OpenID Connect Discovery.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	p := &Provider{client: client}
+	err := getJSON(ctx, client, wellKnown, p)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("oidc: discovery: issuer %q does not match %q", p.Issuer, issuer)
+	}
+	if p.AuthURL == "" || p.TokenURL == "" || p.JWKSURL == "" {
+		return nil, errors.New("oidc: discovery: missing endpoints")
+	}
+
+	return p, nil
+}
+
+// Config is the registration of the application, the relying party, with a
+// provider.
+type Config struct {
+	Provider     *Provider
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// AuthCodeURL returns the URL of the provider to which users are sent to
+// log in. The state and nonce are checked when they come back, and the
+// challenge is derived from the PKCE verifier sent with the code exchange.
+func (c *Config) AuthCodeURL(state, nonce, challenge string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.ClientID)
+	q.Set("redirect_uri", c.RedirectURL)
+	q.Set("scope", "openid email profile")
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(c.Provider.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.Provider.AuthURL + sep + q.Encode()
+}
+
+// Exchange trades the authorization code for the tokens of the user and
+// returns the raw ID token, which must then be verified.
+func (c *Config) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Provider.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	resp, err := c.Provider.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token exchange: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token exchange: %s: %w", resp.Status, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc: token exchange: %s: %s", resp.Status, strings.TrimSpace(token.Error+" "+token.ErrorDescription))
+	}
+	if token.IDToken == "" {
+		return "", errors.New("oidc: token exchange: no id_token in response")
+	}
+
+	return token.IDToken, nil
+}
+
+// RandomString returns a random URL safe string of 32 bytes of entropy,
+// suitable for the state, the nonce and the PKCE verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge returns the S256 PKCE challenge of the verifier, as defined by
+// RFC 7636.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// getJSON decodes the JSON document at the URL into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,229 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientID = "event-planner"
+	testNonce    = "nonce-123"
+	testKeyID    = "key-1"
+)
+
+// testProvider returns a provider whose JWKS endpoint serves the public key
+// of the returned private key, under testKeyID.
+func testProvider(t *testing.T) (*Provider, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(ts.Close)
+
+	return &Provider{Issuer: testIssuer, JWKSURL: ts.URL, client: ts.Client()}, key
+}
+
+// segment encodes v as a base64url JSON segment of a token.
+func segment(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signRS256 returns the token of the header and claims signed with the key.
+func signRS256(t *testing.T, key *rsa.PrivateKey, header, claims any) string {
+	t.Helper()
+
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	provider, key := testProvider(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := map[string]string{"alg": "RS256", "kid": testKeyID, "typ": "JWT"}
+
+	// claims returns valid claims, changed by the function.
+	claims := func(change func(c map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":   testIssuer,
+			"sub":   "user-1",
+			"aud":   testClientID,
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"iat":   now.Unix(),
+			"nonce": testNonce,
+			"email": "alice@example.com",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	// hs256 signs the token with HMAC-SHA256, using the public key of the
+	// provider as the secret, as in key confusion attacks.
+	hs256 := func() string {
+		signed := segment(t, map[string]string{"alg": "HS256", "kid": testKeyID}) + "." + segment(t, claims(nil))
+		mac := hmac.New(sha256.New, key.N.Bytes())
+		mac.Write([]byte(signed))
+		return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "Valid",
+			token: signRS256(t, key, header, claims(nil)),
+		},
+		{
+			name:  "Audience list with authorized party",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["aud"] = []string{testClientID, "other"}; c["azp"] = testClientID })),
+		},
+		{
+			name:  "Expired within the leeway",
+			token: signRS256(t, key, header, claims(func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() })),
+		},
+		{
+			name:    "Signed by another key",
+			token:   signRS256(t, otherKey, header, claims(nil)),
+			wantErr: "bad signature",
+		},
+		{
+			name: "Claims changed after signing",
+			token: func() string {
+				parts := strings.Split(signRS256(t, key, header, claims(nil)), ".")
+				parts[1] = segment(t, claims(func(c map[string]any) { c["sub"] = "admin" }))
+				return strings.Join(parts, ".")
+			}(),
+			wantErr: "bad signature",
+		},
+		{
+			name:    "Wrong issuer",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { c["iss"] = "https://evil.example.com" })),
+			wantErr: "issuer",
+		},
+		{
+			name:    "Wrong audience",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { c["aud"] = "other-client" })),
+			wantErr: "audience",
+		},
+		{
+			name:    "Audience list without authorized party",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { c["aud"] = []string{testClientID, "other"} })),
+			wantErr: "authorized party",
+		},
+		{
+			name:    "Expired",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() })),
+			wantErr: "expired",
+		},
+		{
+			name:    "Issued in the future",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { c["iat"] = now.Add(time.Hour).Unix() })),
+			wantErr: "issued in the future",
+		},
+		{
+			name:    "Nonce mismatch",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { c["nonce"] = "other-nonce" })),
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "Missing nonce",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { delete(c, "nonce") })),
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "Missing subject",
+			token:   signRS256(t, key, header, claims(func(c map[string]any) { delete(c, "sub") })),
+			wantErr: "missing subject",
+		},
+		{
+			name:    "Unknown key ID",
+			token:   signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-2"}, claims(nil)),
+			wantErr: "unknown key",
+		},
+		{
+			name:    "Algorithm none",
+			token:   segment(t, map[string]string{"alg": "none", "kid": testKeyID}) + "." + segment(t, claims(nil)) + ".",
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name:    "Algorithm HS256 with the public key",
+			token:   hs256(),
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name:    "Malformed",
+			token:   "not-a-token",
+			wantErr: "malformed token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.Verify(context.Background(), tt.token, testClientID, testNonce, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("got error %v; want none", err)
+				}
+				if got.Subject != "user-1" || got.Email != "alice@example.com" {
+					t.Errorf("got claims %+v", got)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("got error %v; want %v", err, ErrInvalidToken)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %q; want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
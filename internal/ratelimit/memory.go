@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Limiter keeping the buckets in memory. The limits are lost on
+// restart and are not shared between instances of the application.
+type Memory struct {
+	Bucket Bucket
+
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+}
+
+// memoryBucket is the state of a bucket at the time it was last used.
+type memoryBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// Allow takes a token from the bucket of the key.
+func (m *Memory) Allow(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.buckets == nil {
+		m.buckets = make(map[string]memoryBucket)
+	}
+
+	tokens := float64(m.Bucket.Burst)
+	if b, ok := m.buckets[key]; ok {
+		tokens = m.Bucket.refill(b.tokens, now.Sub(b.at))
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	m.buckets[key] = memoryBucket{tokens: tokens, at: now}
+
+	// Forget the buckets which are full again, they behave as new ones.
+	for k, b := range m.buckets {
+		if now.Sub(b.at) >= m.Bucket.full() {
+			delete(m.buckets, k)
+		}
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Postgres is the PostgreSQL version of SQLite, keeping the buckets in the
+// rate_limits table.
+type Postgres struct {
+	DB     *sql.DB
+	Bucket Bucket
+
+	// Prefix namespaces the keys of the limiter, as limiters with different
+	// buckets share the table.
+	Prefix string
+}
+
+// Allow takes a token from the bucket of the key.
+func (p *Postgres) Allow(ctx context.Context, key string) (bool, error) {
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	key = p.Prefix + key
+
+	// The row of the bucket is created if missing and locked, so that
+	// concurrent attempts with the same key are counted one after the other.
+	stmt := `INSERT INTO rate_limits (key, tokens, updated_at) VALUES ($1, $2, $3)
+	ON CONFLICT (key) DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, stmt, key, p.Bucket.Burst, now)
+	if err != nil {
+		return false, err
+	}
+
+	var tokens float64
+	var last time.Time
+	err = tx.QueryRowContext(ctx, "SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE", key).Scan(&tokens, &last)
+	if err != nil {
+		return false, err
+	}
+
+	tokens = p.Bucket.refill(tokens, now.Sub(last))
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE rate_limits SET tokens = $1, updated_at = $2 WHERE key = $3", tokens, now, key)
+	if err != nil {
+		return false, err
+	}
+
+	return allowed, tx.Commit()
+}
+
+// Prune deletes the buckets of the limiter which are full again, as they
+// behave the same as missing ones.
+func (p *Postgres) Prune(ctx context.Context) error {
+	before := time.Now().Add(-p.Bucket.full())
+
+	_, err := p.DB.ExecContext(ctx, "DELETE FROM rate_limits WHERE starts_with(key, $1) AND updated_at < $2", p.Prefix, before)
+	return err
+}
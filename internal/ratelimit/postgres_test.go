@@ -0,0 +1,77 @@
+//go:build postgres
+
+package ratelimit
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// TestPostgres runs against the database of POSTGRES_TEST_DSN, and is skipped
+// when the variable is not set.
+func TestPostgres(t *testing.T) {
+	db := modeltest.OpenPostgres(t)
+	ctx := context.Background()
+
+	t.Run("Allow", func(t *testing.T) {
+		l := &Postgres{DB: db, Bucket: Bucket{Burst: 3, Every: time.Hour}, Prefix: "allow:"}
+
+		if got := allowN(t, l, "192.0.2.1", 5); got != 3 {
+			t.Errorf("got %d allowed; want the burst of 3", got)
+		}
+		if got := allowN(t, l, "192.0.2.2", 1); got != 1 {
+			t.Errorf("got %d allowed for another key; want 1", got)
+		}
+
+		// Limiters with other prefixes are independent.
+		other := &Postgres{DB: db, Bucket: Bucket{Burst: 1, Every: time.Hour}, Prefix: "other:"}
+		if got := allowN(t, other, "192.0.2.1", 1); got != 1 {
+			t.Errorf("got %d allowed for another prefix; want 1", got)
+		}
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		l := &Postgres{DB: db, Bucket: Bucket{Burst: 1, Every: 100 * time.Millisecond}, Prefix: "refill:"}
+
+		if got := allowN(t, l, "192.0.2.1", 2); got != 1 {
+			t.Fatalf("got %d allowed; want 1", got)
+		}
+
+		time.Sleep(150 * time.Millisecond)
+
+		if got := allowN(t, l, "192.0.2.1", 2); got != 1 {
+			t.Errorf("got %d allowed after a refill; want 1", got)
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "DELETE FROM rate_limits")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		short := &Postgres{DB: db, Bucket: Bucket{Burst: 1, Every: 10 * time.Millisecond}, Prefix: "short:"}
+		long := &Postgres{DB: db, Bucket: Bucket{Burst: 1, Every: time.Hour}, Prefix: "long:"}
+
+		allowN(t, short, "192.0.2.1", 1)
+		allowN(t, long, "192.0.2.1", 1)
+
+		time.Sleep(50 * time.Millisecond)
+
+		for _, l := range []*Postgres{short, long} {
+			err := l.Prune(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		keys := bucketKeys(t, db)
+		if len(keys) != 1 || keys[0] != "long:192.0.2.1" {
+			t.Errorf("got buckets %q; want only %q", keys, "long:192.0.2.1")
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter decides whether an action identified by a key, such as a login
+// attempt from an IP address, is allowed to proceed.
+type Limiter interface {
+	// Allow takes a token from the bucket of the key and reports whether
+	// one was available.
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
+// Bucket configures a token bucket. A bucket holds at most Burst tokens and
+// regains one token every Every; new buckets start full.
+type Bucket struct {
+	Burst int
+	Every time.Duration
+}
+
+// refill returns the tokens in a bucket which held tokens elapsed ago.
+func (b Bucket) refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed > 0 {
+		tokens += float64(elapsed) / float64(b.Every)
+	}
+	return min(tokens, float64(b.Burst))
+}
+
+// full returns how long an unused bucket takes to be full again.
+func (b Bucket) full() time.Duration {
+	return time.Duration(b.Burst) * b.Every
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"testing"
+	"time"
+)
+
+func TestBucketRefill(t *testing.T) {
+	b := Bucket{Burst: 5, Every: time.Minute}
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "No time elapsed", tokens: 2, elapsed: 0, want: 2},
+		{name: "One period", tokens: 2, elapsed: time.Minute, want: 3},
+		{name: "Half a period", tokens: 0, elapsed: 30 * time.Second, want: 0.5},
+		{name: "Capped at the burst", tokens: 4, elapsed: time.Hour, want: 5},
+		{name: "Clock moved backwards", tokens: 2, elapsed: -time.Minute, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.refill(tt.tokens, tt.elapsed); got != tt.want {
+				t.Errorf("got %v tokens; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// limiters returns the limiters under test, with the bucket.
+func limiters(t *testing.T, bucket Bucket) map[string]Limiter {
+	return map[string]Limiter{
+		"Memory": &Memory{Bucket: bucket},
+		"SQLite": &SQLite{DB: modeltest.OpenDB(t), Bucket: bucket, Prefix: "test:"},
+	}
+}
+
+// allowN calls Allow n times for the key and returns how many were allowed.
+func allowN(t *testing.T, l Limiter, key string, n int) int {
+	t.Helper()
+
+	allowed := 0
+	for range n {
+		ok, err := l.Allow(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestAllow(t *testing.T) {
+	for name, l := range limiters(t, Bucket{Burst: 3, Every: time.Hour}) {
+		t.Run(name, func(t *testing.T) {
+			if got := allowN(t, l, "192.0.2.1", 5); got != 3 {
+				t.Errorf("got %d allowed; want the burst of 3", got)
+			}
+
+			// Every key has its own bucket.
+			if got := allowN(t, l, "192.0.2.2", 1); got != 1 {
+				t.Errorf("got %d allowed for another key; want 1", got)
+			}
+		})
+	}
+}
+
+func TestAllowRefills(t *testing.T) {
+	for name, l := range limiters(t, Bucket{Burst: 1, Every: 100 * time.Millisecond}) {
+		t.Run(name, func(t *testing.T) {
+			if got := allowN(t, l, "192.0.2.1", 2); got != 1 {
+				t.Fatalf("got %d allowed; want 1", got)
+			}
+
+			time.Sleep(150 * time.Millisecond)
+
+			if got := allowN(t, l, "192.0.2.1", 2); got != 1 {
+				t.Errorf("got %d allowed after a refill; want 1", got)
+			}
+		})
+	}
+}
+
+func TestSQLitePrefix(t *testing.T) {
+	db := modeltest.OpenDB(t)
+	bucket := Bucket{Burst: 1, Every: time.Hour}
+
+	login := &SQLite{DB: db, Bucket: bucket, Prefix: "login:"}
+	register := &SQLite{DB: db, Bucket: bucket, Prefix: "register:"}
+
+	if got := allowN(t, login, "192.0.2.1", 2); got != 1 {
+		t.Fatalf("got %d logins allowed; want 1", got)
+	}
+	if got := allowN(t, register, "192.0.2.1", 1); got != 1 {
+		t.Errorf("got %d registrations allowed; want 1, limiters with other prefixes are independent", got)
+	}
+}
+
+func TestSQLitePrune(t *testing.T) {
+	ctx := context.Background()
+	db := modeltest.OpenDB(t)
+
+	short := &SQLite{DB: db, Bucket: Bucket{Burst: 1, Every: 10 * time.Millisecond}, Prefix: "short:"}
+	long := &SQLite{DB: db, Bucket: Bucket{Burst: 1, Every: time.Hour}, Prefix: "long:"}
+
+	allowN(t, short, "192.0.2.1", 1)
+	allowN(t, long, "192.0.2.1", 1)
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Only the full buckets of the limiter are deleted.
+	for _, l := range []*SQLite{short, long} {
+		err := l.Prune(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := bucketKeys(t, db)
+	if len(keys) != 1 || keys[0] != "long:192.0.2.1" {
+		t.Errorf("got buckets %q; want only %q", keys, "long:192.0.2.1")
+	}
+}
+
+// bucketKeys returns the keys of the buckets stored in the database, in order.
+func bucketKeys(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT key FROM rate_limits ORDER BY key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// timeLayout is the format of the times stored in the rate_limits table.
+// Fractions of seconds are kept, as buckets may refill in less than a second.
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// SQLite is a Limiter keeping the buckets in the rate_limits table, so that
+// the limits survive restarts and are shared by the instances of the
+// application using the same database.
+type SQLite struct {
+	DB     *sql.DB
+	Bucket Bucket
+
+	// Prefix namespaces the keys of the limiter, as limiters with different
+	// buckets share the table.
+	Prefix string
+}
+
+// Allow takes a token from the bucket of the key.
+func (s *SQLite) Allow(ctx context.Context, key string) (bool, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	key = s.Prefix + key
+
+	// Refill the bucket first. The statement always writes, so the
+	// transaction holds the write lock before the bucket is read back.
+	stmt := `INSERT INTO rate_limits (key, tokens, updated_at) VALUES (?, ?, ?)
+	ON CONFLICT (key) DO UPDATE SET tokens = tokens, updated_at = updated_at`
+
+	_, err = tx.ExecContext(ctx, stmt, key, s.Bucket.Burst, now.Format(timeLayout))
+	if err != nil {
+		return false, err
+	}
+
+	var tokens float64
+	var last time.Time
+	err = tx.QueryRowContext(ctx, "SELECT tokens, updated_at FROM rate_limits WHERE key = ?", key).Scan(&tokens, &last)
+	if err != nil {
+		return false, err
+	}
+
+	tokens = s.Bucket.refill(tokens, now.Sub(last))
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE rate_limits SET tokens = ?, updated_at = ? WHERE key = ?", tokens, now.Format(timeLayout), key)
+	if err != nil {
+		return false, err
+	}
+
+	return allowed, tx.Commit()
+}
+
+// Prune deletes the buckets of the limiter which are full again, as they
+// behave the same as missing ones.
+func (s *SQLite) Prune(ctx context.Context) error {
+	before := time.Now().UTC().Add(-s.Bucket.full())
+
+	stmt := "DELETE FROM rate_limits WHERE key >= ? AND key < ? AND updated_at < ?"
+
+	// The keys starting with the prefix sort between the prefix and the
+	// prefix followed by the largest byte.
+	_, err := s.DB.ExecContext(ctx, stmt, s.Prefix, s.Prefix+"\xff", before.Format(timeLayout))
+	return err
+}
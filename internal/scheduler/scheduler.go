@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/health"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Notifier delivers a reminder to the attendee it is addressed to.
+type Notifier interface {
+	Notify(ctx context.Context, rm models.Reminder) error
+}
+
+// LogNotifier is a Notifier that writes reminders to a logger. It is useful
+// during development and as a fallback when no real delivery is configured.
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+// Notify logs the reminder and never fails.
+func (n *LogNotifier) Notify(_ context.Context, rm models.Reminder) error {
+	n.Logger.Info("reminder",
+		"event", rm.EventID,
+		"title", rm.EventTitle,
+		"date", rm.EventDate,
+		"email", rm.UserEmail,
+		"offset", rm.Offset.String(),
+	)
+	return nil
+}
+
+// Scheduler periodically scans upcoming events and sends reminders to
+// attendees at each of the configured offsets before the event date.
+type Scheduler struct {
+	Reminders models.ReminderRepository
+	Notifier  Notifier
+	Logger    *slog.Logger
+
+	// Offsets holds how long before an event the reminders are sent.
+	Offsets []time.Duration
+
+	// Interval is the time between two scans.
+	Interval time.Duration
+
+	// Heartbeat, when set, beats after every scan.
+	Heartbeat *health.Heartbeat
+}
+
+// Run scans for due reminders immediately and then on every tick of the
+// interval, until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Scan(ctx, time.Now())
+		s.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lease is how long a claimed reminder is reserved for the scheduler sending
+// it. The claims of a scheduler which stopped while sending are taken over by
+// the scans after the lease has expired.
+const lease = 5 * time.Minute
+
+// Scan sends every reminder that is due at the time now. A reminder is
+// skipped once the window of the next smaller offset has opened, so that an
+// event created or found close to its date only gets the latest reminder
+// instead of all the ones it missed. The reminders skipped are recorded when
+// the latest one is claimed.
+func (s *Scheduler) Scan(ctx context.Context, now time.Time) {
+	offsets := slices.Clone(s.Offsets)
+	slices.SortFunc(offsets, func(a, b time.Duration) int { return cmp.Compare(b, a) })
+
+	for i, offset := range offsets {
+		due, err := s.Reminders.Due(ctx, offset, now)
+		if err != nil {
+			s.Logger.Error(err.Error(), "offset", offset.String())
+			continue
+		}
+
+		for _, rm := range due {
+			if ctx.Err() != nil {
+				return
+			}
+			if i+1 < len(offsets) && !rm.EventDate.After(now.Add(offsets[i+1])) {
+				continue
+			}
+			s.send(ctx, rm, offsets[:i], now)
+		}
+	}
+}
+
+// send claims the reminder, skipping the ones at the larger offsets, and
+// delivers it through the notifier. If the claim fails because another
+// instance already took it, nothing is sent.
+func (s *Scheduler) send(ctx context.Context, rm models.Reminder, skipped []time.Duration, now time.Time) {
+	claimed, err := s.Reminders.Claim(ctx, rm, skipped, now, lease)
+	if err != nil {
+		s.Logger.Error(err.Error(), "event", rm.EventID, "user", rm.UserID)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	// The outcome is recorded even when the scheduler is stopping, so that
+	// the claim is not left behind.
+	done := context.WithoutCancel(ctx)
+
+	err = s.Notifier.Notify(ctx, rm)
+	if err != nil {
+		s.Logger.Error(err.Error(), "event", rm.EventID, "user", rm.UserID)
+
+		// Release the claim so that the reminder is retried on the next scan.
+		err = s.Reminders.Release(done, rm)
+		if err != nil {
+			s.Logger.Error(err.Error(), "event", rm.EventID, "user", rm.UserID)
+		}
+		return
+	}
+
+	err = s.Reminders.MarkSent(done, rm)
+	if err != nil {
+		s.Logger.Error(err.Error(), "event", rm.EventID, "user", rm.UserID)
+	}
+}
@@ -0,0 +1,152 @@
+package scheduler_test
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"github.com/madalinpopa/go-event-planner/internal/scheduler"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recorder is a Notifier which records the offsets of the reminders sent.
+type recorder struct {
+	offsets []time.Duration
+}
+
+func (n *recorder) Notify(_ context.Context, rm models.Reminder) error {
+	n.offsets = append(n.offsets, rm.Offset)
+	return nil
+}
+
+func TestScan(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		startsIn    time.Duration
+		want        []time.Duration
+		wantSkipped int
+	}{
+		{name: "Before every window", startsIn: 48 * time.Hour, want: nil},
+		{name: "In the 24h window", startsIn: 23 * time.Hour, want: []time.Duration{24 * time.Hour}},
+		{name: "In the 1h window", startsIn: 30 * time.Minute, want: []time.Duration{time.Hour}, wantSkipped: 1},
+		{name: "Started", startsIn: -time.Minute, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := modeltest.OpenDB(t)
+
+			users := &models.UserModel{DB: db}
+			err := users.Create(ctx, "Alice", "alice@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			userID, err := users.Authenticate(ctx, "alice@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The event is created less than a day, or an hour, before it
+			// starts, so the scan is its first.
+			eventDate := now.Add(tt.startsIn)
+			eventID, err := (&models.EventModel{DB: db}).Create(ctx, userID, "Meetup", "About the meetup", eventDate, eventDate.Add(time.Hour), "Online", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = (&models.RSVPModel{DB: db}).Create(ctx, eventID, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			notifier := &recorder{}
+			s := &scheduler.Scheduler{
+				Reminders: &models.ReminderModel{DB: db},
+				Notifier:  notifier,
+				Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+				Offsets:   []time.Duration{time.Hour, 24 * time.Hour},
+			}
+
+			s.Scan(ctx, now)
+			if !slices.Equal(notifier.offsets, tt.want) {
+				t.Fatalf("got reminders %v; want %v", notifier.offsets, tt.want)
+			}
+
+			// Reminders are sent once.
+			s.Scan(ctx, now.Add(time.Minute))
+			if !slices.Equal(notifier.offsets, tt.want) {
+				t.Errorf("got reminders %v after a second scan; want %v", notifier.offsets, tt.want)
+			}
+
+			var skipped int
+			err = db.QueryRow(`SELECT COUNT(*) FROM reminders WHERE status = 'skipped'`).Scan(&skipped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("got %d skipped reminders; want %d", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestScanReclaimsExpiredClaims(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	db := modeltest.OpenDB(t)
+
+	users := &models.UserModel{DB: db}
+	err := users.Create(ctx, "Alice", "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := users.Authenticate(ctx, "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventDate := now.Add(20 * time.Hour)
+	eventID, err := (&models.EventModel{DB: db}).Create(ctx, userID, "Meetup", "About the meetup", eventDate, eventDate.Add(time.Hour), "Online", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = (&models.RSVPModel{DB: db}).Create(ctx, eventID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another scheduler claimed the reminder and stopped before sending it.
+	reminders := &models.ReminderModel{DB: db}
+	rm := models.Reminder{EventID: eventID, UserID: userID, Offset: 24 * time.Hour}
+	claimed, err := reminders.Claim(ctx, rm, nil, now, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Fatal("reminder not claimed")
+	}
+
+	notifier := &recorder{}
+	s := &scheduler.Scheduler{
+		Reminders: reminders,
+		Notifier:  notifier,
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Offsets:   []time.Duration{time.Hour, 24 * time.Hour},
+	}
+
+	s.Scan(ctx, now.Add(time.Minute))
+	if len(notifier.offsets) != 0 {
+		t.Fatalf("got reminders %v while the claim holds; want none", notifier.offsets)
+	}
+
+	s.Scan(ctx, now.Add(10*time.Minute))
+	want := []time.Duration{24 * time.Hour}
+	if !slices.Equal(notifier.offsets, want) {
+		t.Errorf("got reminders %v once the claim expired; want %v", notifier.offsets, want)
+	}
+}
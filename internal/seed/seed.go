@@ -0,0 +1,195 @@
+package seed
+
+import (
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Options controls the data generated by Generate.
+type Options struct {
+	// Seed makes the generation deterministic: the same options always
+	// produce the same data.
+	Seed uint64
+
+	// Users and Events are the numbers of users and events to generate.
+	// The first user is an administrator.
+	Users  int
+	Events int
+
+	// MaxRSVPs is the largest number of attendees of an event.
+	MaxRSVPs int
+
+	// Now is the reference date of the generated events, which are spread
+	// from PastDays before it to FutureDays after it.
+	Now        time.Time
+	PastDays   int
+	FutureDays int
+
+	// HashedPassword is the password hash shared by every generated user.
+	HashedPassword []byte
+}
+
+// Generate returns sample users, events and RSVPs following the options.
+func Generate(o Options) models.Fixture {
+	r := rand.New(rand.NewPCG(o.Seed, o.Seed^0x9e3779b97f4a7c15))
+	today := time.Date(o.Now.Year(), o.Now.Month(), o.Now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var f models.Fixture
+
+	emails := make(map[string]bool)
+	for i := range o.Users {
+		u := models.User{
+			Role:           models.RoleUser,
+			HashedPassword: o.HashedPassword,
+			CreatedAt:      today.AddDate(0, 0, -(o.PastDays + r.IntN(365))),
+		}
+
+		if i == 0 {
+			u.Name, u.Email, u.Role = "Admin", "admin@example.test", models.RoleAdmin
+		} else {
+			first, last := pick(r, firstNames), pick(r, lastNames)
+			u.Name = first + " " + last
+			u.Email = uniqueEmail(emails, strings.ToLower(first+"."+last))
+		}
+		emails[u.Email] = true
+
+		f.Users = append(f.Users, u)
+	}
+
+	for range o.Events {
+		date := today.AddDate(0, 0, r.IntN(o.PastDays+o.FutureDays+1)-o.PastDays)
+		topic, kind := pick(r, topics), pick(r, kinds)
+
+		e := models.Event{
+			Title:       fmt.Sprintf("%s %s %s", pick(r, adjectives), topic, kind),
+			Description: description(r, topic, kind),
+			Location:    pick(r, locations),
+			EventDate:   date,
+			CreatedAt:   earliest(date, o.Now).AddDate(0, 0, -1-r.IntN(60)),
+		}
+
+		// Some events span several days, and some have no owner, like the
+		// events left by deleted accounts.
+		if r.IntN(100) < 15 {
+			e.EndDate = date.AddDate(0, 0, 1+r.IntN(3))
+		}
+		if len(f.Users) > 0 && r.IntN(100) >= 10 {
+			e.UserID = 1 + r.IntN(len(f.Users))
+		}
+
+		f.Events = append(f.Events, e)
+		eventPosition := len(f.Events)
+
+		attendees := 0
+		if o.MaxRSVPs > 0 && len(f.Users) > 0 {
+			attendees = r.IntN(min(o.MaxRSVPs, len(f.Users)) + 1)
+		}
+
+		// The attendees are distinct users, taken from a random permutation.
+		window := earliest(date, o.Now).Sub(e.CreatedAt)
+		for _, user := range r.Perm(len(f.Users))[:attendees] {
+			f.RSVPs = append(f.RSVPs, models.RSVP{
+				EventID:   eventPosition,
+				UserID:    user + 1,
+				CreatedAt: e.CreatedAt.Add(time.Duration(r.Int64N(int64(window) + 1))),
+			})
+		}
+	}
+
+	return f
+}
+
+// description returns a Markdown description of an event.
+func description(r *rand.Rand, topic, kind string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Join us for a %s about **%s**. %s\n\n", strings.ToLower(kind), topic, pick(r, pitches))
+	b.WriteString("## Agenda\n\n")
+	for _, item := range r.Perm(len(agenda))[:2+r.IntN(3)] {
+		fmt.Fprintf(&b, "- %s\n", agenda[item])
+	}
+	fmt.Fprintf(&b, "\n%s\n", pick(r, closings))
+
+	return b.String()
+}
+
+// uniqueEmail returns an email for the local part which is not in used,
+// numbering it when needed.
+func uniqueEmail(used map[string]bool, local string) string {
+	email := local + "@example.test"
+	for n := 2; used[email]; n++ {
+		email = fmt.Sprintf("%s%d@example.test", local, n)
+	}
+	return email
+}
+
+// earliest returns the earliest of two times.
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// pick returns a random item of the list.
+func pick(r *rand.Rand, list []string) string {
+	return list[r.IntN(len(list))]
+}
+
+var firstNames = []string{
+	"Ada", "Alan", "Amara", "Ana", "Arjun", "Bea", "Carlos", "Chen", "Chloe", "Dan",
+	"Elena", "Emeka", "Farah", "Grace", "Hana", "Ivan", "Jonas", "Kai", "Leila", "Liam",
+	"Lucia", "Maya", "Mihai", "Nadia", "Noah", "Olga", "Omar", "Priya", "Rosa", "Sam",
+	"Sofia", "Tariq", "Theo", "Uma", "Vera", "Wei", "Yara", "Yusuf", "Zoe", "Zora",
+}
+
+var lastNames = []string{
+	"Adams", "Bauer", "Costa", "Dubois", "Eriksen", "Fischer", "Garcia", "Haddad", "Ionescu", "Jensen",
+	"Kim", "Kowalski", "Lopez", "Martin", "Nakamura", "Novak", "Okafor", "Patel", "Popa", "Quinn",
+	"Rossi", "Santos", "Schmidt", "Singh", "Tanaka", "Urban", "Varga", "Wagner", "Yilmaz", "Zhang",
+}
+
+var adjectives = []string{
+	"Annual", "Advanced", "Community", "Hands-on", "Introductory", "Monthly", "Open", "Practical", "Regional", "Spring",
+}
+
+var topics = []string{
+	"Cloud Computing", "Data Engineering", "Design Systems", "Go", "Machine Learning", "Open Source",
+	"Product Management", "Security", "Startup", "Web Accessibility",
+}
+
+var kinds = []string{
+	"Conference", "Hackathon", "Meetup", "Seminar", "Summit", "Workshop",
+}
+
+var locations = []string{
+	"Convention Center, San Francisco", "Downtown Business Center", "Tech Hub, Austin", "Online - Virtual Event",
+	"Grand Hotel Ballroom", "City Library, Room 4", "Innovation Campus, Berlin", "Riverside Hall, London",
+	"Coworking Loft, Bucharest", "University Auditorium, Lisbon",
+}
+
+var pitches = []string{
+	"Meet practitioners and share what worked for you.",
+	"Expect short talks, live demos and plenty of time for questions.",
+	"Bring your laptop, we will be building things together.",
+	"Whether you are just starting or an expert, there is something for you.",
+}
+
+var agenda = []string{
+	"Welcome and introductions",
+	"Keynote",
+	"Lightning talks",
+	"Panel discussion",
+	"Hands-on session",
+	"Networking with snacks",
+}
+
+var closings = []string{
+	"Seats are limited, so RSVP early.",
+	"See you there!",
+	"The session is held in English.",
+	"Slides will be shared after the event.",
+}
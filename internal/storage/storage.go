@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound indicates that no object is stored under the requested key.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores and retrieves binary objects, such as uploaded files,
+// identified by a key made of slash separated path segments.
+type Storage interface {
+	// Put stores the content read from r under key, replacing any object
+	// already stored there.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+
+	// Get opens the object stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,71 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/image/draw"
+	"image"
+	"image/jpeg"
+	"io"
+
+	// Register the GIF and PNG decoders used by image.Decode.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ContentType is the MIME type of the generated thumbnails.
+const ContentType = "image/jpeg"
+
+// maxPixels bounds the size of the images decoded, protecting the server
+// against decompression bombs that are small on disk but huge in memory.
+const maxPixels = 40_000_000
+
+// ErrTooLarge indicates that the image dimensions exceed the decoding limit.
+var ErrTooLarge = errors.New("thumbnail: image dimensions too large")
+
+// Generate decodes a PNG, JPEG or GIF image and returns a JPEG thumbnail
+// fitting in a size by size square, preserving the aspect ratio. Images
+// already smaller than that are re-encoded without being upscaled.
+func Generate(r io.Reader, size int) ([]byte, error) {
+	// Buffer the image so that its header can be checked before decoding it.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+
+	// JPEG has no transparency, so transparent areas are drawn on white.
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	buf := new(bytes.Buffer)
+	err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,196 @@
+package totp_test
+
+import (
+	"context"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"github.com/madalinpopa/go-event-planner/internal/models/modeltest"
+	"github.com/madalinpopa/go-event-planner/internal/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret of the test vectors of RFC 6238, the ASCII
+// string "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The vectors of RFC 6238, appendix B, for SHA-1. The RFC gives 8 digit
+	// codes, of which the last 6 are the codes of 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			got, err := totp.Code(rfcSecret, totp.Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got code %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeLowerCaseSecret(t *testing.T) {
+	want, err := totp.Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := totp.Code(strings.ToLower(rfcSecret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got code %q for the lower case secret; want %q", got, want)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := totp.Code("not base32!", 1)
+	if err == nil {
+		t.Error("got no error for an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := totp.Step(now)
+
+	// code returns the code of the step offset from the current one.
+	code := func(offset int64) string {
+		c, err := totp.Code(rfcSecret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{name: "Current step", code: code(0), wantOK: true, wantStep: current},
+		{name: "Previous step", code: code(-1), wantOK: true, wantStep: current - 1},
+		{name: "Next step", code: code(1), wantOK: true, wantStep: current + 1},
+		{name: "Two steps before", code: code(-2)},
+		{name: "Two steps after", code: code(2)},
+		{name: "Surrounding spaces", code: " " + code(0) + " ", wantOK: true, wantStep: current},
+		{name: "Too short", code: code(0)[:5]},
+		{name: "Too long", code: code(0) + "0"},
+		{name: "Empty", code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := totp.Validate(rfcSecret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %t; want %t", ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Errorf("got step %d; want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestValidateReplay(t *testing.T) {
+	ctx := context.Background()
+	db := modeltest.OpenDB(t)
+	users := &models.UserModel{DB: db}
+
+	err := users.Create(ctx, "Alice", "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := users.Authenticate(ctx, "alice@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = users.EnableTOTP(ctx, id, rfcSecret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1234567890, 0)
+	code, err := totp.Code(rfcSecret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// use validates the code at the time and records its step, as the login
+	// handler does.
+	use := func(code string, at time.Time) bool {
+		step, ok := totp.Validate(rfcSecret, code, at)
+		if !ok {
+			return false
+		}
+		used, err := users.UseTOTPStep(ctx, id, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return used
+	}
+
+	if !use(code, now) {
+		t.Fatal("got the code refused on its first use")
+	}
+
+	// The code is still in the skew window a period later, but its step
+	// was used.
+	if use(code, now.Add(totp.Period)) {
+		t.Error("got the code accepted when replayed")
+	}
+
+	// An older code, in the skew window as well, is refused too.
+	older, err := totp.Code(rfcSecret, totp.Step(now)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if use(older, now) {
+		t.Error("got the code of an earlier step accepted after a later one was used")
+	}
+
+	next, err := totp.Code(rfcSecret, totp.Step(now)+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !use(next, now.Add(totp.Period)) {
+		t.Error("got the code of the next step refused")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := totp.RecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("got recovery code %q; want two groups of five characters", code)
+		}
+		if seen[code] {
+			t.Errorf("got recovery code %q twice", code)
+		}
+		seen[code] = true
+
+		typed := " " + strings.ToUpper(strings.Replace(code, "-", " ", 1))
+		if got, want := totp.NormalizeRecoveryCode(typed), strings.Replace(code, "-", "", 1); got != want {
+			t.Errorf("got %q for %q; want %q", got, typed, want)
+		}
+	}
+}
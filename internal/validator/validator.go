@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"fmt"
+	"net/url"
 	"regexp"
 	"slices"
 	"strings"
@@ -11,10 +13,36 @@ import (
 // EmailRX is a compiled regular expression to validate the format of email addresses according to standard RFC 5322 rules.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// Translator translates the error messages and formats them with their
+// arguments, as fmt.Sprintf does.
+type Translator interface {
+	Sprintf(format string, args ...any) string
+}
+
 // Validator is a struct used to validate data and store field-specific error messages.
 type Validator struct {
 	NonFieldErrors []string
 	FieldErrors    map[string]string
+
+	// Translator translates the error messages. Without one, they are kept
+	// in English.
+	Translator Translator
+}
+
+// SetTranslator sets the translator of the error messages.
+func (v *Validator) SetTranslator(t Translator) {
+	v.Translator = t
+}
+
+// sprintf translates the message and formats it with the arguments.
+func (v *Validator) sprintf(message string, args ...any) string {
+	if v.Translator != nil {
+		return v.Translator.Sprintf(message, args...)
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
 }
 
 // Valid checks if the validator's FieldErrors map is empty, indicating that no validation errors are present.
@@ -23,7 +51,8 @@ func (v *Validator) Valid() bool {
 }
 
 // AddFieldError adds a validation error message for a specific field if it does not already exist in the FieldErrors map.
-func (v *Validator) AddFieldError(key, message string) {
+// The message is translated and formatted with the arguments.
+func (v *Validator) AddFieldError(key, message string, args ...any) {
 
 	// Check if the FieldErrors map is nil.
 	// If it is nil, initialize it to a new empty map to avoid runtime errors when adding values.
@@ -32,20 +61,21 @@ func (v *Validator) AddFieldError(key, message string) {
 	}
 
 	if _, exists := v.FieldErrors[key]; !exists {
-		v.FieldErrors[key] = message
+		v.FieldErrors[key] = v.sprintf(message, args...)
 	}
 }
 
 // AddNonFieldError appends a non-field-specific error message to the NonFieldErrors slice.
-func (v *Validator) AddNonFieldError(message string) {
-	v.NonFieldErrors = append(v.NonFieldErrors, message)
+// The message is translated and formatted with the arguments.
+func (v *Validator) AddNonFieldError(message string, args ...any) {
+	v.NonFieldErrors = append(v.NonFieldErrors, v.sprintf(message, args...))
 }
 
 // CheckField adds a validation error for the specified
 // field if the condition is not met.
-func (v *Validator) CheckField(ok bool, key, message string) {
+func (v *Validator) CheckField(ok bool, key, message string, args ...any) {
 	if !ok {
-		v.AddFieldError(key, message)
+		v.AddFieldError(key, message, args...)
 	}
 }
 
@@ -87,3 +117,12 @@ func MinChars(value string, n int) bool {
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+
+// ValidURL checks if the given string is an absolute http or https URL with a host.
+func ValidURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
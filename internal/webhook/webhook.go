@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/madalinpopa/go-event-planner/internal/health"
+	"github.com/madalinpopa/go-event-planner/internal/models"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SignatureHeader is the request header carrying the HMAC-SHA256 signature
+// of the request body, formatted as "sha256=<hex digest>".
+const SignatureHeader = "X-Webhook-Signature"
+
+// Sign returns the value of the SignatureHeader for the body signed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid SignatureHeader value for the
+// body signed with secret. Receivers can use it to authenticate deliveries.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Dispatcher periodically sends the queued webhook deliveries, retrying
+// failed ones with an exponential backoff until MaxAttempts is reached.
+type Dispatcher struct {
+	Webhooks models.WebhookRepository
+	Client   *http.Client
+	Logger   *slog.Logger
+
+	// Interval is the time between two scans of the delivery queue.
+	Interval time.Duration
+
+	// MaxAttempts is the number of attempts after which a failing delivery
+	// is moved to the dead state.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It doubles with every
+	// further attempt, up to maxBackoff.
+	Backoff time.Duration
+
+	// Heartbeat, when set, beats after every scan of the queue.
+	Heartbeat *health.Heartbeat
+}
+
+// batchSize is the maximum number of deliveries attempted per scan.
+const batchSize = 50
+
+// maxBackoff bounds the delay between two attempts of a delivery.
+const maxBackoff = 24 * time.Hour
+
+// Run dispatches due deliveries immediately and then on every tick of the
+// interval, until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.Dispatch(ctx, time.Now())
+		d.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch attempts every delivery that is due at the time now.
+func (d *Dispatcher) Dispatch(ctx context.Context, now time.Time) {
+	due, err := d.Webhooks.Due(ctx, now, batchSize)
+	if err != nil {
+		d.Logger.Error(err.Error())
+		return
+	}
+
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The lease must outlive the request timeout so that no other
+		// instance picks the delivery up while it is still in flight. It
+		// starts when the delivery is sent, not when the batch was read,
+		// as the deliveries before it may have taken their whole timeout.
+		claimed, err := d.Webhooks.Claim(ctx, delivery.ID, time.Now(), d.Client.Timeout+time.Minute)
+		if err != nil {
+			d.Logger.Error(err.Error(), "delivery", delivery.ID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		d.attempt(ctx, delivery)
+	}
+}
+
+// attempt sends a single delivery and records the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) {
+	start := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+
+	attempt := models.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		StatusCode: statusCode,
+		Duration:   time.Since(start),
+	}
+
+	status := models.DeliverySucceeded
+	next := time.Now()
+
+	if err != nil {
+		attempt.Error = err.Error()
+
+		attempts := delivery.Attempts + 1
+		if attempts >= d.MaxAttempts {
+			status = models.DeliveryDead
+		} else {
+			status = models.DeliveryFailed
+			next = next.Add(d.backoff(attempts))
+		}
+
+		d.Logger.Warn("webhook delivery failed", "delivery", delivery.ID, "attempt", attempts, "status", status, "error", err.Error())
+	}
+
+	// The attempt is recorded even when the dispatcher is stopping.
+	err = d.Webhooks.RecordAttempt(context.WithoutCancel(ctx), attempt, status, next)
+	if err != nil {
+		d.Logger.Error(err.Error(), "delivery", delivery.ID)
+	}
+}
+
+// backoff returns the delay before the retry following the given number of
+// failed attempts: Backoff doubled for every attempt after the first, capped
+// at maxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.Backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// send posts the signed payload to the subscription URL. Any response
+// outside the 2xx range is reported as an error.
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-event-planner-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Drain a bounded amount of the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -1 +1,26 @@
-console.log("Hello World");
\ No newline at end of file
+// Markdown previews: buttons with data-preview-source and data-preview-target
+// post the source field to the preview endpoint, together with the CSRF token
+// of their form, and display the sanitized HTML returned in the target. The
+// message shown when the preview fails, translated by the page, is in
+// data-preview-error.
+document.querySelectorAll("[data-preview-source]").forEach((button) => {
+    const source = document.getElementById(button.dataset.previewSource);
+    const target = document.getElementById(button.dataset.previewTarget);
+
+    button.addEventListener("click", async () => {
+        const body = new URLSearchParams();
+        body.set("csrf_token", button.form.elements["csrf_token"].value);
+        body.set("description", source.value);
+
+        try {
+            const response = await fetch("/events/preview", {method: "POST", body});
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            target.innerHTML = await response.text();
+        } catch (err) {
+            target.textContent = button.dataset.previewError + ": " + err.message;
+        }
+        target.classList.remove("hidden");
+    });
+});